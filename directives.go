@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tehbilly/genny/parse"
+)
+
+// invocation is a genny command line found in a //go:generate directive or
+// a manifest.
+type invocation struct {
+	// dir is the directory the command runs in.
+	dir string
+	// source is where the command line was found, as file:line.
+	source string
+	args   []string
+	// output is the file the command has to write to, when regenerating a
+	// file.
+	output string
+	// templateChanged is whether the template has changed since the output
+	// was generated, when regenerating a file.
+	templateChanged bool
+}
+
+// command parses the command line of the invocation.
+func (inv invocation) command() (*genCommand, error) {
+	c := &genCommand{args: inv.args}
+	fs := flag.NewFlagSet("genny", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	c.flags(fs)
+	if err := fs.Parse(inv.args); err != nil {
+		return nil, fmt.Errorf("%s: %v", inv.source, err)
+	}
+	if err := c.parseArgs(fs.Args()); err != nil {
+		return nil, fmt.Errorf("%s: %v", inv.source, err)
+	}
+	return c, nil
+}
+
+// findDirectives finds the //go:generate genny directives in the Go files
+// in root and its subdirectories. Like the go tool, it skips directories
+// that start with "." or "_", and testdata directories.
+func findDirectives(root string) ([]invocation, error) {
+	var invocations []invocation
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		found, err := fileDirectives(path)
+		if err != nil {
+			return err
+		}
+		invocations = append(invocations, found...)
+		return nil
+	})
+	return invocations, err
+}
+
+// fileDirectives finds the //go:generate genny directives in a Go file.
+func fileDirectives(path string) ([]invocation, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(string(src), "//go:generate") {
+		return nil, nil
+	}
+
+	var pkgName string
+	if file, err := parser.ParseFile(token.NewFileSet(), path, src, parser.PackageClauseOnly); err == nil {
+		pkgName = file.Name.Name
+	}
+	vars := map[string]string{
+		"GOFILE":    filepath.Base(path),
+		"GOPACKAGE": pkgName,
+		"DOLLAR":    "$",
+	}
+
+	var invocations []invocation
+	sc := bufio.NewScanner(strings.NewReader(string(src)))
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line, ok := parse.GenerateDirective(sc.Text())
+		if !ok {
+			continue
+		}
+		source := fmt.Sprintf("%s:%d", path, lineNo)
+		args, err := splitArgs(line, vars)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", source, err)
+		}
+		invocations = append(invocations, invocation{dir: filepath.Dir(path), source: source, args: args})
+	}
+	return invocations, sc.Err()
+}
+
+// readManifest reads a manifest of genny command lines. Each line holds the
+// arguments of one command, written as they would be after `genny` in a
+// //go:generate directive. Empty lines and lines starting with # are
+// ignored. File names are relative to the directory of the manifest.
+func readManifest(path string) ([]invocation, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var invocations []invocation
+	sc := bufio.NewScanner(strings.NewReader(string(src)))
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		source := fmt.Sprintf("%s:%d", path, lineNo)
+		args, err := splitArgs(line, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", source, err)
+		}
+		invocations = append(invocations, invocation{dir: filepath.Dir(path), source: source, args: args})
+	}
+	return invocations, sc.Err()
+}
+
+// splitArgs splits a command line into arguments the way go generate does:
+// arguments are separated by spaces, quoted arguments use Go syntax, and
+// $NAME is expanded from vars, falling back to the environment.
+func splitArgs(line string, vars map[string]string) ([]string, error) {
+	expand := func(name string) string {
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return os.Getenv(name)
+	}
+
+	var args []string
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimSpace(line) {
+		if line[0] == '"' || line[0] == '`' {
+			quoted, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, errors.New("unterminated quoted string")
+			}
+			arg, err := strconv.Unquote(quoted)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, os.Expand(arg, expand))
+			line = line[len(quoted):]
+			continue
+		}
+		end := strings.IndexAny(line, " \t")
+		if end < 0 {
+			end = len(line)
+		}
+		args = append(args, os.Expand(line[:end], expand))
+		line = line[end:]
+	}
+	return args, nil
+}
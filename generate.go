@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// runGenerate runs `genny generate`.
+func runGenerate(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("genny generate", flag.ContinueOnError)
+	workers := fs.Int("workers", runtime.GOMAXPROCS(0), "how many directives to run at the same time")
+	verbose := fs.Bool("v", false, "also list the outputs that are unchanged")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return &exitError{exitcodeInvalidArgs, err}
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	var invocations []invocation
+	for _, pattern := range patterns {
+		found, err := matchDirectives(pattern)
+		if err != nil {
+			return &exitError{exitcodeSourceFileInvalid, err}
+		}
+		invocations = append(invocations, found...)
+	}
+
+	return report(runInvocations(invocations, *workers), *verbose, stdout)
+}
+
+// report prints the results of running invocations and a summary. It fails
+// if any of them failed.
+func report(results []invocationResult, verbose bool, stdout io.Writer) error {
+	var changed, unchanged, failed int
+	for _, r := range results {
+		switch {
+		case r.err != nil:
+			failed++
+			fmt.Fprintf(stdout, "FAIL      %s: %v\n", r.inv.source, r.err)
+		case len(r.changed) > 0:
+			changed++
+			for _, name := range r.changed {
+				if r.inv.templateChanged && sameFile(name, r.inv.output) {
+					fmt.Fprintf(stdout, "changed   %s (the template has changed)\n", name)
+				} else {
+					fmt.Fprintf(stdout, "changed   %s\n", name)
+				}
+			}
+		default:
+			unchanged++
+			if verbose {
+				fmt.Fprintf(stdout, "unchanged %s\n", r.inv.source)
+			}
+		}
+		stdout.Write(r.stdout)
+	}
+	fmt.Fprintf(stdout, "%d changed, %d unchanged, %d failed\n", changed, unchanged, failed)
+
+	if failed > 0 {
+		return &exitError{exitcodeGenFailed, fmt.Errorf("%d of %d failed", failed, len(results))}
+	}
+	return nil
+}
+
+// sameFile gets whether the paths name the same file.
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// matchDirectives finds the //go:generate genny directives matched by a
+// pattern. Like with the go tool, a pattern is a directory, which ends with
+// "/..." to include its subdirectories, or a Go file.
+func matchDirectives(pattern string) ([]invocation, error) {
+	if slashed := filepath.ToSlash(pattern); slashed == "..." || strings.HasSuffix(slashed, "/...") {
+		dir := strings.TrimSuffix(slashed, "...")
+		if dir != "/" {
+			dir = strings.TrimSuffix(dir, "/")
+		}
+		if dir == "" {
+			dir = "."
+		}
+		return findDirectives(filepath.FromSlash(dir))
+	}
+	if strings.HasSuffix(pattern, ".go") {
+		return fileDirectives(pattern)
+	}
+
+	names, err := filepath.Glob(filepath.Join(pattern, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(pattern); err != nil {
+		return nil, err
+	}
+	var invocations []invocation
+	for _, name := range names {
+		found, err := fileDirectives(name)
+		if err != nil {
+			return nil, err
+		}
+		invocations = append(invocations, found...)
+	}
+	return invocations, nil
+}
+
+// invocationResult is the result of running an invocation.
+type invocationResult struct {
+	inv     invocation
+	changed []string
+	// stdout is what the invocation wrote to stdout, if it has no output
+	// file.
+	stdout []byte
+	err    error
+}
+
+// runInvocations runs the invocations on a pool of workers, and gets their
+// results in the same order.
+func runInvocations(invocations []invocation, workers int) []invocationResult {
+	if workers <= 0 {
+		workers = 1
+	}
+	results := make([]invocationResult, len(invocations))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = runInvocation(invocations[i])
+			}
+		}()
+	}
+	for i := range invocations {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// runInvocation runs a single invocation.
+func runInvocation(inv invocation) (r invocationResult) {
+	r.inv = inv
+	defer func() {
+		// a bad template must not take the other directives down with it
+		if p := recover(); p != nil {
+			r.err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	cmd, err := inv.command()
+	if err != nil {
+		r.err = err
+		return r
+	}
+	if inv.output != "" {
+		if r.err = cmd.writeTo(inv.dir, inv.output); r.err != nil {
+			return r
+		}
+	}
+	var stdout bytes.Buffer
+	if r.err = cmd.run(inv.dir, bytes.NewReader(nil), &stdout); r.err != nil {
+		return r
+	}
+	r.changed = cmd.changed
+	r.stdout = stdout.Bytes()
+	return r
+}
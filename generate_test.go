@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"a/queue.go": watchTemplate,
+		"b/list.go": strings.Replace(watchTemplate,
+			"//go:generate genny -in=$GOFILE -out=gen-$GOFILE gen \"Something=int\"",
+			"//go:generate go run github.com/tehbilly/genny@latest -in=$GOFILE -out=gen-$GOFILE gen \"Something=string\"", 1),
+		"c/bad.go": "package bad\n\n//go:generate genny -in=missing.go -out=gen-$GOFILE gen \"Something=int\"\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(src), 0644))
+	}
+
+	var log bytes.Buffer
+	err := runGenerate([]string{dir + "/..."}, &log)
+	assert.Error(t, err)
+	assert.Contains(t, log.String(), "changed   "+filepath.Join(dir, "a", "gen-queue.go"))
+	assert.Contains(t, log.String(), "changed   "+filepath.Join(dir, "b", "gen-list.go"))
+	assert.Contains(t, log.String(), "FAIL      "+filepath.Join(dir, "c", "bad.go")+":3")
+	assert.Contains(t, log.String(), "2 changed, 0 unchanged, 1 failed")
+
+	generated, err := ioutil.ReadFile(filepath.Join(dir, "b", "gen-list.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(generated), "type StringQueue []string")
+	assert.NotContains(t, string(generated), "go:generate")
+
+	// nothing changes the second time
+	log.Reset()
+	err = runGenerate([]string{"-v", filepath.Join(dir, "a"), filepath.Join(dir, "b", "list.go")}, &log)
+	assert.NoError(t, err)
+	assert.Contains(t, log.String(), "unchanged "+filepath.Join(dir, "a", "queue.go")+":5")
+	assert.Contains(t, log.String(), "0 changed, 2 unchanged, 0 failed")
+}
+
+func TestMatchDirectives(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "queue.go"), []byte(watchTemplate), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sub", "queue.go"), []byte(watchTemplate), 0644))
+
+	found, err := matchDirectives(dir)
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+
+	found, err = matchDirectives(dir + "/...")
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+
+	_, err = matchDirectives(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
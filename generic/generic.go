@@ -11,3 +11,35 @@ type Type interface{}
 // references to the specific types.
 //      var GenericType generic.Number
 type Number float64
+
+// Int is the placeholder type that indicates a generic integer constant.
+// When genny is executed, constants of this type will be replaced with int
+// constants with the value given for them.
+//      const Capacity generic.Int = 32
+type Int = int
+
+// Uint is the placeholder type that indicates a generic unsigned integer
+// constant.
+//      const Capacity generic.Uint = 32
+type Uint = uint
+
+// Float is the placeholder type that indicates a generic floating-point
+// constant.
+//      const LoadFactor generic.Float = 0.75
+type Float = float64
+
+// String is the placeholder type that indicates a generic string constant.
+//      const Prefix generic.String = "item"
+type String = string
+
+// Bool is the placeholder type that indicates a generic boolean constant.
+//      const Sorted generic.Bool = false
+type Bool = bool
+
+// Name is the placeholder type that indicates a generic name. Its value
+// only takes part in the names of the generated code, so that a template
+// can be generated more than once for the same types, e.g. with
+// Prefix=Session:
+//      type Prefix generic.Name
+//      type PrefixCache struct{}
+type Name struct{}
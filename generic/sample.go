@@ -0,0 +1,55 @@
+package generic
+
+import (
+	"reflect"
+)
+
+// Sampler is implemented by types that provide their own sample values.
+// genny uses it for the types it has no samples for that the template marks
+// with //genny:sampler.
+//      func (p Point) Sample(i int) Point { return Point{X: i, Y: i} }
+type Sampler[T any] interface {
+	// Sample gets the i-th sample value.
+	Sample(i int) T
+}
+
+// Sample is the placeholder for the i-th sample value of a generic type.
+// When genny is executed, calls to Sample will be replaced with sample
+// values of the specific types.
+//      q.Push(generic.Sample[Something](0))
+// In generic code it returns i+1 for number and interface types, the i-th
+// letter for strings, and alternates true and false for booleans. Other
+// types get the zero value, unless they implement Sampler.
+func Sample[T any](i int) T {
+	var v T
+	if s, ok := any(v).(Sampler[T]); ok {
+		return s.Sample(i)
+	}
+	rv := reflect.ValueOf(&v).Elem()
+	switch rv.Kind() {
+	case reflect.Interface:
+		if reflect.TypeOf(i).Implements(rv.Type()) {
+			rv.Set(reflect.ValueOf(i + 1))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		rv.SetInt(int64(i + 1))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		rv.SetUint(uint64(i + 1))
+	case reflect.Float32, reflect.Float64:
+		rv.SetFloat(float64(i) + 1.5)
+	case reflect.String:
+		rv.SetString(string(rune('a' + i%26)))
+	case reflect.Bool:
+		rv.SetBool(i%2 == 0)
+	}
+	return v
+}
+
+// Zero is the placeholder for the zero value of a generic type.
+// When genny is executed, calls to Zero will be replaced with the zero
+// value of the specific types.
+//      var empty = generic.Zero[Something]()
+func Zero[T any]() T {
+	var v T
+	return v
+}
@@ -1,8 +1,13 @@
 module github.com/tehbilly/genny
 
-go 1.12
+go 1.18
 
 require (
 	github.com/stretchr/testify v1.3.0
 	golang.org/x/tools v0.0.0-20190319232107-3f1ed9edd1b4
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+)
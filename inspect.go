@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tehbilly/genny/parse"
+)
+
+// runInspect runs `genny inspect`.
+func runInspect(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("genny inspect", flag.ContinueOnError)
+	in := fs.String("in", "", "template to inspect instead of stdin")
+	asJSON := fs.Bool("json", false, "print the description as JSON")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return &exitError{exitcodeInvalidArgs, err}
+	}
+
+	filename := "stdin"
+	if *in != "" {
+		file, err := os.Open(*in)
+		if err != nil {
+			return &exitError{exitcodeSourceFileInvalid, err}
+		}
+		defer file.Close()
+		filename, stdin = *in, file
+	}
+	info, err := parse.Inspect(filename, stdin)
+	if err != nil {
+		return &exitError{exitcodeSourceFileInvalid, err}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+	printTemplateInfo(stdout, info)
+	return nil
+}
+
+// printTemplateInfo prints the description of a template as text.
+func printTemplateInfo(w io.Writer, info *parse.TemplateInfo) {
+	fmt.Fprintf(w, "template %s (package %s)\n", info.Filename, info.Package)
+
+	fmt.Fprintln(w, "\ngeneric types:")
+	if len(info.GenericTypes) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for _, gt := range info.GenericTypes {
+		if gt.Interface {
+			fmt.Fprintf(w, "  %s: interface embedding generic.%s\n", gt.Name, gt.Kind)
+		} else {
+			fmt.Fprintf(w, "  %s: generic.%s\n", gt.Name, gt.Kind)
+		}
+		if gt.Doc != "" {
+			fmt.Fprintf(w, "    %s\n", strings.ReplaceAll(gt.Doc, "\n", "\n    "))
+		}
+		if len(gt.Embeds) > 0 {
+			fmt.Fprintf(w, "    embeds: %s\n", strings.Join(gt.Embeds, ", "))
+		}
+		for _, method := range gt.Methods {
+			fmt.Fprintf(w, "    requires: %s\n", method)
+		}
+	}
+
+	if len(info.Constants) > 0 {
+		fmt.Fprintln(w, "\nconstants:")
+	}
+	for _, c := range info.Constants {
+		named := ""
+		if c.Named {
+			named = ", named"
+		}
+		fmt.Fprintf(w, "  %s: generic.%s = %s (%s%s)\n", c.Name, c.Kind, c.Default, c.Type, named)
+	}
+
+	fmt.Fprintln(w, "\ndirectives:")
+	if len(info.Directives) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for _, d := range info.Directives {
+		fmt.Fprintf(w, "  %d: %s\n", d.Line, d.Text)
+	}
+}
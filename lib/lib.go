@@ -0,0 +1,95 @@
+// Package lib is the library of templates that is built into genny, so that
+// `genny get lib:<name>` generates code from them without fetching anything.
+// Each template is a package of its own under templates, which is built and
+// tested with the rest of genny. Its tests are in a companion _test.go, which
+// is also generated for several typesets and run against the generated code.
+// Its documentation is in a doc.go of its own, so that it isn't copied into
+// the generated code.
+package lib
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"sort"
+
+	"github.com/tehbilly/genny/parse"
+)
+
+// Prefix is the prefix of the templates of the library given to `genny get`,
+// as in lib:set.
+const Prefix = "lib:"
+
+//go:embed templates
+var templates embed.FS
+
+// Template describes a template of the library.
+type Template struct {
+	Name string `json:"name"`
+	// Synopsis is the first sentence of the template's package
+	// documentation.
+	Synopsis string `json:"synopsis"`
+	// Info describes the generic types and constants of the template.
+	Info *parse.TemplateInfo `json:"info"`
+}
+
+// Names gets the names of the templates of the library, in order.
+func Names() []string {
+	entries, err := fs.ReadDir(templates, "templates")
+	if err != nil {
+		panic(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Filename gets the name of the file of a template, for messages.
+func Filename(name string) string {
+	return path.Join("templates", name, name+".go")
+}
+
+// Source gets the source of the named template.
+func Source(name string) ([]byte, error) {
+	source, err := templates.ReadFile(Filename(name))
+	if err != nil {
+		return nil, fmt.Errorf("no template %q in the library, see genny lib list", name)
+	}
+	return source, nil
+}
+
+// List describes the templates of the library, in order of their names.
+func List() ([]Template, error) {
+	var list []Template
+	for _, name := range Names() {
+		source, err := Source(name)
+		if err != nil {
+			return nil, err
+		}
+		docName := path.Join("templates", name, "doc.go")
+		docSource, err := templates.ReadFile(docName)
+		if err != nil {
+			return nil, err
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), docName, docSource, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		info, err := parse.Inspect(Filename(name), bytes.NewReader(source))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, Template{Name: name, Synopsis: doc.Synopsis(file.Doc.Text()), Info: info})
+	}
+	return list, nil
+}
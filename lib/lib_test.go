@@ -0,0 +1,110 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tehbilly/genny/parse"
+)
+
+// typeSets are those the templates are generated for in the tests, by the
+// generic types they declare.
+var typeSets = map[string]string{
+	"Elem":      "Elem=int,string,Stamp:*time.Time",
+	"Key,Value": "Key=int,string Value=string,Bytes:[]byte,Stamp:*time.Time",
+}
+
+func TestList(t *testing.T) {
+	list, err := List()
+	require.NoError(t, err)
+	var names []string
+	for _, template := range list {
+		names = append(names, template.Name)
+		assert.NotEmpty(t, template.Synopsis, template.Name)
+	}
+	assert.Equal(t, []string{"btree", "concurrentmap", "heap", "lru", "orderedmap", "queue", "ringbuffer", "set", "sortedslice", "stack"}, names)
+
+	_, err = Source("nope")
+	assert.Error(t, err)
+}
+
+// TestGenerate generates the templates and their tests for the typesets,
+// and runs the generated tests.
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module generated\n\ngo 1.18\n"), 0644))
+
+	list, err := List()
+	require.NoError(t, err)
+	for _, template := range list {
+		var generics []string
+		for _, gt := range template.Info.GenericTypes {
+			generics = append(generics, gt.Name)
+		}
+		arg, ok := typeSets[strings.Join(generics, ",")]
+		require.True(t, ok, "no typesets for the generic types %v of %s", generics, template.Name)
+		sets, err := parse.TypeSet(arg)
+		require.NoError(t, err)
+
+		source, err := Source(template.Name)
+		require.NoError(t, err)
+		testSource, err := templates.ReadFile(testFilename(template.Name))
+		require.NoError(t, err, "every template has tests")
+		for _, useAst := range []bool{true, false} {
+			opts := parse.Options{UseAst: useAst}
+			out, err := parse.Generate(Filename(template.Name), bytes.NewReader(source), sets, opts)
+			if !assert.NoError(t, err, "%s, ast: %v", template.Name, useAst) {
+				continue
+			}
+			typeCheck(t, Filename(template.Name), out)
+			testOut, err := parse.Generate(testFilename(template.Name), bytes.NewReader(testSource), sets, opts)
+			if !assert.NoError(t, err, "%s tests, ast: %v", template.Name, useAst) {
+				continue
+			}
+
+			pkgDir := filepath.Join(dir, fmt.Sprintf("ast%v", useAst), template.Name)
+			require.NoError(t, os.MkdirAll(pkgDir, 0755))
+			require.NoError(t, ioutil.WriteFile(filepath.Join(pkgDir, template.Name+".go"), out, 0644))
+			require.NoError(t, ioutil.WriteFile(filepath.Join(pkgDir, template.Name+"_test.go"), testOut, 0644))
+		}
+	}
+
+	if testing.Short() {
+		t.Skip("not running the generated tests in short mode")
+	}
+	cmd := exec.Command("go", "test", "./...")
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, "the generated tests fail:\n%s", output)
+}
+
+// testFilename gets the name of the file of the tests of a template.
+func testFilename(name string) string {
+	return strings.TrimSuffix(Filename(name), ".go") + "_test.go"
+}
+
+// typeCheck checks that the generated code compiles.
+func typeCheck(t *testing.T, filename string, source []byte) {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, source, 0)
+	if !assert.NoError(t, err, "%s", source) {
+		return
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check(file.Name.Name, fset, []*ast.File{file}, nil)
+	assert.NoError(t, err, "%s", source)
+}
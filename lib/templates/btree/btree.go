@@ -0,0 +1,814 @@
+package btree
+
+import (
+	"io"
+
+	"github.com/tehbilly/genny/generic"
+)
+
+// Key is the type of the keys of the tree.
+type Key generic.Type
+
+// Value is the type of the values of the tree.
+type Value generic.Type
+
+const (
+	keyValueKX = 128 //TODO benchmark tune this number if using custom types.
+	keyValueKD = 64  //TODO benchmark tune this number if using custom types.
+)
+
+type (
+	// KeyValueCmp compares a and b. The result is:
+	//
+	//	< 0 if a <  b
+	//	  0 if a == b
+	//	> 0 if a >  b
+	//
+	KeyValueCmp func(a, b Key) int
+
+	keyValueData struct { // data page
+		c int
+		d [2*keyValueKD + 1]keyValueDataEntry
+		n *keyValueData
+		p *keyValueData
+	}
+
+	keyValueDataEntry struct { // d element
+		k Key
+		v Value
+	}
+
+	// KeyValueEnumerator captures the state of enumerating a tree. It is
+	// returned from the Seek* methods. The enumerator is aware of any
+	// mutations made to the tree in the process of enumerating it and
+	// automatically resumes the enumeration at the proper position, if possible.
+	//
+	// However, once a KeyValueEnumerator returns io.EOF to signal "no more
+	// items", it does no more attempt to "resync" on tree mutation(s).  In
+	// other words, io.EOF from an Enumaretor is "sticky" (idempotent).
+	KeyValueEnumerator struct {
+		err error
+		hit bool
+		i   int
+		k   Key
+		q   *keyValueData
+		t   *KeyValueTree
+		ver int64
+	}
+
+	// KeyValueTree is a B+tree.
+	KeyValueTree struct {
+		c     int
+		cmp   KeyValueCmp
+		first *keyValueData
+		last  *keyValueData
+		r     interface{}
+		ver   int64
+	}
+
+	keyValueIndexEntry struct { // x element
+		ch  interface{}
+		sep *keyValueData
+	}
+
+	keyValueIndex struct { // index page
+		c int
+		x [2*keyValueKX + 2]keyValueIndexEntry
+	}
+)
+
+var ( // R/O zeros
+	zeroKeyValueData       keyValueData
+	zeroKeyValueDataEntry  keyValueDataEntry
+	zeroKeyValueIndex      keyValueIndex
+	zeroKeyValueIndexEntry keyValueIndexEntry
+)
+
+func clearKeyValue(q interface{}) {
+	switch x := q.(type) {
+	case *keyValueIndex:
+		for i := 0; i <= x.c; i++ { // Ch0 Sep0 ... Chn-1 Sepn-1 Chn
+			clearKeyValue(x.x[i].ch)
+		}
+		*x = zeroKeyValueIndex // GC
+	case *keyValueData:
+		*x = zeroKeyValueData // GC
+	}
+}
+
+// -------------------------------------------------------------------------- x
+
+func newKeyValueIndex(ch0 interface{}) *keyValueIndex {
+	r := &keyValueIndex{}
+	r.x[0].ch = ch0
+	return r
+}
+
+func (q *keyValueIndex) extract(i int) {
+	q.c--
+	if i < q.c {
+		copy(q.x[i:], q.x[i+1:q.c+1])
+		q.x[q.c].ch = q.x[q.c+1].ch
+		q.x[q.c].sep = nil                  // GC
+		q.x[q.c+1] = zeroKeyValueIndexEntry // GC
+	}
+}
+
+func (q *keyValueIndex) insert(i int, d *keyValueData, ch interface{}) *keyValueIndex {
+	c := q.c
+	if i < c {
+		q.x[c+1].ch = q.x[c].ch
+		copy(q.x[i+2:], q.x[i+1:c])
+		q.x[i+1].sep = q.x[i].sep
+	}
+	c++
+	q.c = c
+	q.x[i].sep = d
+	q.x[i+1].ch = ch
+	return q
+}
+
+func (q *keyValueIndex) siblings(i int) (l, r *keyValueData) {
+	if i >= 0 {
+		if i > 0 {
+			l = q.x[i-1].ch.(*keyValueData)
+		}
+		if i < q.c {
+			r = q.x[i+1].ch.(*keyValueData)
+		}
+	}
+	return
+}
+
+// -------------------------------------------------------------------------- d
+
+func (l *keyValueData) mvL(r *keyValueData, c int) {
+	copy(l.d[l.c:], r.d[:c])
+	copy(r.d[:], r.d[c:r.c])
+	l.c += c
+	r.c -= c
+}
+
+func (l *keyValueData) mvR(r *keyValueData, c int) {
+	copy(r.d[c:], r.d[:r.c])
+	copy(r.d[:c], l.d[l.c-c:])
+	r.c += c
+	l.c -= c
+}
+
+// ----------------------------------------------------------------------- Tree
+
+// NewKeyValueTree returns a newly created, empty KeyValueTree. The compare
+// function is used for collation.
+func NewKeyValueTree(cmp KeyValueCmp) *KeyValueTree {
+	return &KeyValueTree{cmp: cmp}
+}
+
+// Clear removes all K/V pairs from the tree.
+func (t *KeyValueTree) Clear() {
+	if t.r == nil {
+		return
+	}
+
+	clearKeyValue(t.r)
+	t.c, t.first, t.last, t.r = 0, nil, nil, nil
+	t.ver++
+}
+
+func (t *KeyValueTree) cat(p *keyValueIndex, q, r *keyValueData, pi int) {
+	t.ver++
+	q.mvL(r, r.c)
+	if r.n != nil {
+		r.n.p = q
+	} else {
+		t.last = q
+	}
+	q.n = r.n //TODO recycle r
+	if p.c > 1 {
+		p.extract(pi)
+		p.x[pi].ch = q
+	} else { //TODO recycle r
+		t.r = q
+	}
+}
+
+func (t *KeyValueTree) catX(p, q, r *keyValueIndex, pi int) {
+	t.ver++
+	q.x[q.c].sep = p.x[pi].sep
+	copy(q.x[q.c+1:], r.x[:r.c])
+	q.c += r.c + 1
+	q.x[q.c].ch = r.x[r.c].ch //TODO recycle r
+	if p.c > 1 {
+		p.c--
+		pc := p.c
+		if pi < pc {
+			p.x[pi].sep = p.x[pi+1].sep
+			copy(p.x[pi+1:], p.x[pi+2:pc+1])
+			p.x[pc].ch = p.x[pc+1].ch
+			p.x[pc].sep = nil  // GC
+			p.x[pc+1].ch = nil // GC
+		}
+		return
+	}
+
+	t.r = q //TODO recycle r
+}
+
+// Delete removes the k's KV pair, if it exists, in which case Delete returns
+// true.
+func (t *KeyValueTree) Delete(k Key) (ok bool) {
+	pi := -1
+	var p *keyValueIndex
+	q := t.r
+	if q == nil {
+		return
+	}
+
+	for {
+		var i int
+		i, ok = t.find(q, k)
+		if ok {
+			switch x := q.(type) {
+			case *keyValueIndex:
+				dp := x.x[i].sep
+				switch {
+				case dp.c > keyValueKD:
+					t.extract(dp, 0)
+				default:
+					if x.c < keyValueKX && q != t.r {
+						t.underflowX(p, &x, pi, &i)
+					}
+					pi = i + 1
+					p = x
+					q = x.x[pi].ch
+					ok = false
+					continue
+				}
+			case *keyValueData:
+				t.extract(x, i)
+				if x.c >= keyValueKD {
+					return
+				}
+
+				if q != t.r {
+					t.underflow(p, x, pi)
+				} else if t.c == 0 {
+					t.Clear()
+				}
+			}
+			return
+		}
+
+		switch x := q.(type) {
+		case *keyValueIndex:
+			if x.c < keyValueKX && q != t.r {
+				t.underflowX(p, &x, pi, &i)
+			}
+			pi = i
+			p = x
+			q = x.x[i].ch
+		case *keyValueData:
+			return
+		}
+	}
+}
+
+func (t *KeyValueTree) extract(q *keyValueData, i int) {
+	t.ver++
+	//r = q.d[i].v // prepared for Extract
+	q.c--
+	if i < q.c {
+		copy(q.d[i:], q.d[i+1:q.c+1])
+	}
+	q.d[q.c] = zeroKeyValueDataEntry // GC
+	t.c--
+	return
+}
+
+func (t *KeyValueTree) find(q interface{}, k Key) (i int, ok bool) {
+	var mk Key
+	l := 0
+	switch x := q.(type) {
+	case *keyValueIndex:
+		h := x.c - 1
+		for l <= h {
+			m := (l + h) >> 1
+			mk = x.x[m].sep.d[0].k
+			switch cmp := t.cmp(k, mk); {
+			case cmp > 0:
+				l = m + 1
+			case cmp == 0:
+				return m, true
+			default:
+				h = m - 1
+			}
+		}
+	case *keyValueData:
+		h := x.c - 1
+		for l <= h {
+			m := (l + h) >> 1
+			mk = x.d[m].k
+			switch cmp := t.cmp(k, mk); {
+			case cmp > 0:
+				l = m + 1
+			case cmp == 0:
+				return m, true
+			default:
+				h = m - 1
+			}
+		}
+	}
+	return l, false
+}
+
+// First returns the first item of the tree in collating order, or zeros if
+// the tree is empty.
+func (t *KeyValueTree) First() (k Key, v Value) {
+	if q := t.first; q != nil {
+		q := &q.d[0]
+		k, v = q.k, q.v
+	}
+	return
+}
+
+// Get returns what k is associated with and true if it exists. Otherwise Get
+// returns a zero and false.
+func (t *KeyValueTree) Get(k Key) (v Value, ok bool) {
+	q := t.r
+	if q == nil {
+		return
+	}
+
+	for {
+		var i int
+		if i, ok = t.find(q, k); ok {
+			switch x := q.(type) {
+			case *keyValueIndex:
+				return x.x[i].sep.d[0].v, true
+			case *keyValueData:
+				return x.d[i].v, true
+			}
+		}
+		switch x := q.(type) {
+		case *keyValueIndex:
+			q = x.x[i].ch
+		default:
+			return
+		}
+	}
+}
+
+func (t *KeyValueTree) insert(q *keyValueData, i int, k Key, v Value) *keyValueData {
+	t.ver++
+	c := q.c
+	if i < c {
+		copy(q.d[i+1:], q.d[i:c])
+	}
+	c++
+	q.c = c
+	q.d[i].k, q.d[i].v = k, v
+	t.c++
+	return q
+}
+
+// Last returns the last item of the tree in collating order, or zeros if the
+// tree is empty.
+func (t *KeyValueTree) Last() (k Key, v Value) {
+	if q := t.last; q != nil {
+		q := &q.d[q.c-1]
+		k, v = q.k, q.v
+	}
+	return
+}
+
+// Len returns the number of items in the tree.
+func (t *KeyValueTree) Len() int {
+	return t.c
+}
+
+func (t *KeyValueTree) overflow(p *keyValueIndex, q *keyValueData, pi, i int, k Key, v Value) {
+	t.ver++
+	l, r := p.siblings(pi)
+
+	if l != nil && l.c < 2*keyValueKD {
+		l.mvL(q, 1)
+		t.insert(q, i-1, k, v)
+		return
+	}
+
+	if r != nil && r.c < 2*keyValueKD {
+		if i < 2*keyValueKD {
+			q.mvR(r, 1)
+			t.insert(q, i, k, v)
+		} else {
+			t.insert(r, 0, k, v)
+		}
+		return
+	}
+
+	t.split(p, q, pi, i, k, v)
+}
+
+// Seek returns a KeyValueEnumerator positioned on an item such that k >= the
+// item's k. ok reports whether they are equal. The KeyValueEnumerator's
+// position is possibly after the last item in the tree.
+func (t *KeyValueTree) Seek(k Key) (e *KeyValueEnumerator, ok bool) {
+	q := t.r
+	if q == nil {
+		e = &KeyValueEnumerator{nil, false, 0, k, nil, t, t.ver}
+		return
+	}
+
+	for {
+		var i int
+		if i, ok = t.find(q, k); ok {
+			switch x := q.(type) {
+			case *keyValueIndex:
+				e = &KeyValueEnumerator{nil, ok, 0, k, x.x[i].sep, t, t.ver}
+				return
+			case *keyValueData:
+				e = &KeyValueEnumerator{nil, ok, i, k, x, t, t.ver}
+				return
+			}
+		}
+		switch x := q.(type) {
+		case *keyValueIndex:
+			q = x.x[i].ch
+		case *keyValueData:
+			e = &KeyValueEnumerator{nil, ok, i, k, x, t, t.ver}
+			return
+		}
+	}
+}
+
+// SeekFirst returns an enumerator positioned on the first KV pair in the tree,
+// if any. For an empty tree, err == io.EOF is returned and e will be nil.
+func (t *KeyValueTree) SeekFirst() (e *KeyValueEnumerator, err error) {
+	q := t.first
+	if q == nil {
+		return nil, io.EOF
+	}
+
+	return &KeyValueEnumerator{nil, true, 0, q.d[0].k, q, t, t.ver}, nil
+}
+
+// SeekLast returns an enumerator positioned on the last KV pair in the tree,
+// if any. For an empty tree, err == io.EOF is returned and e will be nil.
+func (t *KeyValueTree) SeekLast() (e *KeyValueEnumerator, err error) {
+	q := t.last
+	if q == nil {
+		return nil, io.EOF
+	}
+
+	return &KeyValueEnumerator{nil, true, q.c - 1, q.d[q.c-1].k, q, t, t.ver}, nil
+}
+
+// Set associates v with k.
+func (t *KeyValueTree) Set(k Key, v Value) {
+	pi := -1
+	var p *keyValueIndex
+	q := t.r
+	if q != nil {
+		for {
+			i, ok := t.find(q, k)
+			if ok {
+				switch x := q.(type) {
+				case *keyValueIndex:
+					x.x[i].sep.d[0].v = v
+				case *keyValueData:
+					x.d[i].v = v
+				}
+				return
+			}
+
+			switch x := q.(type) {
+			case *keyValueIndex:
+				if x.c > 2*keyValueKX {
+					t.splitX(p, &x, pi, &i)
+				}
+				pi = i
+				p = x
+				q = x.x[i].ch
+			case *keyValueData:
+				switch {
+				case x.c < 2*keyValueKD:
+					t.insert(x, i, k, v)
+				default:
+					t.overflow(p, x, pi, i, k, v)
+				}
+				return
+			}
+		}
+	}
+
+	z := t.insert(&keyValueData{}, 0, k, v)
+	t.r, t.first, t.last = z, z, z
+	return
+}
+
+// Put combines Get and Set in a more efficient way where the tree is walked
+// only once. The upd(ater) receives (oldV, true) if a KV pair for k exists or
+// (zero, false) otherwise. It can then return (newV, true) to create or
+// overwrite the V in the KV pair, or (whatever, false) if it decides not to
+// create or not to update the KV pair.
+//
+//	tree.Set(k, v) conceptually equals
+//
+//	tree.Put(k, func(k, v []byte){ return v, true }([]byte, bool))
+//
+// modulo the differing results.
+func (t *KeyValueTree) Put(k Key, upd func(oldV Value, exists bool) (newV Value, write bool)) (oldV Value, written bool) {
+	pi := -1
+	var p *keyValueIndex
+	q := t.r
+	var newV Value
+	if q != nil {
+		for {
+			i, ok := t.find(q, k)
+			if ok {
+				switch x := q.(type) {
+				case *keyValueIndex:
+					oldV = x.x[i].sep.d[0].v
+					newV, written = upd(oldV, true)
+					if !written {
+						return
+					}
+
+					x.x[i].sep.d[0].v = newV
+				case *keyValueData:
+					oldV = x.d[i].v
+					newV, written = upd(oldV, true)
+					if !written {
+						return
+					}
+
+					x.d[i].v = newV
+				}
+				return
+			}
+
+			switch x := q.(type) {
+			case *keyValueIndex:
+				if x.c > 2*keyValueKX {
+					t.splitX(p, &x, pi, &i)
+				}
+				pi = i
+				p = x
+				q = x.x[i].ch
+			case *keyValueData: // new KV pair
+				newV, written = upd(newV, false)
+				if !written {
+					return
+				}
+
+				switch {
+				case x.c < 2*keyValueKD:
+					t.insert(x, i, k, newV)
+				default:
+					t.overflow(p, x, pi, i, k, newV)
+				}
+				return
+			}
+		}
+	}
+
+	// new KV pair in empty tree
+	newV, written = upd(newV, false)
+	if !written {
+		return
+	}
+
+	z := t.insert(&keyValueData{}, 0, k, newV)
+	t.r, t.first, t.last = z, z, z
+	return
+}
+
+func (t *KeyValueTree) split(p *keyValueIndex, q *keyValueData, pi, i int, k Key, v Value) {
+	t.ver++
+	r := &keyValueData{}
+	if q.n != nil {
+		r.n = q.n
+		r.n.p = r
+	} else {
+		t.last = r
+	}
+	q.n = r
+	r.p = q
+
+	copy(r.d[:], q.d[keyValueKD:2*keyValueKD])
+	for i := range q.d[keyValueKD:] {
+		q.d[keyValueKD+i] = zeroKeyValueDataEntry
+	}
+	q.c = keyValueKD
+	r.c = keyValueKD
+	if pi >= 0 {
+		p.insert(pi, r, r)
+	} else {
+		t.r = newKeyValueIndex(q).insert(0, r, r)
+	}
+	if i > keyValueKD {
+		t.insert(r, i-keyValueKD, k, v)
+		return
+	}
+
+	t.insert(q, i, k, v)
+}
+
+func (t *KeyValueTree) splitX(p *keyValueIndex, pp **keyValueIndex, pi int, i *int) {
+	t.ver++
+	q := *pp
+	r := &keyValueIndex{}
+	copy(r.x[:], q.x[keyValueKX+1:])
+	q.c = keyValueKX
+	r.c = keyValueKX
+	if pi >= 0 {
+		p.insert(pi, q.x[keyValueKX].sep, r)
+	} else {
+		t.r = newKeyValueIndex(q).insert(0, q.x[keyValueKX].sep, r)
+	}
+	q.x[keyValueKX].sep = nil
+	for i := range q.x[keyValueKX+1:] {
+		q.x[keyValueKX+i+1] = zeroKeyValueIndexEntry
+	}
+	if *i > keyValueKX {
+		*pp = r
+		*i -= keyValueKX + 1
+	}
+}
+
+func (t *KeyValueTree) underflow(p *keyValueIndex, q *keyValueData, pi int) {
+	t.ver++
+	l, r := p.siblings(pi)
+
+	if l != nil && l.c+q.c >= 2*keyValueKD {
+		l.mvR(q, 1)
+	} else if r != nil && q.c+r.c >= 2*keyValueKD {
+		q.mvL(r, 1)
+		r.d[r.c] = zeroKeyValueDataEntry // GC
+	} else if l != nil {
+		t.cat(p, l, q, pi-1)
+	} else {
+		t.cat(p, q, r, pi)
+	}
+}
+
+func (t *KeyValueTree) underflowX(p *keyValueIndex, pp **keyValueIndex, pi int, i *int) {
+	t.ver++
+	var l, r *keyValueIndex
+	q := *pp
+
+	if pi >= 0 {
+		if pi > 0 {
+			l = p.x[pi-1].ch.(*keyValueIndex)
+		}
+		if pi < p.c {
+			r = p.x[pi+1].ch.(*keyValueIndex)
+		}
+	}
+
+	if l != nil && l.c > keyValueKX {
+		q.x[q.c+1].ch = q.x[q.c].ch
+		copy(q.x[1:], q.x[:q.c])
+		q.x[0].ch = l.x[l.c].ch
+		q.x[0].sep = p.x[pi-1].sep
+		q.c++
+		*i++
+		l.c--
+		p.x[pi-1].sep = l.x[l.c].sep
+		return
+	}
+
+	if r != nil && r.c > keyValueKX {
+		q.x[q.c].sep = p.x[pi].sep
+		q.c++
+		q.x[q.c].ch = r.x[0].ch
+		p.x[pi].sep = r.x[0].sep
+		copy(r.x[:], r.x[1:r.c])
+		r.c--
+		rc := r.c
+		r.x[rc].ch = r.x[rc+1].ch
+		r.x[rc].sep = nil
+		r.x[rc+1].ch = nil
+		return
+	}
+
+	if l != nil {
+		*i += l.c + 1
+		t.catX(p, l, q, pi-1)
+		*pp = l
+		return
+	}
+
+	t.catX(p, q, r, pi)
+}
+
+// ----------------------------------------------------------------- Enumerator
+
+// Next returns the currently enumerated item, if it exists and moves to the
+// next item in collation order. If there is no item to return, err == io.EOF
+// is returned.
+func (e *KeyValueEnumerator) Next() (k Key, v Value, err error) {
+	if err = e.err; err != nil {
+		return
+	}
+
+	if e.ver != e.t.ver {
+		f, hit := e.t.Seek(e.k)
+		if !e.hit && hit {
+			if err = f.next(); err != nil {
+				return
+			}
+		}
+
+		*e = *f
+	}
+	if e.q == nil {
+		e.err, err = io.EOF, io.EOF
+		return
+	}
+
+	if e.i >= e.q.c {
+		if err = e.next(); err != nil {
+			return
+		}
+	}
+
+	i := e.q.d[e.i]
+	k, v = i.k, i.v
+	e.k, e.hit = k, false
+	e.next()
+	return
+}
+
+func (e *KeyValueEnumerator) next() error {
+	if e.q == nil {
+		e.err = io.EOF
+		return io.EOF
+	}
+
+	switch {
+	case e.i < e.q.c-1:
+		e.i++
+	default:
+		if e.q, e.i = e.q.n, 0; e.q == nil {
+			e.err = io.EOF
+		}
+	}
+	return e.err
+}
+
+// Prev returns the currently enumerated item, if it exists and moves to the
+// previous item in collation order. If there is no item to return, err ==
+// io.EOF is returned.
+func (e *KeyValueEnumerator) Prev() (k Key, v Value, err error) {
+	if err = e.err; err != nil {
+		return
+	}
+
+	if e.ver != e.t.ver {
+		f, hit := e.t.Seek(e.k)
+		if !e.hit && hit {
+			if err = f.prev(); err != nil {
+				return
+			}
+		}
+
+		*e = *f
+	}
+	if e.q == nil {
+		e.err, err = io.EOF, io.EOF
+		return
+	}
+
+	if e.i >= e.q.c {
+		if err = e.next(); err != nil {
+			return
+		}
+	}
+
+	i := e.q.d[e.i]
+	k, v = i.k, i.v
+	e.k, e.hit = k, false
+	e.prev()
+	return
+}
+
+func (e *KeyValueEnumerator) prev() error {
+	if e.q == nil {
+		e.err = io.EOF
+		return io.EOF
+	}
+
+	switch {
+	case e.i > 0:
+		e.i--
+	default:
+		if e.q = e.q.p; e.q == nil {
+			e.err = io.EOF
+			break
+		}
+
+		e.i = e.q.c - 1
+	}
+	return e.err
+}
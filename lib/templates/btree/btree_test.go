@@ -0,0 +1,125 @@
+package btree
+
+import (
+	"io"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+//genny:sample []byte []byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")
+//genny:sample *time.Time &[]time.Time{time.Unix(1, 0)}[0], &[]time.Time{time.Unix(2, 0)}[0], &[]time.Time{time.Unix(3, 0)}[0], &[]time.Time{time.Unix(4, 0)}[0], &[]time.Time{time.Unix(5, 0)}[0]
+
+// keyValueVs are different values.
+//
+//genny:samples
+var keyValueVs = []Value{1, 2, 3, 4, 5}
+
+// keyValueKOf gets the i-th of the keys of the tests, which are all
+// different. There are more of them than samples, so that the tree has
+// several levels.
+func keyValueKOf(i int) Key {
+	var k Key
+	switch p := any(&k).(type) {
+	case *string:
+		*p = strconv.Itoa(i)
+	default:
+		k = any(i).(Key)
+	}
+	return k
+}
+
+// keyValueVOf gets the value the tests map the i-th key to.
+func keyValueVOf(i int) Value {
+	return keyValueVs[i%len(keyValueVs)]
+}
+
+func TestKeyValueTree(t *testing.T) {
+	const n = 20000
+	ks := make([]Key, n)
+	order := make(map[Key]int, n)
+	for i := range ks {
+		ks[i] = keyValueKOf(i)
+		order[ks[i]] = i
+	}
+	tree := NewKeyValueTree(func(a, b Key) int { return order[a] - order[b] })
+
+	// check checks that the tree has the keys from 'from' to n by step, both
+	// ways
+	check := func(from, step int) {
+		t.Helper()
+		if want := (n - from + step - 1) / step; tree.Len() != want {
+			t.Fatalf("the tree should have %d items: got %d", want, tree.Len())
+		}
+		e, err := tree.SeekFirst()
+		for i := from; i < n; i += step {
+			k, v, err := e.Next()
+			if err != nil || k != ks[i] || !reflect.DeepEqual(v, keyValueVOf(i)) {
+				t.Fatalf("Next should get item %d: got %v, %v, %v", i, k, v, err)
+			}
+		}
+		if _, _, err = e.Next(); err != io.EOF {
+			t.Fatalf("Next should get io.EOF after the last item: got %v", err)
+		}
+		e, err = tree.SeekLast()
+		for i := from + (n-1-from)/step*step; i >= from; i -= step {
+			k, _, err := e.Prev()
+			if err != nil || k != ks[i] {
+				t.Fatalf("Prev should get item %d: got %v, %v", i, k, err)
+			}
+		}
+		if _, _, err = e.Prev(); err != io.EOF {
+			t.Fatalf("Prev should get io.EOF before the first item: got %v", err)
+		}
+	}
+
+	for j := 0; j < n; j++ {
+		i := j * 7919 % n
+		tree.Set(ks[i], keyValueVOf(i))
+	}
+	for i, k := range ks {
+		if v, ok := tree.Get(k); !ok || !reflect.DeepEqual(v, keyValueVOf(i)) {
+			t.Fatalf("Get should get the value of key %d: got %v, %v", i, v, ok)
+		}
+	}
+	check(0, 1)
+
+	for j := 0; j < n; j++ {
+		if i := j * 7919 % n; i%2 == 0 && !tree.Delete(ks[i]) {
+			t.Fatalf("Delete should delete key %d", i)
+		}
+	}
+	if tree.Delete(ks[0]) {
+		t.Errorf("Delete should fail for a key that isn't in the tree")
+	}
+	if _, ok := tree.Get(ks[0]); ok {
+		t.Errorf("Get should fail for a deleted key")
+	}
+	check(1, 2)
+
+	if k, _ := tree.First(); k != ks[1] {
+		t.Errorf("First should get the first key: got %v", k)
+	}
+	if k, _ := tree.Last(); k != ks[n-1] {
+		t.Errorf("Last should get the last key: got %v", k)
+	}
+	e, ok := tree.Seek(ks[10])
+	if k, _, err := e.Next(); ok || err != nil || k != ks[11] {
+		t.Errorf("Seek should get to the next key after a deleted one: got %v, %v, %v", k, ok, err)
+	}
+
+	old, written := tree.Put(ks[1], func(old Value, exists bool) (Value, bool) {
+		return keyValueVOf(2), exists
+	})
+	if !written || !reflect.DeepEqual(old, keyValueVOf(1)) {
+		t.Errorf("Put should replace the value of a key: got %v, %v", old, written)
+	}
+	if v, _ := tree.Get(ks[1]); !reflect.DeepEqual(v, keyValueVOf(2)) {
+		t.Errorf("Put should replace the value of a key: got %v", v)
+	}
+
+	tree.Clear()
+	if _, err := tree.SeekFirst(); tree.Len() != 0 || err != io.EOF {
+		t.Errorf("Clear should empty the tree")
+	}
+}
@@ -0,0 +1,3 @@
+// Package btree is a genny template of a B+tree, an ordered map that can be
+// enumerated in order from any point.
+package btree
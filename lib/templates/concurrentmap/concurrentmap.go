@@ -0,0 +1,88 @@
+package concurrentmap
+
+import (
+	"sync"
+
+	"github.com/tehbilly/genny/generic"
+)
+
+// Key is the type of the keys of the map. It has to be comparable.
+type Key generic.Type
+
+// Value is the type of the values of the map.
+type Value generic.Type
+
+// KeyValueConcurrentMap is a map guarded by a read-write lock, so that it
+// can be used by several goroutines at once. A zero map is empty and ready
+// to use.
+type KeyValueConcurrentMap struct {
+	mu      sync.RWMutex
+	entries map[Key]Value
+}
+
+// NewKeyValueConcurrentMap makes an empty map with room for n entries.
+func NewKeyValueConcurrentMap(n int) *KeyValueConcurrentMap {
+	return &KeyValueConcurrentMap{entries: make(map[Key]Value, n)}
+}
+
+// Load gets what k maps to. ok is false if k isn't in the map.
+func (m *KeyValueConcurrentMap) Load(k Key) (v Value, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok = m.entries[k]
+	return v, ok
+}
+
+// Store maps k to v.
+func (m *KeyValueConcurrentMap) Store(k Key, v Value) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.entries == nil {
+		m.entries = make(map[Key]Value)
+	}
+	m.entries[k] = v
+}
+
+// LoadOrStore gets what k maps to if it is in the map. Otherwise it maps k
+// to v and gets v. loaded is whether k was in the map.
+func (m *KeyValueConcurrentMap) LoadOrStore(k Key, v Value) (actual Value, loaded bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if actual, loaded = m.entries[k]; loaded {
+		return actual, true
+	}
+	if m.entries == nil {
+		m.entries = make(map[Key]Value)
+	}
+	m.entries[k] = v
+	return v, false
+}
+
+// Delete removes the entry of k from the map. It gets whether there was one.
+func (m *KeyValueConcurrentMap) Delete(k Key) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.entries[k]
+	delete(m.entries, k)
+	return ok
+}
+
+// Len gets the number of entries in the map.
+func (m *KeyValueConcurrentMap) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.entries)
+}
+
+// Range calls f for the entries of the map, in no particular order, until f
+// returns false. The map is locked for reading meanwhile, so f mustn't
+// change it.
+func (m *KeyValueConcurrentMap) Range(f func(k Key, v Value) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for k, v := range m.entries {
+		if !f(k, v) {
+			return
+		}
+	}
+}
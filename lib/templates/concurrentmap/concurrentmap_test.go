@@ -0,0 +1,73 @@
+package concurrentmap
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+//genny:sample []byte []byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")
+//genny:sample *time.Time &[]time.Time{time.Unix(1, 0)}[0], &[]time.Time{time.Unix(2, 0)}[0], &[]time.Time{time.Unix(3, 0)}[0], &[]time.Time{time.Unix(4, 0)}[0], &[]time.Time{time.Unix(5, 0)}[0]
+
+// keyValueKs are different keys.
+//
+//genny:samples
+var keyValueKs = []Key{1, 2, 3, 4, 5}
+
+// keyValueVs are different values.
+//
+//genny:samples
+var keyValueVs = []Value{1, 2, 3, 4, 5}
+
+func TestKeyValueConcurrentMap(t *testing.T) {
+	m := NewKeyValueConcurrentMap(0)
+	m.Store(keyValueKs[0], keyValueVs[0])
+	if v, ok := m.Load(keyValueKs[0]); !ok || !reflect.DeepEqual(v, keyValueVs[0]) {
+		t.Errorf("Load should get the value stored: got %v, %v", v, ok)
+	}
+	if v, loaded := m.LoadOrStore(keyValueKs[0], keyValueVs[1]); !loaded || !reflect.DeepEqual(v, keyValueVs[0]) {
+		t.Errorf("LoadOrStore should get the value of a key in the map: got %v, %v", v, loaded)
+	}
+	if v, loaded := m.LoadOrStore(keyValueKs[1], keyValueVs[1]); loaded || !reflect.DeepEqual(v, keyValueVs[1]) {
+		t.Errorf("LoadOrStore should store the value of a new key: got %v, %v", v, loaded)
+	}
+	if m.Len() != 2 {
+		t.Errorf("the map should have two entries: got %d", m.Len())
+	}
+	if !m.Delete(keyValueKs[0]) || m.Delete(keyValueKs[0]) {
+		t.Errorf("Delete should get whether the key was in the map")
+	}
+	if _, ok := m.Load(keyValueKs[0]); ok {
+		t.Errorf("Load should fail for a deleted key")
+	}
+}
+
+func TestKeyValueConcurrentMapConcurrently(t *testing.T) {
+	m := NewKeyValueConcurrentMap(0)
+	var wg sync.WaitGroup
+	for i := range keyValueKs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.Store(keyValueKs[i], keyValueVs[i])
+				m.Load(keyValueKs[(i+1)%len(keyValueKs)])
+				m.Range(func(k Key, v Value) bool { return true })
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := 0
+	m.Range(func(k Key, v Value) bool {
+		for i, sample := range keyValueKs {
+			if sample == k && reflect.DeepEqual(v, keyValueVs[i]) {
+				seen++
+			}
+		}
+		return true
+	})
+	if seen != len(keyValueKs) || m.Len() != len(keyValueKs) {
+		t.Errorf("Range should go over every entry stored: got %d of %d", seen, m.Len())
+	}
+}
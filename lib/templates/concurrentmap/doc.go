@@ -0,0 +1,3 @@
+// Package concurrentmap is a genny template of a map that is safe for
+// concurrent use.
+package concurrentmap
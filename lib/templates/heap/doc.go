@@ -0,0 +1,3 @@
+// Package heap is a genny template of a binary heap, a priority queue that
+// pops its least item first.
+package heap
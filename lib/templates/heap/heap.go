@@ -0,0 +1,83 @@
+package heap
+
+import "github.com/tehbilly/genny/generic"
+
+// Elem is the type of the items in the heap.
+type Elem generic.Type
+
+// ElemHeap is a binary min-heap ordered by a less function. The item it pops
+// is always one that no other item is less than.
+type ElemHeap struct {
+	items []Elem
+	less  func(a, b Elem) bool
+}
+
+// NewElemHeap makes an empty heap ordered by less, which reports whether a
+// goes before b.
+func NewElemHeap(less func(a, b Elem) bool) *ElemHeap {
+	return &ElemHeap{less: less}
+}
+
+// Push adds x to the heap.
+func (h *ElemHeap) Push(x Elem) {
+	h.items = append(h.items, x)
+	h.up(len(h.items) - 1)
+}
+
+// Pop removes and returns the least item of the heap. ok is false if the
+// heap is empty.
+func (h *ElemHeap) Pop() (x Elem, ok bool) {
+	if len(h.items) == 0 {
+		return x, false
+	}
+	last := len(h.items) - 1
+	x = h.items[0]
+	h.items[0] = h.items[last]
+	var zero Elem
+	h.items[last] = zero
+	h.items = h.items[:last]
+	h.down(0)
+	return x, true
+}
+
+// Peek gets the least item of the heap without removing it. ok is false if
+// the heap is empty.
+func (h *ElemHeap) Peek() (x Elem, ok bool) {
+	if len(h.items) == 0 {
+		return x, false
+	}
+	return h.items[0], true
+}
+
+// Len gets the number of items in the heap.
+func (h *ElemHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *ElemHeap) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !h.less(h.items[i], h.items[parent]) {
+			return
+		}
+		h.items[i], h.items[parent] = h.items[parent], h.items[i]
+		i = parent
+	}
+}
+
+func (h *ElemHeap) down(i int) {
+	for {
+		least, left, right := i, 2*i+1, 2*i+2
+		if left < len(h.items) && h.less(h.items[left], h.items[least]) {
+			least = left
+		}
+		if right < len(h.items) && h.less(h.items[right], h.items[least]) {
+			least = right
+		}
+		if least == i {
+			return
+		}
+		h.items[i], h.items[least] = h.items[least], h.items[i]
+		i = least
+	}
+}
@@ -0,0 +1,64 @@
+package heap
+
+import "testing"
+
+//genny:sample *time.Time &[]time.Time{time.Unix(1, 0)}[0], &[]time.Time{time.Unix(2, 0)}[0], &[]time.Time{time.Unix(3, 0)}[0], &[]time.Time{time.Unix(4, 0)}[0], &[]time.Time{time.Unix(5, 0)}[0]
+
+// elemSamples are different items, in the order elemLess sorts them in.
+//
+//genny:samples
+var elemSamples = []Elem{1, 2, 3, 4, 5}
+
+// elemLess orders the items as elemSamples does.
+func elemLess(a, b Elem) bool {
+	return elemIndex(a) < elemIndex(b)
+}
+
+// elemIndex gets the index of x in elemSamples.
+func elemIndex(x Elem) int {
+	for i, sample := range elemSamples {
+		if sample == x {
+			return i
+		}
+	}
+	panic("not a sample")
+}
+
+func TestElemHeap(t *testing.T) {
+	h := NewElemHeap(elemLess)
+	for _, i := range []int{3, 0, 4, 1, 2, 0} {
+		h.Push(elemSamples[i])
+	}
+	if h.Len() != 6 {
+		t.Errorf("Push should add the items: got %d items", h.Len())
+	}
+	if x, ok := h.Peek(); !ok || x != elemSamples[0] {
+		t.Errorf("Peek should get the least item: got %v, %v", x, ok)
+	}
+	for _, want := range []int{0, 0, 1, 2, 3, 4} {
+		if x, ok := h.Pop(); !ok || x != elemSamples[want] {
+			t.Fatalf("Pop should get the least item: got %v, %v, want %v", x, ok, elemSamples[want])
+		}
+	}
+	if _, ok := h.Pop(); ok {
+		t.Errorf("Pop should fail on an empty heap")
+	}
+	if _, ok := h.Peek(); ok {
+		t.Errorf("Peek should fail on an empty heap")
+	}
+}
+
+func TestElemHeapMany(t *testing.T) {
+	h := NewElemHeap(elemLess)
+	for i := 0; i < 100; i++ {
+		h.Push(elemSamples[i*7%len(elemSamples)])
+	}
+	last := 0
+	for h.Len() > 0 {
+		x, _ := h.Pop()
+		if elemIndex(x) < last {
+			t.Fatalf("Pop should get the items in order: got %v after %v", x, elemSamples[last])
+		}
+		last = elemIndex(x)
+	}
+}
@@ -0,0 +1,3 @@
+// Package lru is a genny template of a cache that evicts its least recently
+// used entries.
+package lru
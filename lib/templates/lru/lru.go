@@ -0,0 +1,136 @@
+package lru
+
+import "github.com/tehbilly/genny/generic"
+
+// Key is the type of the keys of the cache. It has to be comparable.
+type Key generic.Type
+
+// Value is the type of the values of the cache.
+type Value generic.Type
+
+// KeyValueLRU is a cache that holds up to a number of entries, evicting the
+// least recently used one to make room for another. It is not safe for
+// concurrent use.
+type KeyValueLRU struct {
+	// OnEvicted, if not nil, is called with each entry that is evicted.
+	OnEvicted func(k Key, v Value)
+
+	capacity int
+	entries  map[Key]*keyValueLRUEntry
+	// newest and oldest are the ends of the list of entries, from the most
+	// recently used to the least.
+	newest, oldest *keyValueLRUEntry
+}
+
+type keyValueLRUEntry struct {
+	k            Key
+	v            Value
+	newer, older *keyValueLRUEntry
+}
+
+// NewKeyValueLRU makes an empty cache that holds up to capacity entries, or
+// any number of them if capacity is zero.
+func NewKeyValueLRU(capacity int) *KeyValueLRU {
+	return &KeyValueLRU{capacity: capacity, entries: make(map[Key]*keyValueLRUEntry)}
+}
+
+// Add maps k to v, making it the most recently used entry. It gets whether
+// an entry was evicted to make room for it.
+func (c *KeyValueLRU) Add(k Key, v Value) (evicted bool) {
+	if e, ok := c.entries[k]; ok {
+		e.v = v
+		c.use(e)
+		return false
+	}
+	e := &keyValueLRUEntry{k: k, v: v}
+	c.entries[k] = e
+	c.pushNewest(e)
+	if c.capacity > 0 && len(c.entries) > c.capacity {
+		c.RemoveOldest()
+		return true
+	}
+	return false
+}
+
+// Get gets what k maps to, making it the most recently used entry. ok is
+// false if k isn't in the cache.
+func (c *KeyValueLRU) Get(k Key) (v Value, ok bool) {
+	e, ok := c.entries[k]
+	if !ok {
+		return v, false
+	}
+	c.use(e)
+	return e.v, true
+}
+
+// Peek gets what k maps to without using the entry. ok is false if k isn't
+// in the cache.
+func (c *KeyValueLRU) Peek(k Key) (v Value, ok bool) {
+	e, ok := c.entries[k]
+	if !ok {
+		return v, false
+	}
+	return e.v, true
+}
+
+// Remove removes the entry of k from the cache, without calling OnEvicted.
+// It gets whether there was one.
+func (c *KeyValueLRU) Remove(k Key) bool {
+	e, ok := c.entries[k]
+	if !ok {
+		return false
+	}
+	c.unlink(e)
+	delete(c.entries, k)
+	return true
+}
+
+// RemoveOldest evicts the least recently used entry, if any.
+func (c *KeyValueLRU) RemoveOldest() {
+	e := c.oldest
+	if e == nil {
+		return
+	}
+	c.unlink(e)
+	delete(c.entries, e.k)
+	if c.OnEvicted != nil {
+		c.OnEvicted(e.k, e.v)
+	}
+}
+
+// Len gets the number of entries in the cache.
+func (c *KeyValueLRU) Len() int {
+	return len(c.entries)
+}
+
+// use makes e the most recently used entry.
+func (c *KeyValueLRU) use(e *keyValueLRUEntry) {
+	if c.newest != e {
+		c.unlink(e)
+		c.pushNewest(e)
+	}
+}
+
+func (c *KeyValueLRU) pushNewest(e *keyValueLRUEntry) {
+	e.older = c.newest
+	if c.newest != nil {
+		c.newest.newer = e
+	} else {
+		c.oldest = e
+	}
+	c.newest = e
+}
+
+func (c *KeyValueLRU) unlink(e *keyValueLRUEntry) {
+	if e.newer != nil {
+		e.newer.older = e.older
+	} else {
+		c.newest = e.older
+	}
+	if e.older != nil {
+		e.older.newer = e.newer
+	} else {
+		c.oldest = e.newer
+	}
+	e.newer, e.older = nil, nil
+}
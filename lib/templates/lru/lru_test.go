@@ -0,0 +1,77 @@
+package lru
+
+import (
+	"reflect"
+	"testing"
+)
+
+//genny:sample []byte []byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")
+//genny:sample *time.Time &[]time.Time{time.Unix(1, 0)}[0], &[]time.Time{time.Unix(2, 0)}[0], &[]time.Time{time.Unix(3, 0)}[0], &[]time.Time{time.Unix(4, 0)}[0], &[]time.Time{time.Unix(5, 0)}[0]
+
+// keyValueKs are different keys.
+//
+//genny:samples
+var keyValueKs = []Key{1, 2, 3, 4, 5}
+
+// keyValueVs are different values.
+//
+//genny:samples
+var keyValueVs = []Value{1, 2, 3, 4, 5}
+
+func TestKeyValueLRU(t *testing.T) {
+	c := NewKeyValueLRU(0)
+	for i, k := range keyValueKs {
+		if c.Add(k, keyValueVs[i]) {
+			t.Errorf("Add should not evict entries from a cache without a capacity")
+		}
+	}
+	if c.Len() != len(keyValueKs) {
+		t.Errorf("Add should add the entries: got %d entries", c.Len())
+	}
+	for i, k := range keyValueKs {
+		if v, ok := c.Get(k); !ok || !reflect.DeepEqual(v, keyValueVs[i]) {
+			t.Errorf("Get should get the value of the key: got %v, %v, want %v", v, ok, keyValueVs[i])
+		}
+	}
+	c.Add(keyValueKs[0], keyValueVs[1])
+	if v, ok := c.Peek(keyValueKs[0]); !ok || !reflect.DeepEqual(v, keyValueVs[1]) || c.Len() != len(keyValueKs) {
+		t.Errorf("Add should replace the value of a key: got %v, %v", v, ok)
+	}
+	if !c.Remove(keyValueKs[0]) || c.Remove(keyValueKs[0]) {
+		t.Errorf("Remove should get whether the key was in the cache")
+	}
+	if _, ok := c.Get(keyValueKs[0]); ok {
+		t.Errorf("Get should fail for a removed key")
+	}
+}
+
+func TestKeyValueLRUEviction(t *testing.T) {
+	c := NewKeyValueLRU(2)
+	var evicted []Key
+	c.OnEvicted = func(k Key, v Value) {
+		evicted = append(evicted, k)
+	}
+	c.Add(keyValueKs[0], keyValueVs[0])
+	c.Add(keyValueKs[1], keyValueVs[1])
+	c.Get(keyValueKs[0])
+	if !c.Add(keyValueKs[2], keyValueVs[2]) {
+		t.Errorf("Add should evict an entry from a full cache")
+	}
+	if _, ok := c.Peek(keyValueKs[1]); ok || len(evicted) != 1 || evicted[0] != keyValueKs[1] {
+		t.Errorf("Add should evict the least recently used entry: evicted %v", evicted)
+	}
+
+	// Peek doesn't use the entry, so the first key is evicted next
+	c.Peek(keyValueKs[0])
+	c.Add(keyValueKs[3], keyValueVs[3])
+	if _, ok := c.Peek(keyValueKs[0]); ok || len(evicted) != 2 || evicted[1] != keyValueKs[0] {
+		t.Errorf("Add should evict the least recently used entry: evicted %v", evicted)
+	}
+
+	c.Remove(keyValueKs[2])
+	c.RemoveOldest()
+	c.RemoveOldest()
+	if c.Len() != 0 || len(evicted) != 3 || evicted[2] != keyValueKs[3] {
+		t.Errorf("RemoveOldest should evict the remaining entry, and Remove not call OnEvicted: evicted %v", evicted)
+	}
+}
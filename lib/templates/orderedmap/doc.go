@@ -0,0 +1,3 @@
+// Package orderedmap is a genny template of a map that remembers the order
+// its entries were set in.
+package orderedmap
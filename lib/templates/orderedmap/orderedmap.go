@@ -0,0 +1,93 @@
+package orderedmap
+
+import "github.com/tehbilly/genny/generic"
+
+// Key is the type of the keys of the map. It has to be comparable.
+type Key generic.Type
+
+// Value is the type of the values of the map.
+type Value generic.Type
+
+// KeyValueOrderedMap is a map that ranges over its entries in the order
+// they were first set in. A zero map is empty and ready to use.
+type KeyValueOrderedMap struct {
+	entries     map[Key]*keyValueOrderedEntry
+	first, last *keyValueOrderedEntry
+}
+
+type keyValueOrderedEntry struct {
+	k          Key
+	v          Value
+	prev, next *keyValueOrderedEntry
+}
+
+// NewKeyValueOrderedMap makes an empty map with room for n entries.
+func NewKeyValueOrderedMap(n int) *KeyValueOrderedMap {
+	return &KeyValueOrderedMap{entries: make(map[Key]*keyValueOrderedEntry, n)}
+}
+
+// Get gets what k maps to. ok is false if k isn't in the map.
+func (m *KeyValueOrderedMap) Get(k Key) (v Value, ok bool) {
+	e, ok := m.entries[k]
+	if !ok {
+		return v, false
+	}
+	return e.v, true
+}
+
+// Set maps k to v. A new entry goes last; an entry that was set before keeps
+// its place.
+func (m *KeyValueOrderedMap) Set(k Key, v Value) {
+	if e, ok := m.entries[k]; ok {
+		e.v = v
+		return
+	}
+	if m.entries == nil {
+		m.entries = make(map[Key]*keyValueOrderedEntry)
+	}
+	e := &keyValueOrderedEntry{k: k, v: v, prev: m.last}
+	if m.last != nil {
+		m.last.next = e
+	} else {
+		m.first = e
+	}
+	m.last = e
+	m.entries[k] = e
+}
+
+// Delete removes the entry of k from the map. It gets whether there was one.
+func (m *KeyValueOrderedMap) Delete(k Key) bool {
+	e, ok := m.entries[k]
+	if !ok {
+		return false
+	}
+	delete(m.entries, k)
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		m.first = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		m.last = e.prev
+	}
+	return true
+}
+
+// Len gets the number of entries in the map.
+func (m *KeyValueOrderedMap) Len() int {
+	return len(m.entries)
+}
+
+// Range calls f for the entries of the map in order, until f returns false.
+// f may delete the entry it is called for.
+func (m *KeyValueOrderedMap) Range(f func(k Key, v Value) bool) {
+	for e := m.first; e != nil; {
+		next := e.next
+		if !f(e.k, e.v) {
+			return
+		}
+		e = next
+	}
+}
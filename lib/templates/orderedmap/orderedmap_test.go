@@ -0,0 +1,68 @@
+package orderedmap
+
+import (
+	"reflect"
+	"testing"
+)
+
+//genny:sample []byte []byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")
+//genny:sample *time.Time &[]time.Time{time.Unix(1, 0)}[0], &[]time.Time{time.Unix(2, 0)}[0], &[]time.Time{time.Unix(3, 0)}[0], &[]time.Time{time.Unix(4, 0)}[0], &[]time.Time{time.Unix(5, 0)}[0]
+
+// keyValueKs are different keys.
+//
+//genny:samples
+var keyValueKs = []Key{1, 2, 3, 4, 5}
+
+// keyValueVs are different values.
+//
+//genny:samples
+var keyValueVs = []Value{1, 2, 3, 4, 5}
+
+// keyValueOrder gets the indexes in keyValueKs of the keys of m, in the
+// order m ranges over them.
+func keyValueOrder(m *KeyValueOrderedMap) []int {
+	var order []int
+	m.Range(func(k Key, v Value) bool {
+		for i, sample := range keyValueKs {
+			if sample == k {
+				order = append(order, i)
+			}
+		}
+		return true
+	})
+	return order
+}
+
+func TestKeyValueOrderedMap(t *testing.T) {
+	m := NewKeyValueOrderedMap(0)
+	for _, i := range []int{3, 1, 4, 0} {
+		m.Set(keyValueKs[i], keyValueVs[i])
+	}
+	m.Set(keyValueKs[1], keyValueVs[2])
+	if got := keyValueOrder(m); !reflect.DeepEqual(got, []int{3, 1, 4, 0}) {
+		t.Errorf("Range should go over the keys in the order they were first set: got %v", got)
+	}
+	if v, ok := m.Get(keyValueKs[1]); !ok || !reflect.DeepEqual(v, keyValueVs[2]) {
+		t.Errorf("Set should replace the value of a key: got %v, %v", v, ok)
+	}
+	if _, ok := m.Get(keyValueKs[2]); ok {
+		t.Errorf("Get should fail for a key that wasn't set")
+	}
+
+	if !m.Delete(keyValueKs[1]) || m.Delete(keyValueKs[1]) {
+		t.Errorf("Delete should get whether the key was in the map")
+	}
+	m.Set(keyValueKs[1], keyValueVs[1])
+	if got := keyValueOrder(m); !reflect.DeepEqual(got, []int{3, 4, 0, 1}) || m.Len() != 4 {
+		t.Errorf("a key set again after Delete should go last: got %v", got)
+	}
+
+	n := 0
+	m.Range(func(k Key, v Value) bool {
+		n++
+		return n < 2
+	})
+	if n != 2 {
+		t.Errorf("Range should stop when f returns false: called %d times", n)
+	}
+}
@@ -0,0 +1,2 @@
+// Package queue is a genny template of a first-in first-out queue.
+package queue
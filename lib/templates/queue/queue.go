@@ -0,0 +1,65 @@
+package queue
+
+import "github.com/tehbilly/genny/generic"
+
+// Elem is the type of the items in the queue.
+type Elem generic.Type
+
+// ElemQueue is a first-in first-out queue. The zero value is an empty queue.
+type ElemQueue struct {
+	items []Elem
+	head  int
+}
+
+// NewElemQueue makes an empty queue with room for n items.
+func NewElemQueue(n int) *ElemQueue {
+	return &ElemQueue{items: make([]Elem, 0, n)}
+}
+
+// Push adds x to the back of the queue.
+func (q *ElemQueue) Push(x Elem) {
+	if q.head > 0 && len(q.items) == cap(q.items) {
+		// reuse the room in front rather than growing
+		n := copy(q.items, q.items[q.head:])
+		q.clear(n, len(q.items))
+		q.items, q.head = q.items[:n], 0
+	}
+	q.items = append(q.items, x)
+}
+
+// Pop removes and returns the item at the front of the queue. ok is false
+// if the queue is empty.
+func (q *ElemQueue) Pop() (x Elem, ok bool) {
+	if q.head == len(q.items) {
+		return x, false
+	}
+	x = q.items[q.head]
+	q.clear(q.head, q.head+1)
+	q.head++
+	if q.head == len(q.items) {
+		q.items, q.head = q.items[:0], 0
+	}
+	return x, true
+}
+
+// Peek gets the item at the front of the queue without removing it. ok is
+// false if the queue is empty.
+func (q *ElemQueue) Peek() (x Elem, ok bool) {
+	if q.head == len(q.items) {
+		return x, false
+	}
+	return q.items[q.head], true
+}
+
+// Len gets the number of items in the queue.
+func (q *ElemQueue) Len() int {
+	return len(q.items) - q.head
+}
+
+// clear zeroes the items from i to j, so that they can be collected.
+func (q *ElemQueue) clear(i, j int) {
+	var zero Elem
+	for ; i < j; i++ {
+		q.items[i] = zero
+	}
+}
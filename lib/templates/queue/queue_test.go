@@ -0,0 +1,55 @@
+package queue
+
+import "testing"
+
+//genny:sample *time.Time &[]time.Time{time.Unix(1, 0)}[0], &[]time.Time{time.Unix(2, 0)}[0], &[]time.Time{time.Unix(3, 0)}[0], &[]time.Time{time.Unix(4, 0)}[0], &[]time.Time{time.Unix(5, 0)}[0]
+
+// elemSamples are different items.
+//
+//genny:samples
+var elemSamples = []Elem{1, 2, 3, 4, 5}
+
+func TestElemQueue(t *testing.T) {
+	q := NewElemQueue(2)
+	for _, x := range elemSamples {
+		q.Push(x)
+	}
+	if q.Len() != len(elemSamples) {
+		t.Errorf("Push should add the items: got %d items", q.Len())
+	}
+	if x, ok := q.Peek(); !ok || x != elemSamples[0] {
+		t.Errorf("Peek should get the first item: got %v, %v", x, ok)
+	}
+	for _, want := range elemSamples {
+		if x, ok := q.Pop(); !ok || x != want {
+			t.Fatalf("Pop should get the items in the order they were pushed: got %v, %v, want %v", x, ok, want)
+		}
+	}
+	if _, ok := q.Pop(); ok {
+		t.Errorf("Pop should fail on an empty queue")
+	}
+	if _, ok := q.Peek(); ok {
+		t.Errorf("Peek should fail on an empty queue")
+	}
+}
+
+func TestElemQueueReuse(t *testing.T) {
+	var q ElemQueue
+	pushed, popped := 0, 0
+	for round := 0; round < 10; round++ {
+		for i := 0; i < 3; i++ {
+			q.Push(elemSamples[pushed%len(elemSamples)])
+			pushed++
+		}
+		for i := 0; i < 2; i++ {
+			x, ok := q.Pop()
+			if want := elemSamples[popped%len(elemSamples)]; !ok || x != want {
+				t.Fatalf("Pop should get the items in the order they were pushed: got %v, %v, want %v", x, ok, want)
+			}
+			popped++
+		}
+	}
+	if q.Len() != pushed-popped {
+		t.Errorf("Len should get the number of items: got %d, want %d", q.Len(), pushed-popped)
+	}
+}
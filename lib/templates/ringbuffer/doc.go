@@ -0,0 +1,3 @@
+// Package ringbuffer is a genny template of a fixed size ring buffer that
+// overwrites its oldest items when it is full.
+package ringbuffer
@@ -0,0 +1,68 @@
+package ringbuffer
+
+import "github.com/tehbilly/genny/generic"
+
+// Elem is the type of the items in the ring buffer.
+type Elem generic.Type
+
+// ElemRingBuffer holds the most recent items written to it, up to its size.
+type ElemRingBuffer struct {
+	items []Elem
+	// start is the index of the oldest item, and n the number of items.
+	start, n int
+}
+
+// NewElemRingBuffer makes an empty ring buffer that holds up to size items.
+// It panics if size isn't positive.
+func NewElemRingBuffer(size int) *ElemRingBuffer {
+	if size <= 0 {
+		panic("ring buffer size must be positive")
+	}
+	return &ElemRingBuffer{items: make([]Elem, size)}
+}
+
+// Write adds x as the newest item. If the buffer is full the oldest item is
+// dropped, and Write gets it with overwritten true.
+func (r *ElemRingBuffer) Write(x Elem) (dropped Elem, overwritten bool) {
+	end := (r.start + r.n) % len(r.items)
+	if r.n == len(r.items) {
+		dropped, overwritten = r.items[end], true
+		r.start = (r.start + 1) % len(r.items)
+	} else {
+		r.n++
+	}
+	r.items[end] = x
+	return dropped, overwritten
+}
+
+// Read removes and returns the oldest item. ok is false if the buffer is
+// empty.
+func (r *ElemRingBuffer) Read() (x Elem, ok bool) {
+	if r.n == 0 {
+		return x, false
+	}
+	x = r.items[r.start]
+	var zero Elem
+	r.items[r.start] = zero
+	r.start = (r.start + 1) % len(r.items)
+	r.n--
+	return x, true
+}
+
+// At gets the i-th oldest item. It panics if i is out of range.
+func (r *ElemRingBuffer) At(i int) Elem {
+	if i < 0 || i >= r.n {
+		panic("ring buffer index out of range")
+	}
+	return r.items[(r.start+i)%len(r.items)]
+}
+
+// Len gets the number of items in the buffer.
+func (r *ElemRingBuffer) Len() int {
+	return r.n
+}
+
+// Size gets the number of items the buffer holds when it is full.
+func (r *ElemRingBuffer) Size() int {
+	return len(r.items)
+}
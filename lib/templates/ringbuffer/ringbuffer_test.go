@@ -0,0 +1,70 @@
+package ringbuffer
+
+import "testing"
+
+//genny:sample *time.Time &[]time.Time{time.Unix(1, 0)}[0], &[]time.Time{time.Unix(2, 0)}[0], &[]time.Time{time.Unix(3, 0)}[0], &[]time.Time{time.Unix(4, 0)}[0], &[]time.Time{time.Unix(5, 0)}[0]
+
+// elemSamples are different items.
+//
+//genny:samples
+var elemSamples = []Elem{1, 2, 3, 4, 5}
+
+func TestElemRingBufferOverwrite(t *testing.T) {
+	r := NewElemRingBuffer(3)
+	for _, x := range elemSamples[:3] {
+		if _, overwritten := r.Write(x); overwritten {
+			t.Fatalf("Write should not overwrite an item until the buffer is full")
+		}
+	}
+	for i, x := range elemSamples[3:] {
+		dropped, overwritten := r.Write(x)
+		if !overwritten || dropped != elemSamples[i] {
+			t.Errorf("Write should overwrite the oldest item of a full buffer: got %v, %v, want %v", dropped, overwritten, elemSamples[i])
+		}
+	}
+	if r.Len() != 3 || r.Size() != 3 {
+		t.Fatalf("Write should keep the size of the buffer: got %d of %d items", r.Len(), r.Size())
+	}
+	for i := 0; i < r.Len(); i++ {
+		if x := r.At(i); x != elemSamples[i+2] {
+			t.Errorf("At should get the %d-th oldest item: got %v, want %v", i, x, elemSamples[i+2])
+		}
+	}
+}
+
+func TestElemRingBufferWraparound(t *testing.T) {
+	r := NewElemRingBuffer(3)
+	written, read := 0, 0
+	for round := 0; round < 10; round++ {
+		for i := 0; i < 2; i++ {
+			r.Write(elemSamples[written%len(elemSamples)])
+			written++
+		}
+		for i := 0; i < 2; i++ {
+			x, ok := r.Read()
+			if want := elemSamples[read%len(elemSamples)]; !ok || x != want {
+				t.Fatalf("Read should get the oldest item: got %v, %v, want %v", x, ok, want)
+			}
+			read++
+		}
+	}
+	if _, ok := r.Read(); ok || r.Len() != 0 {
+		t.Errorf("Read should fail on an empty buffer")
+	}
+}
+
+func TestElemRingBufferPanics(t *testing.T) {
+	for name, f := range map[string]func(){
+		"size": func() { NewElemRingBuffer(0) },
+		"At":   func() { NewElemRingBuffer(1).At(0) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s should panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
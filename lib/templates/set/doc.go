@@ -0,0 +1,2 @@
+// Package set is a genny template of a set of comparable items.
+package set
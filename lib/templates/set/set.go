@@ -0,0 +1,98 @@
+package set
+
+import "github.com/tehbilly/genny/generic"
+
+// Elem is the type of the items in the set. It has to be comparable.
+type Elem generic.Type
+
+// ElemSet is a set. It is a map, so it can be made with make or a composite
+// literal, and ranged over.
+type ElemSet map[Elem]struct{}
+
+// NewElemSet makes a set of the items.
+func NewElemSet(items ...Elem) ElemSet {
+	s := make(ElemSet, len(items))
+	for _, x := range items {
+		s[x] = struct{}{}
+	}
+	return s
+}
+
+// Add adds x to the set. It gets whether x was not in the set already.
+func (s ElemSet) Add(x Elem) bool {
+	if _, ok := s[x]; ok {
+		return false
+	}
+	s[x] = struct{}{}
+	return true
+}
+
+// Remove removes x from the set. It gets whether x was in the set.
+func (s ElemSet) Remove(x Elem) bool {
+	if _, ok := s[x]; !ok {
+		return false
+	}
+	delete(s, x)
+	return true
+}
+
+// Contains gets whether x is in the set.
+func (s ElemSet) Contains(x Elem) bool {
+	_, ok := s[x]
+	return ok
+}
+
+// Len gets the number of items in the set.
+func (s ElemSet) Len() int {
+	return len(s)
+}
+
+// Union makes a set of the items that are in s, in other or in both.
+func (s ElemSet) Union(other ElemSet) ElemSet {
+	union := make(ElemSet, len(s)+len(other))
+	for x := range s {
+		union[x] = struct{}{}
+	}
+	for x := range other {
+		union[x] = struct{}{}
+	}
+	return union
+}
+
+// Intersection makes a set of the items that are in both s and other.
+func (s ElemSet) Intersection(other ElemSet) ElemSet {
+	if len(other) < len(s) {
+		s, other = other, s
+	}
+	intersection := make(ElemSet)
+	for x := range s {
+		if _, ok := other[x]; ok {
+			intersection[x] = struct{}{}
+		}
+	}
+	return intersection
+}
+
+// Difference makes a set of the items that are in s but not in other.
+func (s ElemSet) Difference(other ElemSet) ElemSet {
+	difference := make(ElemSet)
+	for x := range s {
+		if _, ok := other[x]; !ok {
+			difference[x] = struct{}{}
+		}
+	}
+	return difference
+}
+
+// Equal gets whether s and other have the same items.
+func (s ElemSet) Equal(other ElemSet) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for x := range s {
+		if _, ok := other[x]; !ok {
+			return false
+		}
+	}
+	return true
+}
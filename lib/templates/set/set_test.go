@@ -0,0 +1,46 @@
+package set
+
+import "testing"
+
+//genny:sample *time.Time &[]time.Time{time.Unix(1, 0)}[0], &[]time.Time{time.Unix(2, 0)}[0], &[]time.Time{time.Unix(3, 0)}[0], &[]time.Time{time.Unix(4, 0)}[0], &[]time.Time{time.Unix(5, 0)}[0]
+
+// elemSamples are different items.
+//
+//genny:samples
+var elemSamples = []Elem{1, 2, 3, 4, 5}
+
+func TestElemSet(t *testing.T) {
+	s := NewElemSet(elemSamples[0], elemSamples[1])
+	if !s.Add(elemSamples[2]) || s.Add(elemSamples[0]) {
+		t.Errorf("Add should get whether the item is new")
+	}
+	if s.Len() != 3 || !s.Contains(elemSamples[2]) || s.Contains(elemSamples[3]) {
+		t.Errorf("the set should have the items added: got %v", s)
+	}
+	if !s.Remove(elemSamples[1]) || s.Remove(elemSamples[1]) {
+		t.Errorf("Remove should get whether the item was in the set")
+	}
+	if s.Len() != 2 || s.Contains(elemSamples[1]) {
+		t.Errorf("Remove should remove the item: got %v", s)
+	}
+}
+
+func TestElemSetOperations(t *testing.T) {
+	a := NewElemSet(elemSamples[0], elemSamples[1], elemSamples[2])
+	b := NewElemSet(elemSamples[1], elemSamples[2], elemSamples[3])
+	for name, test := range map[string]struct{ got, want ElemSet }{
+		"Union":        {a.Union(b), NewElemSet(elemSamples[:4]...)},
+		"Intersection": {a.Intersection(b), NewElemSet(elemSamples[1], elemSamples[2])},
+		"Difference":   {a.Difference(b), NewElemSet(elemSamples[0])},
+	} {
+		if !test.got.Equal(test.want) {
+			t.Errorf("%s: got %v, want %v", name, test.got, test.want)
+		}
+	}
+	if a.Equal(b) || a.Equal(NewElemSet(elemSamples[:2]...)) || !a.Equal(NewElemSet(elemSamples[:3]...)) {
+		t.Errorf("Equal should get whether the sets have the same items")
+	}
+	if a.Len() != 3 || b.Len() != 3 {
+		t.Errorf("the operations should not change the sets")
+	}
+}
@@ -0,0 +1,3 @@
+// Package sortedslice is a genny template of a slice that keeps its items
+// sorted, for ordered iteration and binary search.
+package sortedslice
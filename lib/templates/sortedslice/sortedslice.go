@@ -0,0 +1,83 @@
+package sortedslice
+
+import (
+	"sort"
+
+	"github.com/tehbilly/genny/generic"
+)
+
+// Elem is the type of the items in the slice.
+type Elem generic.Type
+
+// ElemSortedSlice is a slice kept sorted by a less function. Items that are
+// not less than each other either way are equivalent, and keep the order
+// they were inserted in.
+type ElemSortedSlice struct {
+	items []Elem
+	less  func(a, b Elem) bool
+}
+
+// NewElemSortedSlice makes a sorted slice of the items, ordered by less,
+// which reports whether a goes before b. The items are copied.
+func NewElemSortedSlice(less func(a, b Elem) bool, items ...Elem) *ElemSortedSlice {
+	s := &ElemSortedSlice{items: append([]Elem(nil), items...), less: less}
+	sort.SliceStable(s.items, func(i, j int) bool { return less(s.items[i], s.items[j]) })
+	return s
+}
+
+// Insert adds x after the items equivalent to it, and gets its index.
+func (s *ElemSortedSlice) Insert(x Elem) int {
+	i := sort.Search(len(s.items), func(i int) bool { return s.less(x, s.items[i]) })
+	var zero Elem
+	s.items = append(s.items, zero)
+	copy(s.items[i+1:], s.items[i:])
+	s.items[i] = x
+	return i
+}
+
+// Search gets the index of the first item that x doesn't go after, which is
+// where x would be inserted before the items equivalent to it.
+func (s *ElemSortedSlice) Search(x Elem) int {
+	return sort.Search(len(s.items), func(i int) bool { return !s.less(s.items[i], x) })
+}
+
+// Index gets the index of the first item equivalent to x. ok is false if
+// there is none.
+func (s *ElemSortedSlice) Index(x Elem) (i int, ok bool) {
+	i = s.Search(x)
+	return i, i < len(s.items) && !s.less(x, s.items[i])
+}
+
+// Remove removes the first item equivalent to x. It gets whether there was
+// one.
+func (s *ElemSortedSlice) Remove(x Elem) bool {
+	i, ok := s.Index(x)
+	if ok {
+		s.RemoveAt(i)
+	}
+	return ok
+}
+
+// RemoveAt removes the item at index i. It panics if i is out of range.
+func (s *ElemSortedSlice) RemoveAt(i int) {
+	copy(s.items[i:], s.items[i+1:])
+	var zero Elem
+	s.items[len(s.items)-1] = zero
+	s.items = s.items[:len(s.items)-1]
+}
+
+// At gets the item at index i. It panics if i is out of range.
+func (s *ElemSortedSlice) At(i int) Elem {
+	return s.items[i]
+}
+
+// Len gets the number of items in the slice.
+func (s *ElemSortedSlice) Len() int {
+	return len(s.items)
+}
+
+// Items gets the items in order. The slice is shared, so it mustn't be
+// changed.
+func (s *ElemSortedSlice) Items() []Elem {
+	return s.items
+}
@@ -0,0 +1,84 @@
+package sortedslice
+
+import "testing"
+
+//genny:sample *time.Time &[]time.Time{time.Unix(1, 0)}[0], &[]time.Time{time.Unix(2, 0)}[0], &[]time.Time{time.Unix(3, 0)}[0], &[]time.Time{time.Unix(4, 0)}[0], &[]time.Time{time.Unix(5, 0)}[0]
+
+// elemSamples are different items, in the order elemPairLess sorts them in.
+//
+//genny:samples
+var elemSamples = []Elem{1, 2, 3, 4, 5}
+
+// elemPairLess orders the items as elemSamples does, in pairs: the first and
+// second samples are equivalent, as are the third and fourth.
+func elemPairLess(a, b Elem) bool {
+	return elemIndex(a)/2 < elemIndex(b)/2
+}
+
+// elemIndex gets the index of x in elemSamples.
+func elemIndex(x Elem) int {
+	for i, sample := range elemSamples {
+		if sample == x {
+			return i
+		}
+	}
+	panic("not a sample")
+}
+
+// checkElemSortedSlice checks that s has the samples of the indexes, in order.
+func checkElemSortedSlice(t *testing.T, s *ElemSortedSlice, indexes ...int) {
+	t.Helper()
+	if s.Len() != len(indexes) {
+		t.Fatalf("got %v, want the samples %v", s.Items(), indexes)
+	}
+	for i, index := range indexes {
+		if s.At(i) != elemSamples[index] {
+			t.Fatalf("got %v, want the samples %v", s.Items(), indexes)
+		}
+	}
+}
+
+func TestElemSortedSliceNew(t *testing.T) {
+	s := NewElemSortedSlice(elemPairLess, elemSamples[4], elemSamples[3], elemSamples[1], elemSamples[2], elemSamples[0])
+	checkElemSortedSlice(t, s, 1, 0, 3, 2, 4)
+}
+
+func TestElemSortedSliceInsert(t *testing.T) {
+	s := NewElemSortedSlice(elemPairLess)
+	for _, i := range []int{4, 1, 2, 0, 3} {
+		s.Insert(elemSamples[i])
+	}
+	checkElemSortedSlice(t, s, 1, 0, 2, 3, 4)
+	if i := s.Insert(elemSamples[1]); i != 2 {
+		t.Errorf("Insert should add the item after those equivalent to it: got index %d", i)
+	}
+	checkElemSortedSlice(t, s, 1, 0, 1, 2, 3, 4)
+}
+
+func TestElemSortedSliceSearch(t *testing.T) {
+	s := NewElemSortedSlice(elemPairLess, elemSamples[0], elemSamples[1], elemSamples[3])
+	for sample, want := range []int{0, 0, 2, 2, 3} {
+		if i := s.Search(elemSamples[sample]); i != want {
+			t.Errorf("Search should get the index of the first item not before sample %d: got %d, want %d", sample, i, want)
+		}
+	}
+	if i, ok := s.Index(elemSamples[2]); !ok || i != 2 {
+		t.Errorf("Index should get the first equivalent item: got %d, %v", i, ok)
+	}
+	if _, ok := s.Index(elemSamples[4]); ok {
+		t.Errorf("Index should fail without an equivalent item")
+	}
+}
+
+func TestElemSortedSliceRemove(t *testing.T) {
+	s := NewElemSortedSlice(elemPairLess, elemSamples[1], elemSamples[0], elemSamples[3], elemSamples[2])
+	if !s.Remove(elemSamples[0]) {
+		t.Errorf("Remove should remove an equivalent item")
+	}
+	checkElemSortedSlice(t, s, 0, 3, 2)
+	if s.Remove(elemSamples[4]) {
+		t.Errorf("Remove should fail without an equivalent item")
+	}
+	s.RemoveAt(1)
+	checkElemSortedSlice(t, s, 0, 2)
+}
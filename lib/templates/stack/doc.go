@@ -0,0 +1,2 @@
+// Package stack is a genny template of a last-in first-out stack.
+package stack
@@ -0,0 +1,49 @@
+package stack
+
+import "github.com/tehbilly/genny/generic"
+
+// Elem is the type of the items on the stack.
+type Elem generic.Type
+
+// ElemStack is a last-in first-out stack. The zero value is an empty stack.
+type ElemStack struct {
+	items []Elem
+}
+
+// NewElemStack makes an empty stack with room for n items.
+func NewElemStack(n int) *ElemStack {
+	return &ElemStack{items: make([]Elem, 0, n)}
+}
+
+// Push adds x to the top of the stack.
+func (s *ElemStack) Push(x Elem) {
+	s.items = append(s.items, x)
+}
+
+// Pop removes and returns the item at the top of the stack. ok is false if
+// the stack is empty.
+func (s *ElemStack) Pop() (x Elem, ok bool) {
+	if len(s.items) == 0 {
+		return x, false
+	}
+	last := len(s.items) - 1
+	x = s.items[last]
+	var zero Elem
+	s.items[last] = zero
+	s.items = s.items[:last]
+	return x, true
+}
+
+// Peek gets the item at the top of the stack without removing it. ok is
+// false if the stack is empty.
+func (s *ElemStack) Peek() (x Elem, ok bool) {
+	if len(s.items) == 0 {
+		return x, false
+	}
+	return s.items[len(s.items)-1], true
+}
+
+// Len gets the number of items on the stack.
+func (s *ElemStack) Len() int {
+	return len(s.items)
+}
@@ -0,0 +1,34 @@
+package stack
+
+import "testing"
+
+//genny:sample *time.Time &[]time.Time{time.Unix(1, 0)}[0], &[]time.Time{time.Unix(2, 0)}[0], &[]time.Time{time.Unix(3, 0)}[0], &[]time.Time{time.Unix(4, 0)}[0], &[]time.Time{time.Unix(5, 0)}[0]
+
+// elemSamples are different items.
+//
+//genny:samples
+var elemSamples = []Elem{1, 2, 3, 4, 5}
+
+func TestElemStack(t *testing.T) {
+	var s ElemStack
+	for _, x := range elemSamples {
+		s.Push(x)
+	}
+	if s.Len() != len(elemSamples) {
+		t.Errorf("Push should add the items: got %d items", s.Len())
+	}
+	if x, ok := s.Peek(); !ok || x != elemSamples[len(elemSamples)-1] {
+		t.Errorf("Peek should get the last item: got %v, %v", x, ok)
+	}
+	for i := len(elemSamples) - 1; i >= 0; i-- {
+		if x, ok := s.Pop(); !ok || x != elemSamples[i] {
+			t.Fatalf("Pop should get the last item pushed: got %v, %v, want %v", x, ok, elemSamples[i])
+		}
+	}
+	if _, ok := s.Pop(); ok {
+		t.Errorf("Pop should fail on an empty stack")
+	}
+	if _, ok := NewElemStack(1).Peek(); ok {
+		t.Errorf("Peek should fail on an empty stack")
+	}
+}
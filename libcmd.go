@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tehbilly/genny/lib"
+)
+
+// runLib runs `genny lib`.
+func runLib(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return &exitError{exitcodeInvalidArgs, errors.New("not enough arguments to lib")}
+	}
+	if args[0] != "list" {
+		return &exitError{exitcodeInvalidArgs, fmt.Errorf("unknown lib command %q", args[0])}
+	}
+
+	fs := flag.NewFlagSet("genny lib list", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the templates as JSON")
+	if err := fs.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return &exitError{exitcodeInvalidArgs, err}
+	}
+
+	list, err := lib.List()
+	if err != nil {
+		return &exitError{exitcodeInternalError, err}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+	for _, t := range list {
+		var generics []string
+		for _, gt := range t.Info.GenericTypes {
+			generics = append(generics, gt.Name)
+		}
+		for _, c := range t.Info.Constants {
+			generics = append(generics, c.Name+"="+c.Default)
+		}
+		fmt.Fprintf(stdout, "%s%s (%s)\n", lib.Prefix, t.Name, strings.Join(generics, ", "))
+		fmt.Fprintf(stdout, "    %s\n", t.Synopsis)
+	}
+	return nil
+}
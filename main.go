@@ -2,16 +2,22 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"go/build"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
-	// "path"
+	"path"
+	"path/filepath"
 	"runtime/debug"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/tehbilly/genny/lib"
 	"github.com/tehbilly/genny/out"
 	"github.com/tehbilly/genny/parse"
 )
@@ -34,6 +40,9 @@ const (
 	exitcodeInternalError
 )
 
+// gennylibPrefix is where `genny get` fetches templates from.
+const gennylibPrefix = "https://github.com/metabition/gennylib/raw/master/"
+
 func main() {
 	var (
 		mainErr  error
@@ -51,135 +60,589 @@ func main() {
 		os.Exit(exitCode)
 	}()
 
-	var (
-		in      = flag.String("in", "", "file to parse instead of stdin")
-		out     = flag.String("out", "", "file to save output to instead of stdout")
-		pkgName = flag.String("pkg", "", "package name for generated files")
-		genTag  = flag.String("tag", "", "build tag that is stripped from output")
-		useAst  = flag.Bool("ast", false, "whether to use AST implementation")
-		imports Strings
-		prefix  = "https://github.com/metabition/gennylib/raw/master/"
-	)
-	flag.Var(&imports, "imp", "specify an import explicitly (can be specified multiple times)")
+	cmd := &genCommand{args: os.Args[1:]}
+	cmd.flags(flag.CommandLine)
 	flag.Usage = usage
 	flag.Parse()
 	args := flag.Args()
 
-	if len(args) < 2 {
-		usage()
-		os.Exit(exitcodeInvalidArgs)
+	var subcommand string
+	if len(args) > 0 {
+		subcommand = strings.ToLower(args[0])
+	}
+	switch subcommand {
+	case "watch":
+		mainErr = runWatch(args[1:], os.Stdout)
+	case "generate":
+		mainErr = runGenerate(args[1:], os.Stdout)
+	case "inspect":
+		mainErr = runInspect(args[1:], os.Stdin, os.Stdout)
+	case "plan":
+		mainErr = runPlan(args[1:], os.Stdin, os.Stdout)
+	case "regen":
+		mainErr = runRegen(args[1:], os.Stdout)
+	case "lib":
+		mainErr = runLib(args[1:], os.Stdout)
+	default:
+		mainErr = cmd.parseArgs(args)
+		if mainErr == nil {
+			mainErr = cmd.run("", os.Stdin, os.Stdout)
+		}
 	}
 
-	if strings.ToLower(args[0]) != "gen" && strings.ToLower(args[0]) != "get" {
+	exitCode = exitCodeOf(mainErr, os.Stderr, usage)
+	mainErr = nil
+}
+
+// exitCodeOf reports err, the error of the command, to stderr and gets the
+// code genny exits with for it. Invalid arguments are followed by the usage.
+func exitCodeOf(err error, stderr io.Writer, usage func()) int {
+	if err == nil {
+		return 0
+	}
+	fmt.Fprintf(stderr, "error: %v\n", err)
+	var exitErr *exitError
+	if !errors.As(err, &exitErr) {
+		return exitcodeGenFailed
+	}
+	if exitErr.code == exitcodeInvalidArgs {
 		usage()
-		os.Exit(exitcodeInvalidArgs)
+	}
+	return exitErr.code
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: genny [{flags}] gen "{types}"
+
+gen - generates type specific code from generic code.
+get <package/file> - fetch a generic template from the online library and gen it.
+get lib:<name> - gen a template of the library built into genny, e.g. lib:set.
+lib list [-json] - list the templates of the built-in library and their generic types.
+watch [{watch flags}] [{dir}] - regenerate the outputs of the //go:generate genny lines in dir
+  (default ".") whenever their templates change. Run "genny watch -h" for the watch flags.
+generate [{generate flags}] [{patterns}] - run the //go:generate genny lines in the directories
+  matching the patterns (default "./..."), in parallel, and report what changed.
+inspect [-in=""] [-json] - describe the generic types and directives of a template.
+plan [{flags}] [-json] "{types}" - show the identifiers that gen would rename with the same
+  flags and the exported API it would generate for each typeset, without writing any code.
+regen [{regen flags}] [{patterns}] - regenerate files from the provenance recorded in their
+  headers by -provenance (default "./..."). Run "genny regen -h" for the regen flags.
+
+{flags}  - (optional) Command line flags (see below)
+{types}  - (required) Specific types for each generic type in the source
+{types} format:  {generic}={specific}[,another][ {generic2}={specific2}]
+
+Examples:
+  Generic=Specific
+  Generic1=Specific1 Generic2=Specific2
+  Generic1=Specific1,Specific2 Generic2=Specific3,Specific4
+  Generic=SpecificTitle:package.Type,AnotherSpecific
+
+Flags:`)
+	flag.PrintDefaults()
+}
+
+// exitError is an error that makes genny exit with a specific code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// genCommand is a `genny [{flags}] gen|get ...` command line.
+type genCommand struct {
+	in      string
+	out     string
+	pkgName string
+	genTags Strings
+	useAst  bool
+	genTest bool
+	stream  bool
+	perm    fileMode
+	imports Strings
+	// buildTags are build constraint expressions added to the output.
+	buildTags Strings
+	// consts are the values of the generic constants of the template.
+	consts constants
+	format parse.FormatOptions
+	// naming and placement are how the specific types are named in
+	// identifiers.
+	naming    string
+	placement string
+	// literals is the policy for string literals.
+	literals string
+	// rename renames the top-level declarations of the output.
+	rename parse.RenameOptions
+	// trace is the file the substitutions are traced to, or "-" for
+	// stderr, and traceDir the directory they are traced to along with the
+	// intermediate output of each typeset.
+	trace    string
+	traceDir string
+	// header is the file holding the header of the output, if any.
+	header     string
+	provenance bool
+	// args are the command line arguments, recorded as provenance.
+	args []string
+
+	// get is the template to fetch from the online library, or the one of
+	// the built-in library prefixed with lib.Prefix, if any.
+	get      string
+	typeSets []map[string]parse.TypeRef
+
+	// changed are the output files that run has changed.
+	changed []string
+}
+
+// flags registers the command line flags of the command.
+func (c *genCommand) flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.in, "in", "", "file to parse instead of stdin")
+	fs.StringVar(&c.out, "out", "", "file to save output to instead of stdout")
+	fs.StringVar(&c.pkgName, "pkg", "", "package name for generated files")
+	fs.Var(&c.genTags, "tag", "build tag that is stripped from the build constraint of the output (can be specified multiple times)")
+	fs.Var(&c.buildTags, "buildtag", "build constraint expression added to the output, e.g. \"!purego\" (can be specified multiple times)")
+	fs.BoolVar(&c.useAst, "ast", false, "whether to use AST implementation")
+	fs.BoolVar(&c.genTest, "test", false, "also generate {out}_test.go from the template's {in}_test.go (requires -in and -out)")
+	fs.BoolVar(&c.stream, "stream", false, "write each typeset as soon as it is generated, for very large numbers of typesets")
+	fs.Var(&c.perm, "perm", "permissions of the output files in octal, e.g. 0444 for read only (default: keep those of an existing file, or 0644)")
+	fs.StringVar(&c.header, "header", "", "file holding the comment to put at the top of the output instead of the default one")
+	fs.BoolVar(&c.provenance, "provenance", false, "record the template, its hash, the command line and the genny version in the header of the output")
+	fs.Var(&c.imports, "imp", "specify an import explicitly (can be specified multiple times)")
+	fs.Var(&c.consts, "const", "value of a generic constant of the template, as Name=value, for the typesets that don't give one (can be specified multiple times)")
+	fs.StringVar(&c.naming, "naming", "qualified", "comma separated naming presets for the specific types in identifiers: qualified or unqualified, suffixes, aliasonly")
+	fs.StringVar(&c.placement, "placement", "inplace", "where the specific types go in identifiers: inplace, prefix or suffix")
+	fs.StringVar(&c.literals, "literals", "never", "which string literals the specific types are substituted into: never, marked (those marked //genny:literal) or describe (the generic type names, as %T prints the types)")
+	fs.StringVar(&c.rename.Prefix, "prefix", "", "prefix added to the names of the top-level declarations of the output, e.g. Fast for FastIntQueue")
+	fs.StringVar(&c.rename.Suffix, "suffix", "", "suffix added to the names of the top-level declarations of the output")
+	fs.BoolVar(&c.rename.Export, "export", false, "export the top-level declarations of the output")
+	fs.BoolVar(&c.rename.Unexport, "unexport", false, "unexport the top-level declarations of the output")
+	fs.StringVar(&c.format.LocalPrefix, "local", "", "put imports beginning with these comma separated prefixes after the third party ones, like goimports -local")
+	fs.BoolVar(&c.format.FormatOnly, "formatonly", false, "only format the output, without adding or removing imports")
+	fs.IntVar(&c.format.TabWidth, "tabwidth", 8, "tab width used to align the output")
+	fs.BoolVar(&c.format.StripComments, "stripcomments", false, "drop the comments from the output, including the header, other than the generated code marker, the build constraints, the provenance and directives")
+	fs.StringVar(&c.trace, "trace", "", "write a JSON line for every substitution and the code generated for each typeset to this file, or - for stderr")
+	fs.StringVar(&c.traceDir, "tracedir", "", "write the code generated for each typeset to its own file in this directory, and the substitutions to trace.jsonl in it")
+}
+
+// parseArgs parses the arguments that follow the flags.
+func (c *genCommand) parseArgs(args []string) error {
+	if len(args) < 2 {
+		return &exitError{exitcodeInvalidArgs, errors.New("not enough arguments")}
 	}
 
 	// parse the typesets
-	var setsArg = args[1]
-	if strings.ToLower(args[0]) == "get" {
-		setsArg = args[2]
+	var setsArg string
+	switch strings.ToLower(args[0]) {
+	case "gen":
+		setsArg = args[1]
+	case "get":
+		if len(args) != 3 {
+			return &exitError{exitcodeInvalidArgs, errors.New("not enough arguments to get")}
+		}
+		c.get, setsArg = args[1], args[2]
+	default:
+		return &exitError{exitcodeInvalidArgs, fmt.Errorf("unknown command %q", args[0])}
+	}
+	if c.genTest && (c.in == "" || c.out == "") {
+		return &exitError{exitcodeInvalidArgs, errors.New("-test requires -in and -out")}
+	}
+	if c.trace != "" && c.traceDir != "" {
+		return &exitError{exitcodeInvalidArgs, errors.New("-trace and -tracedir can't be used together")}
+	}
+
+	if _, err := parse.ParseNaming(c.naming); err != nil {
+		return &exitError{exitcodeInvalidArgs, err}
+	}
+	if _, err := parse.ParsePlacement(c.placement); err != nil {
+		return &exitError{exitcodeInvalidArgs, err}
 	}
+	if _, err := parse.ParseLiteralPolicy(c.literals); err != nil {
+		return &exitError{exitcodeInvalidArgs, err}
+	}
+	if err := c.rename.Check(); err != nil {
+		return &exitError{exitcodeInvalidArgs, err}
+	}
+	if c.stream && c.rename.Enabled() {
+		return &exitError{exitcodeInvalidArgs, errors.New("-stream can't be used with -prefix, -suffix, -export or -unexport")}
+	}
+
 	typeSets, err := parse.TypeSet(setsArg)
 	if err != nil {
-		exitCode, mainErr = exitcodeInvalidTypeSet, err
-		return
+		return &exitError{exitcodeInvalidTypeSet, err}
+	}
+	c.typeSets = typeSets
+	return nil
+}
+
+// options gets the parse options for the command. The naming flags have been
+// checked by parseArgs.
+func (c *genCommand) options() parse.Options {
+	naming, _ := parse.ParseNaming(c.naming)
+	placement, _ := parse.ParsePlacement(c.placement)
+	literals, _ := parse.ParseLiteralPolicy(c.literals)
+	return parse.Options{
+		PackageName:      c.pkgName,
+		Imports:          c.imports,
+		StripTags:        c.genTags,
+		BuildConstraints: c.buildTags,
+		UseAst:           c.useAst,
+		Format:           c.format,
+		Constants:        c.consts,
+		Naming:           naming,
+		Placement:        placement,
+		Literals:         literals,
+		Rename:           c.rename,
 	}
+}
 
-	outWriter := newWriter(*out)
+// tracer gets the tracer of the -trace or -tracedir flag, if any, and a
+// function that closes it.
+func (c *genCommand) tracer(dir string) (parse.Tracer, func() error, error) {
+	noop := func() error { return nil }
+	switch {
+	case c.traceDir != "":
+		tracer, err := parse.NewDirTracer(c.path(dir, c.traceDir))
+		if err != nil {
+			return nil, noop, err
+		}
+		return tracer, tracer.(io.Closer).Close, nil
+	case c.trace == "-":
+		return parse.NewJSONTracer(os.Stderr), noop, nil
+	case c.trace != "":
+		file, err := os.Create(c.path(dir, c.trace))
+		if err != nil {
+			return nil, noop, err
+		}
+		return parse.NewJSONTracer(file), file.Close, nil
+	}
+	return nil, noop, nil
+}
 
-	if strings.ToLower(args[0]) == "get" {
-		if len(args) != 3 {
-			fmt.Println("not enough arguments to get")
-			usage()
-			os.Exit(exitcodeInvalidArgs)
+// path resolves a file name of the command relative to dir.
+func (c *genCommand) path(dir, fileName string) string {
+	if fileName == "" || dir == "" || filepath.IsAbs(fileName) {
+		return fileName
+	}
+	return filepath.Join(dir, fileName)
+}
+
+// run does the work. File names are relative to dir, or to the working
+// directory if dir is empty.
+func (c *genCommand) run(dir string, stdin io.Reader, stdout io.Writer) error {
+	in, outName := c.path(dir, c.in), c.path(dir, c.out)
+	opts := c.options()
+	opts.TemplatePackage = c.templatePackage(in, outName)
+	if c.header != "" {
+		header, err := ioutil.ReadFile(c.path(dir, c.header))
+		if err != nil {
+			return &exitError{exitcodeSourceFileInvalid, err}
+		}
+		opts.Header = string(header)
+	}
+	if c.provenance {
+		opts.Provenance = c.provenanceOf(dir, in, outName)
+	}
+	tracer, closeTrace, err := c.tracer(dir)
+	if err != nil {
+		return &exitError{exitcodeDestFileFailed, err}
+	}
+	defer closeTrace()
+	opts.Trace = tracer
+
+	var outWriter io.Writer = stdout
+	var lf *out.LazyFile
+	if outName != "" {
+		lf = &out.LazyFile{FileName: outName, Perm: c.perm.mode}
+		defer lf.Abort()
+		outWriter = lf
+	}
+
+	if name := strings.TrimPrefix(c.get, lib.Prefix); name != c.get {
+		var source []byte
+		source, err = lib.Source(name)
+		if err != nil {
+			return &exitError{exitcodeGetFailed, err}
 		}
-		r, err := http.Get(prefix + args[1])
+		err = gen(lib.Filename(name), bytes.NewReader(source), c.typeSets, opts, c.stream, outWriter)
+	} else if c.get != "" {
+		var r *http.Response
+		r, err = http.Get(gennylibPrefix + c.get)
 		if err != nil {
-			exitCode, mainErr = exitcodeGetFailed, err
-			return
+			return &exitError{exitcodeGetFailed, err}
 		}
-		b, err := ioutil.ReadAll(r.Body)
+		var b []byte
+		b, err = ioutil.ReadAll(r.Body)
 		if err != nil {
-			exitCode, mainErr = exitcodeGetFailed, err
-			return
+			return &exitError{exitcodeGetFailed, err}
 		}
 		r.Body.Close()
 		br := bytes.NewReader(b)
-		err = gen(*in, *pkgName, br, typeSets, imports, outWriter, *genTag, *useAst)
-	} else if len(*in) > 0 {
+		err = gen(in, br, c.typeSets, opts, c.stream, outWriter)
+	} else if len(in) > 0 {
 		var file *os.File
-		file, err = os.Open(*in)
+		file, err = os.Open(in)
 		if err != nil {
-			exitCode = exitcodeSourceFileInvalid
-			return
+			return &exitError{exitcodeSourceFileInvalid, err}
 		}
 		defer file.Close()
-		err = gen(*in, *pkgName, file, typeSets, imports, outWriter, *genTag, *useAst)
+		err = gen(in, file, c.typeSets, opts, c.stream, outWriter)
 	} else {
 		var source []byte
-		source, err = ioutil.ReadAll(os.Stdin)
+		source, err = ioutil.ReadAll(stdin)
 		if err != nil {
-			exitCode = exitcodeStdinFailed
-			return
+			return &exitError{exitcodeStdinFailed, err}
 		}
 		reader := bytes.NewReader(source)
-		err = gen("stdin", *pkgName, reader, typeSets, imports, outWriter, *genTag, *useAst)
+		err = gen("stdin", reader, c.typeSets, opts, c.stream, outWriter)
 	}
 
 	// do the work
 	if err != nil {
-		exitCode, mainErr = exitcodeGenFailed, err
+		return &exitError{exitcodeGenFailed, unformatted(outName, err)}
 	}
+	if lf != nil {
+		if err := lf.Close(); err != nil {
+			return &exitError{exitcodeDestFileFailed, err}
+		}
+		os.Remove(unformattedFileName(outName))
+		if lf.Changed() {
+			c.changed = append(c.changed, outName)
+		}
+	}
+
+	if c.genTest {
+		// only the output itself is traced
+		opts.Trace = nil
+		changed, err := genTests(in, outName, c.typeSets, opts, c.stream, c.perm.mode)
+		if err != nil {
+			return &exitError{exitcodeGenFailed, err}
+		}
+		if changed {
+			c.changed = append(c.changed, testFileName(outName))
+		}
+	}
+	return nil
 }
 
-func usage() {
-	fmt.Fprintln(os.Stderr, `usage: genny [{flags}] gen "{types}"
+// gen performs the generic generation.
+func gen(filename string, in io.ReadSeeker, typesets []map[string]parse.TypeRef, opts parse.Options, stream bool, out io.Writer) error {
+	if stream {
+		return parse.GenerateTo(out, filename, in, typesets, opts)
+	}
 
-gen - generates type specific code from generic code.
-get <package/file> - fetch a generic template from the online library and gen it.
+	var output []byte
+	var err error
 
-{flags}  - (optional) Command line flags (see below)
-{types}  - (required) Specific types for each generic type in the source
-{types} format:  {generic}={specific}[,another][ {generic2}={specific2}]
+	output, err = parse.Generate(filename, in, typesets, opts)
+	if err != nil {
+		return err
+	}
 
-Examples:
-  Generic=Specific
-  Generic1=Specific1 Generic2=Specific2
-  Generic1=Specific1,Specific2 Generic2=Specific3,Specific4
-  Generic=SpecificTitle:package.Type,AnotherSpecific
+	out.Write(output)
+	return nil
+}
 
-Flags:`)
-	flag.PrintDefaults()
+// genTests generates the companion test file of the output from the test
+// file of the template.
+func genTests(in, outName string, typesets []map[string]parse.TypeRef, opts parse.Options, stream bool, perm os.FileMode) (changed bool, err error) {
+	testIn := testFileName(in)
+	file, err := os.Open(testIn)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	if opts.Rename.Enabled() {
+		// the references to the output are renamed like its declarations
+		source, err := ioutil.ReadFile(outName)
+		if err != nil {
+			return false, err
+		}
+		if opts.Rename.Declared, err = parse.DeclaredNames(outName, source); err != nil {
+			return false, err
+		}
+	}
+	lf := &out.LazyFile{FileName: testFileName(outName), Perm: perm}
+	defer lf.Abort()
+	if err := gen(testIn, file, typesets, opts, stream, lf); err != nil {
+		return false, unformatted(testFileName(outName), err)
+	}
+	if err = lf.Close(); err == nil {
+		os.Remove(unformattedFileName(testFileName(outName)))
+	}
+	return lf.Changed(), err
+}
+
+// unformattedFileName gets the name of the file that the output is written
+// to when it can't be formatted.
+func unformattedFileName(outName string) string {
+	return outName + ".unformatted"
 }
 
-func newWriter(fileName string) io.Writer {
-	if fileName == "" {
-		return os.Stdout
+// unformatted adds the generated code to err if it could not be formatted,
+// so that it can be debugged. It is written next to the output file, and the
+// lines around the one that failed are shown; without an output file, all of
+// it is shown.
+func unformatted(outName string, err error) error {
+	source, line, ok := parse.Unformatted(err)
+	if !ok {
+		return err
+	}
+	if outName == "" {
+		return fmt.Errorf("%w\n%s", err, numberLines(source, line, -1))
 	}
-	lf := &out.LazyFile{FileName: fileName}
-	defer lf.Close()
-	return lf
+	sidecar := unformattedFileName(outName)
+	if werr := ioutil.WriteFile(sidecar, source, 0644); werr != nil {
+		return fmt.Errorf("%w\n%s", err, numberLines(source, line, -1))
+	}
+	return fmt.Errorf("%w\nthe unformatted output is in %s:\n%s", err, sidecar, numberLines(source, line, 3))
 }
 
-func fatal(code int, a ...interface{}) {
-	fmt.Println(a...)
-	os.Exit(code)
+// numberLines numbers the lines of source within context lines of line,
+// marking that line. All of them are numbered if context is negative.
+func numberLines(source []byte, line, context int) string {
+	lines := strings.Split(strings.TrimSuffix(string(source), "\n"), "\n")
+	first, last := 1, len(lines)
+	if context >= 0 && line > 0 {
+		if first = line - context; first < 1 {
+			first = 1
+		}
+		if last = line + context; last > len(lines) {
+			last = len(lines)
+		}
+	}
+	var b strings.Builder
+	for n := first; n <= last; n++ {
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s%5d | %s\n", marker, n, lines[n-1])
+	}
+	return strings.TrimSuffix(b.String(), "\n")
 }
 
-// gen performs the generic generation.
-func gen(filename, pkgName string, in io.ReadSeeker, typesets []map[string]parse.TypeRef, imports []string, out io.Writer, tag string, useAst bool) error {
+// provenanceOf gets the provenance to record in the output. Paths are
+// relative to the directory of the output, or the working directory if there
+// is no output file.
+func (c *genCommand) provenanceOf(dir, in, outName string) *parse.Provenance {
+	base := "."
+	if outName != "" {
+		base = filepath.Dir(outName)
+	}
+	rel := func(path string) string {
+		absBase, err := filepath.Abs(base)
+		if err != nil {
+			return filepath.ToSlash(path)
+		}
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return filepath.ToSlash(path)
+		}
+		if r, err := filepath.Rel(absBase, absPath); err == nil {
+			return filepath.ToSlash(r)
+		}
+		return filepath.ToSlash(absPath)
+	}
 
-	var output []byte
-	var err error
+	p := &parse.Provenance{Args: c.args, Version: version()}
+	if dir == "" {
+		dir = "."
+	}
+	p.Dir = rel(dir)
+	if strings.HasPrefix(c.get, lib.Prefix) {
+		p.Template = c.get
+	} else if c.get != "" {
+		p.Template = gennylibPrefix + c.get
+	} else if in != "" {
+		p.Template = rel(in)
+	}
+	return p
+}
+
+// version gets the version of genny.
+func version() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		return info.Main.Version
+	}
+	return ""
+}
 
-	output, err = parse.Generics(filename, pkgName, in, typesets, imports, tag, useAst)
+// templatePackage gets the import path of the template's package if the
+// output goes into another package, so that references to the template's
+// package can be qualified. It gets "" if the import path can't be found.
+func (c *genCommand) templatePackage(in, outName string) string {
+	if c.pkgName == "" || in == "" || outName == "" {
+		return ""
+	}
+	inDir, err := filepath.Abs(filepath.Dir(in))
 	if err != nil {
-		return err
+		return ""
+	}
+	outDir, err := filepath.Abs(filepath.Dir(outName))
+	if err != nil || inDir == outDir {
+		return ""
+	}
+	importPath, err := dirImportPath(inDir)
+	if err != nil {
+		return ""
 	}
+	return importPath
+}
 
-	out.Write(output)
-	return nil
+// dirImportPath gets the import path of the package in dir, from the go.mod
+// file of its module or, failing that, from GOPATH.
+func dirImportPath(dir string) (string, error) {
+	for modDir := dir; ; {
+		if mod, err := ioutil.ReadFile(filepath.Join(modDir, "go.mod")); err == nil {
+			modPath := modulePath(mod)
+			if modPath == "" {
+				return "", fmt.Errorf("%s: no module path", filepath.Join(modDir, "go.mod"))
+			}
+			rel, err := filepath.Rel(modDir, dir)
+			if err != nil {
+				return "", err
+			}
+			return path.Join(modPath, filepath.ToSlash(rel)), nil
+		}
+		parent := filepath.Dir(modDir)
+		if parent == modDir {
+			break
+		}
+		modDir = parent
+	}
+	for _, gopath := range filepath.SplitList(build.Default.GOPATH) {
+		rel, err := filepath.Rel(filepath.Join(gopath, "src"), dir)
+		if err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel), nil
+		}
+	}
+	return "", fmt.Errorf("cannot find the import path of %s", dir)
+}
+
+// modulePath gets the module path declared in a go.mod file.
+func modulePath(mod []byte) string {
+	for _, line := range strings.Split(string(mod), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+		if modPath, err := strconv.Unquote(fields[1]); err == nil {
+			return modPath
+		}
+		return fields[1]
+	}
+	return ""
+}
+
+// testFileName gets the name of the test file that accompanies a Go file.
+func testFileName(fileName string) string {
+	return strings.TrimSuffix(fileName, ".go") + "_test.go"
 }
 
 // Strings is a list of strings for flag
@@ -194,3 +657,50 @@ func (i *Strings) Set(value string) error {
 	*i = append(*i, value)
 	return nil
 }
+
+// constants are the values of generic constants for flag
+type constants map[string]string
+
+func (c constants) String() string {
+	var pairs []string
+	for name, value := range c {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// Set method to implement flag.Value
+func (c *constants) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("%q is not Name=value", value)
+	}
+	if *c == nil {
+		*c = make(constants)
+	}
+	(*c)[parts[0]] = parts[1]
+	return nil
+}
+
+// fileMode is file permissions in octal for flag
+type fileMode struct {
+	mode os.FileMode
+}
+
+func (m *fileMode) String() string {
+	if m == nil || m.mode == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%#o", m.mode)
+}
+
+// Set method to implement flag.Value
+func (m *fileMode) Set(value string) error {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode == 0 || os.FileMode(mode)&^os.ModePerm != 0 {
+		return fmt.Errorf("invalid permissions %q", value)
+	}
+	m.mode = os.FileMode(mode)
+	return nil
+}
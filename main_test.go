@@ -1 +1,219 @@
-package main_test
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tehbilly/genny/parse"
+)
+
+func TestDirImportPath(t *testing.T) {
+	importPath, err := dirImportPath(filepath.Join(mustGetwd(t), "parse", "test", "crosspkg"))
+	require.NoError(t, err)
+	assert.Equal(t, "github.com/tehbilly/genny/parse/test/crosspkg", importPath)
+
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("// a module\nmodule \"example.com/m\"\n\ngo 1.18\n"), 0644))
+	importPath, err = dirImportPath(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "example.com/m", importPath)
+}
+
+func TestTemplatePackage(t *testing.T) {
+	in := filepath.Join("parse", "test", "crosspkg", "generic_list.go")
+	c := &genCommand{pkgName: "other"}
+	assert.Equal(t, "github.com/tehbilly/genny/parse/test/crosspkg", c.templatePackage(in, filepath.Join("other", "lists.go")))
+	assert.Equal(t, "", c.templatePackage(in, filepath.Join("parse", "test", "crosspkg", "lists.go")), "same directory")
+
+	c.pkgName = ""
+	assert.Equal(t, "", c.templatePackage(in, filepath.Join("other", "lists.go")), "no -pkg")
+}
+
+func mustGetwd(t *testing.T) string {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	return wd
+}
+
+func TestProvenanceOf(t *testing.T) {
+	c := &genCommand{args: []string{"-provenance", "-in=queue.go", "-out=gen/queue.go", "gen", "Something=int"}}
+	p := c.provenanceOf("templates", filepath.Join("templates", "queue.go"), filepath.Join("templates", "gen", "queue.go"))
+	assert.Equal(t, "../queue.go", p.Template)
+	assert.Equal(t, "..", p.Dir)
+	assert.Equal(t, c.args, p.Args)
+
+	c.get = "maps/concurrentmap.go"
+	p = c.provenanceOf("", "", "")
+	assert.Equal(t, gennylibPrefix+"maps/concurrentmap.go", p.Template)
+	assert.Equal(t, ".", p.Dir)
+
+	c.get = "lib:set"
+	p = c.provenanceOf("", "", "")
+	assert.Equal(t, "lib:set", p.Template)
+}
+
+func TestUnformatted(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "queue.go"), []byte(watchTemplate), 0644))
+	output := filepath.Join(dir, "queue_gen.go")
+
+	broken := &genCommand{in: "queue.go", out: "queue_gen.go"}
+	require.NoError(t, broken.parseArgs([]string{"gen", "Something=Broken:map[int"}))
+	err := broken.run(dir, nil, ioutil.Discard)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the unformatted output is in "+unformattedFileName(output))
+	assert.Contains(t, err.Error(), "> ")
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err))
+	source, err := ioutil.ReadFile(unformattedFileName(output))
+	require.NoError(t, err)
+	assert.Contains(t, string(source), "map[int")
+
+	// without an output file all of it is shown
+	broken.out = ""
+	err = broken.run(dir, nil, ioutil.Discard)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "    1 | // Code generated by genny. DO NOT EDIT.\n")
+
+	fixed := &genCommand{in: "queue.go", out: "queue_gen.go"}
+	require.NoError(t, fixed.parseArgs([]string{"gen", "Something=int"}))
+	require.NoError(t, fixed.run(dir, nil, ioutil.Discard))
+	assert.FileExists(t, output)
+	_, err = os.Stat(unformattedFileName(output))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestNumberLines(t *testing.T) {
+	source := []byte("a\nb\nc\nd\ne\n")
+	assert.Equal(t, "     2 | b\n>    3 | c\n     4 | d", numberLines(source, 3, 1))
+	assert.Equal(t, ">    1 | a\n     2 | b", numberLines(source, 1, 1))
+	assert.Equal(t, "     1 | a\n     2 | b\n     3 | c\n     4 | d\n     5 | e", numberLines(source, 0, 3))
+}
+
+func TestConstantsFlag(t *testing.T) {
+	var c constants
+	require.NoError(t, c.Set("Capacity=64"))
+	require.NoError(t, c.Set("Prefix=a=b"))
+	assert.Equal(t, constants{"Capacity": "64", "Prefix": "a=b"}, c)
+	assert.Equal(t, "Capacity=64, Prefix=a=b", c.String())
+	assert.Error(t, c.Set("Capacity"))
+	assert.Error(t, c.Set("=64"))
+}
+
+func TestTrace(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "queue.go"), []byte(watchTemplate), 0644))
+
+	c := &genCommand{in: "queue.go", out: "queue_gen.go", trace: "trace.jsonl"}
+	require.NoError(t, c.parseArgs([]string{"gen", "Something=int"}))
+	require.NoError(t, c.run(dir, nil, ioutil.Discard))
+	log, err := ioutil.ReadFile(filepath.Join(dir, "trace.jsonl"))
+	require.NoError(t, err)
+	assert.Contains(t, string(log), `"event":"intermediate"`)
+
+	c = &genCommand{trace: "trace.jsonl", traceDir: "trace"}
+	assert.Error(t, c.parseArgs([]string{"gen", "Something=int"}))
+}
+
+func TestRenameWithTests(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "queue.go"), []byte(watchTemplate), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "queue_test.go"), []byte(`package queue
+
+import "testing"
+
+func TestSomethingQueue(t *testing.T) {
+	var q SomethingQueue
+	if len(q) != 0 {
+		t.Error("a new queue should be empty")
+	}
+}
+`), 0644))
+
+	c := &genCommand{in: "queue.go", out: "queue_gen.go", genTest: true, rename: parse.RenameOptions{Prefix: "fast"}}
+	require.NoError(t, c.parseArgs([]string{"gen", "Something=int"}))
+	require.NoError(t, c.run(dir, nil, ioutil.Discard))
+	output, err := ioutil.ReadFile(filepath.Join(dir, "queue_gen.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "type FastIntQueue []int")
+	tests, err := ioutil.ReadFile(filepath.Join(dir, "queue_gen_test.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(tests), "func TestIntQueue(t *testing.T) {\n\tvar q FastIntQueue\n")
+
+	c = &genCommand{stream: true, rename: parse.RenameOptions{Suffix: "V2"}}
+	assert.Error(t, c.parseArgs([]string{"gen", "Something=int"}))
+	c = &genCommand{rename: parse.RenameOptions{Export: true, Unexport: true}}
+	assert.Error(t, c.parseArgs([]string{"gen", "Something=int"}))
+}
+
+func TestGetLib(t *testing.T) {
+	dir := t.TempDir()
+	c := &genCommand{out: "set_gen.go", pkgName: "things"}
+	require.NoError(t, c.parseArgs([]string{"get", "lib:set", "Elem=string"}))
+	require.NoError(t, c.run(dir, nil, ioutil.Discard))
+	output, err := ioutil.ReadFile(filepath.Join(dir, "set_gen.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "\npackage things\n")
+	assert.Contains(t, string(output), "type StringSet map[string]struct{}")
+	assert.NotContains(t, string(output), "Package set")
+
+	c = &genCommand{}
+	require.NoError(t, c.parseArgs([]string{"get", "lib:nope", "Elem=string"}))
+	err = c.run(dir, nil, ioutil.Discard)
+	var exitErr *exitError
+	require.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, exitcodeGetFailed, exitErr.code)
+}
+
+func TestLibList(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, runLib([]string{"list"}, &buf))
+	assert.Contains(t, buf.String(), "lib:set (Elem)\n")
+	assert.Contains(t, buf.String(), "lib:lru (Key, Value)\n")
+
+	assert.Error(t, runLib(nil, &buf))
+	assert.Error(t, runLib([]string{"show"}, &buf))
+}
+
+func TestExitCodeOf(t *testing.T) {
+	var stderr bytes.Buffer
+	var usages int
+	usage := func() { usages++ }
+
+	c := &genCommand{genTest: true}
+	err := c.parseArgs([]string{"gen", "Something=int"})
+	assert.Equal(t, exitcodeInvalidArgs, exitCodeOf(err, &stderr, usage))
+	assert.Equal(t, "error: -test requires -in and -out\n", stderr.String())
+	assert.Equal(t, 1, usages)
+
+	stderr.Reset()
+	assert.Equal(t, exitcodeGenFailed, exitCodeOf(errors.New("failed"), &stderr, usage))
+	assert.Equal(t, "error: failed\n", stderr.String())
+	assert.Equal(t, 1, usages)
+
+	stderr.Reset()
+	assert.Equal(t, 0, exitCodeOf(nil, &stderr, usage))
+	assert.Empty(t, stderr.String())
+}
+
+func TestBadOptionValues(t *testing.T) {
+	for _, test := range []struct {
+		command *genCommand
+		message string
+	}{
+		{&genCommand{naming: "bogus", placement: "inplace", literals: "never"}, "naming has to be"},
+		{&genCommand{naming: "qualified", placement: "bogus", literals: "never"}, "placement has to be"},
+		{&genCommand{naming: "qualified", placement: "inplace", literals: "bogus"}, "literals has to be"},
+	} {
+		var stderr bytes.Buffer
+		err := test.command.parseArgs([]string{"gen", "Something=int"})
+		assert.Equal(t, exitcodeInvalidArgs, exitCodeOf(err, &stderr, func() {}))
+		assert.Contains(t, stderr.String(), `error: "bogus" is bad: `+test.message)
+	}
+}
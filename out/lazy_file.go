@@ -1,38 +1,153 @@
 package out
 
 import (
+	"bytes"
+	"io"
 	"os"
 	"path"
 )
 
+// defaultPerm is the permissions of new files when LazyFile.Perm is not set.
+const defaultPerm os.FileMode = 0644
+
 // LazyFile is an io.WriteCloser which defers creation of the file it is supposed to write in
 // till the first call to its write function in order to prevent creation of file, if no write
 // is supposed to happen.
+//
+// The output is written to a temporary file next to FileName, which is renamed into place by
+// Close, so that the file is never left half written. If the output is the same as what the
+// file already holds, the file is left alone, so its modification time does not change.
 type LazyFile struct {
 	// FileName is path to the file to which genny will write.
 	FileName string
-	file     *os.File
+	// Perm is the permissions to give the file. If it is zero, an existing file keeps its
+	// permissions and a new one gets 0644.
+	Perm    os.FileMode
+	file    *os.File
+	changed bool
+}
+
+// Changed gets whether Close has replaced the file with different contents.
+func (lw *LazyFile) Changed() bool {
+	return lw.changed
 }
 
-// Close closes the file if it is created. Returns nil if no file is created.
+// Close replaces the file with what has been written, unless it is the same. Returns nil if
+// nothing has been written.
 func (lw *LazyFile) Close() error {
-	if lw.file != nil {
-		return lw.file.Close()
+	if lw.file == nil {
+		return nil
+	}
+	tmp := lw.file
+	lw.file = nil
+	defer os.Remove(tmp.Name())
+
+	existing, err := os.Stat(lw.FileName)
+	if err != nil && !os.IsNotExist(err) {
+		tmp.Close()
+		return err
+	}
+	perm := lw.Perm
+	if perm == 0 {
+		perm = defaultPerm
+		if existing != nil {
+			perm = existing.Mode().Perm()
+		}
+	}
+
+	if existing != nil {
+		same, err := sameContents(tmp, lw.FileName, existing.Size())
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		if same {
+			tmp.Close()
+			if existing.Mode().Perm() != perm {
+				return os.Chmod(lw.FileName, perm)
+			}
+			return nil
+		}
+	}
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), lw.FileName); err != nil {
+		return err
 	}
+	lw.changed = true
 	return nil
 }
 
-// Write writes to the specified file and creates the file first time it is called.
+// Abort throws away what has been written, leaving the file as it was. It does nothing after
+// Close, so it can be deferred to clean up when generation fails.
+func (lw *LazyFile) Abort() error {
+	if lw.file == nil {
+		return nil
+	}
+	tmp := lw.file
+	lw.file = nil
+	tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
+// Write writes to the temporary file and creates it the first time it is called.
 func (lw *LazyFile) Write(p []byte) (int, error) {
 	if lw.file == nil {
-		err := os.MkdirAll(path.Dir(lw.FileName), 0755)
+		dir := path.Dir(lw.FileName)
+		err := os.MkdirAll(dir, 0755)
 		if err != nil {
 			return 0, err
 		}
-		lw.file, err = os.Create(lw.FileName)
+		lw.file, err = os.CreateTemp(dir, "."+path.Base(lw.FileName)+".*.tmp")
 		if err != nil {
 			return 0, err
 		}
 	}
 	return lw.file.Write(p)
 }
+
+// sameContents gets whether the contents of tmp, which has just been written, are the same as
+// those of the file called fileName, which is size bytes long.
+func sameContents(tmp *os.File, fileName string, size int64) (bool, error) {
+	written, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, err
+	}
+	if size != written {
+		return false, nil
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	existing, err := os.Open(fileName)
+	if err != nil {
+		return false, err
+	}
+	defer existing.Close()
+
+	a := make([]byte, 32*1024)
+	b := make([]byte, len(a))
+	for {
+		n, errA := io.ReadFull(tmp, a)
+		m, errB := io.ReadFull(existing, b)
+		if n != m || !bytes.Equal(a[:n], b[:m]) {
+			return false, nil
+		}
+		if errA == io.EOF || errA == io.ErrUnexpectedEOF {
+			return errB == errA, nil
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil {
+			return false, errB
+		}
+	}
+}
@@ -2,10 +2,13 @@ package out_test
 
 import (
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/tehbilly/genny/out"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 const testFileName = "test-file.go"
@@ -47,3 +50,91 @@ func TestNoWrite(t *testing.T) {
 	_, err := os.Stat(testFileName)
 	assert.True(t, os.IsNotExist(err), "Expected file not to be created")
 }
+
+func TestWriteOnClose(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "sub", "out.go")
+	lf := out.LazyFile{FileName: fileName}
+	_, err := lf.Write([]byte("package out\n"))
+	require.NoError(t, err)
+	_, err = os.Stat(fileName)
+	assert.True(t, os.IsNotExist(err), "Expected file not to be created before Close")
+
+	require.NoError(t, lf.Close())
+	b, err := ioutil.ReadFile(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, "package out\n", string(b))
+	info, err := os.Stat(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	entries, err := ioutil.ReadDir(filepath.Dir(fileName))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "Expected the temporary file to be gone")
+}
+
+func TestAbort(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "out.go")
+	require.NoError(t, ioutil.WriteFile(fileName, []byte("old"), 0644))
+
+	lf := out.LazyFile{FileName: fileName}
+	_, err := lf.Write([]byte("half written"))
+	require.NoError(t, err)
+	require.NoError(t, lf.Abort())
+	require.NoError(t, lf.Close())
+
+	b, err := ioutil.ReadFile(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(b))
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "Expected the temporary file to be gone")
+}
+
+func TestUnchanged(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "out.go")
+	require.NoError(t, ioutil.WriteFile(fileName, []byte("package out\n"), 0600))
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(fileName, modTime, modTime))
+
+	lf := out.LazyFile{FileName: fileName}
+	_, err := lf.Write([]byte("package out\n"))
+	require.NoError(t, err)
+	require.NoError(t, lf.Close())
+
+	assert.False(t, lf.Changed())
+	info, err := os.Stat(fileName)
+	require.NoError(t, err)
+	assert.True(t, modTime.Equal(info.ModTime()), "Expected unchanged file not to be written")
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	lf = out.LazyFile{FileName: fileName}
+	_, err = lf.Write([]byte("package changed\n"))
+	require.NoError(t, err)
+	require.NoError(t, lf.Close())
+	assert.True(t, lf.Changed())
+
+	b, err := ioutil.ReadFile(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, "package changed\n", string(b))
+	info, err = os.Stat(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "Expected permissions to be kept")
+}
+
+func TestPerm(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "out.go")
+	for _, contents := range []string{"package out\n", "package out\n", "package changed\n"} {
+		lf := out.LazyFile{FileName: fileName, Perm: 0444}
+		_, err := lf.Write([]byte(contents))
+		require.NoError(t, err)
+		require.NoError(t, lf.Close())
+
+		info, err := os.Stat(fileName)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0444), info.Mode().Perm())
+	}
+	b, err := ioutil.ReadFile(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, "package changed\n", string(b))
+}
@@ -0,0 +1,88 @@
+package parse
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commonInitialisms are the initialisms that Go names keep in a single case,
+// like ID in userID or HTTP in httpServer. The list is the one golint uses.
+var commonInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true,
+	"DNS": true, "EOF": true, "GUID": true, "HTML": true, "HTTP": true,
+	"HTTPS": true, "ID": true, "IP": true, "JSON": true, "LHS": true,
+	"QPS": true, "RAM": true, "RHS": true, "RPC": true, "SLA": true,
+	"SMTP": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true,
+	"TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true,
+	"URI": true, "URL": true, "UTF8": true, "VM": true, "XML": true,
+	"XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// casing puts the start of s in the case of an exported or an unexported
+// identifier. An initialism at the start is kept in a single case, so ID
+// becomes id, HTTPServer httpServer and url URL.
+func casing(s string, exported bool) string {
+	if s == "" {
+		return s
+	}
+	if exported {
+		return exportedCase(s)
+	}
+	return unexportedCase(s)
+}
+
+func exportedCase(s string) string {
+	// the leading word in lower case, if it is an initialism
+	end := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLower(r) })
+	if end == -1 {
+		end = len(s)
+	}
+	if end > 0 && commonInitialisms[strings.ToUpper(s[:end])] {
+		return strings.ToUpper(s[:end]) + s[end:]
+	}
+	return upperFirst(s)
+}
+
+func unexportedCase(s string) string {
+	runes := []rune(s)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	switch {
+	case n == 0:
+		return s
+	case n == len(runes) || !unicode.IsLower(runes[n]):
+		// ID, URL2 or ID_x
+	case n == 1:
+		// Foo
+	case commonInitialisms[string(runes[:n])] && runes[n] == 's' && (n+1 == len(runes) || !unicode.IsLower(runes[n+1])):
+		// the plural IDs
+	default:
+		// HTTPServer keeps the S of Server
+		n--
+	}
+	return strings.ToLower(string(runes[:n])) + string(runes[n:])
+}
+
+// upperFirst puts the first letter of s in upper case.
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// startsUpper gets whether s starts with an upper case letter.
+func startsUpper(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsUpper(r)
+}
+
+// startsLower gets whether s starts with a lower case letter.
+func startsLower(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsLower(r)
+}
@@ -0,0 +1,39 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCasing(t *testing.T) {
+	cases := []struct {
+		in, exported, unexported string
+	}{
+		{"int", "Int", "int"},
+		{"Thing", "Thing", "thing"},
+		{"ID", "ID", "id"},
+		{"id", "ID", "id"},
+		{"URL", "URL", "url"},
+		{"url", "URL", "url"},
+		{"IDs", "IDs", "ids"},
+		{"HTTPServer", "HTTPServer", "httpServer"},
+		{"httpServer", "HTTPServer", "httpServer"},
+		{"URL2", "URL2", "url2"},
+		{"userID", "UserID", "userID"},
+		{"Ölçü", "Ölçü", "ölçü"},
+		{"élan", "Élan", "élan"},
+		{"", "", ""},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.exported, casing(c.in, true), c.in)
+		assert.Equal(t, c.unexported, casing(c.in, false), c.in)
+	}
+}
+
+func TestBoundaryUnicode(t *testing.T) {
+	assert.Equal(t, len("Öl"), indexBoundary("ÖlThing", "Thing"))
+	assert.Equal(t, -1, indexBoundary("élthing", "thing"))
+	assert.Equal(t, "ÖlInt", replaceBoundary("ÖlThing", "Thing", "Int"))
+	assert.Equal(t, len("Ölx"), indexFold("ÖlxTHING", "thing"))
+}
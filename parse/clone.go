@@ -0,0 +1,414 @@
+package parse
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// cloneFile makes a deep copy of the file, so that it can be transformed
+// without changing the original. The file must have been parsed without
+// object resolution. Comment groups and import specs, which are referenced
+// from more than one place in a file, are shared in the copy too.
+func cloneFile(file *ast.File) *ast.File {
+	c := &cloner{
+		comments: make(map[*ast.CommentGroup]*ast.CommentGroup),
+		imports:  make(map[*ast.ImportSpec]*ast.ImportSpec),
+	}
+	cp := *file
+	cp.Doc = c.commentGroup(file.Doc)
+	cp.Name = c.ident(file.Name)
+	cp.Decls = make([]ast.Decl, len(file.Decls))
+	for i, decl := range file.Decls {
+		cp.Decls[i] = c.node(decl).(ast.Decl)
+	}
+	cp.Imports = make([]*ast.ImportSpec, len(file.Imports))
+	for i, spec := range file.Imports {
+		cp.Imports[i] = c.importSpec(spec)
+	}
+	cp.Comments = make([]*ast.CommentGroup, len(file.Comments))
+	for i, group := range file.Comments {
+		cp.Comments[i] = c.commentGroup(group)
+	}
+	return &cp
+}
+
+type cloner struct {
+	comments map[*ast.CommentGroup]*ast.CommentGroup
+	imports  map[*ast.ImportSpec]*ast.ImportSpec
+}
+
+func (c *cloner) commentGroup(group *ast.CommentGroup) *ast.CommentGroup {
+	if group == nil {
+		return nil
+	}
+	if cp, ok := c.comments[group]; ok {
+		return cp
+	}
+	cp := &ast.CommentGroup{List: make([]*ast.Comment, len(group.List))}
+	for i, comment := range group.List {
+		cmt := *comment
+		cp.List[i] = &cmt
+	}
+	c.comments[group] = cp
+	return cp
+}
+
+func (c *cloner) importSpec(spec *ast.ImportSpec) *ast.ImportSpec {
+	if cp, ok := c.imports[spec]; ok {
+		return cp
+	}
+	cp := *spec
+	cp.Doc = c.commentGroup(spec.Doc)
+	cp.Name = c.ident(spec.Name)
+	cp.Path = c.basicLit(spec.Path)
+	cp.Comment = c.commentGroup(spec.Comment)
+	c.imports[spec] = &cp
+	return &cp
+}
+
+func (c *cloner) ident(ident *ast.Ident) *ast.Ident {
+	if ident == nil {
+		return nil
+	}
+	cp := *ident
+	return &cp
+}
+
+func (c *cloner) idents(idents []*ast.Ident) []*ast.Ident {
+	if idents == nil {
+		return nil
+	}
+	cp := make([]*ast.Ident, len(idents))
+	for i, ident := range idents {
+		cp[i] = c.ident(ident)
+	}
+	return cp
+}
+
+func (c *cloner) basicLit(lit *ast.BasicLit) *ast.BasicLit {
+	if lit == nil {
+		return nil
+	}
+	cp := *lit
+	return &cp
+}
+
+func (c *cloner) expr(expr ast.Expr) ast.Expr {
+	if expr == nil {
+		return nil
+	}
+	return c.node(expr).(ast.Expr)
+}
+
+func (c *cloner) exprs(exprs []ast.Expr) []ast.Expr {
+	if exprs == nil {
+		return nil
+	}
+	cp := make([]ast.Expr, len(exprs))
+	for i, expr := range exprs {
+		cp[i] = c.expr(expr)
+	}
+	return cp
+}
+
+func (c *cloner) stmt(stmt ast.Stmt) ast.Stmt {
+	if stmt == nil {
+		return nil
+	}
+	return c.node(stmt).(ast.Stmt)
+}
+
+func (c *cloner) stmts(stmts []ast.Stmt) []ast.Stmt {
+	if stmts == nil {
+		return nil
+	}
+	cp := make([]ast.Stmt, len(stmts))
+	for i, stmt := range stmts {
+		cp[i] = c.stmt(stmt)
+	}
+	return cp
+}
+
+func (c *cloner) block(block *ast.BlockStmt) *ast.BlockStmt {
+	if block == nil {
+		return nil
+	}
+	return c.node(block).(*ast.BlockStmt)
+}
+
+func (c *cloner) fieldList(list *ast.FieldList) *ast.FieldList {
+	if list == nil {
+		return nil
+	}
+	cp := *list
+	if list.List != nil {
+		cp.List = make([]*ast.Field, len(list.List))
+		for i, field := range list.List {
+			f := *field
+			f.Doc = c.commentGroup(field.Doc)
+			f.Names = c.idents(field.Names)
+			f.Type = c.expr(field.Type)
+			f.Tag = c.basicLit(field.Tag)
+			f.Comment = c.commentGroup(field.Comment)
+			cp.List[i] = &f
+		}
+	}
+	return &cp
+}
+
+func (c *cloner) funcType(ft *ast.FuncType) *ast.FuncType {
+	if ft == nil {
+		return nil
+	}
+	cp := *ft
+	cp.TypeParams = c.fieldList(ft.TypeParams)
+	cp.Params = c.fieldList(ft.Params)
+	cp.Results = c.fieldList(ft.Results)
+	return &cp
+}
+
+// node copies any node other than the ones that have their own methods.
+func (c *cloner) node(node ast.Node) ast.Node {
+	switch n := node.(type) {
+	case *ast.Ident:
+		return c.ident(n)
+	case *ast.BasicLit:
+		return c.basicLit(n)
+	case *ast.BadExpr:
+		cp := *n
+		return &cp
+	case *ast.Ellipsis:
+		cp := *n
+		cp.Elt = c.expr(n.Elt)
+		return &cp
+	case *ast.FuncLit:
+		cp := *n
+		cp.Type = c.funcType(n.Type)
+		cp.Body = c.block(n.Body)
+		return &cp
+	case *ast.CompositeLit:
+		cp := *n
+		cp.Type = c.expr(n.Type)
+		cp.Elts = c.exprs(n.Elts)
+		return &cp
+	case *ast.ParenExpr:
+		cp := *n
+		cp.X = c.expr(n.X)
+		return &cp
+	case *ast.SelectorExpr:
+		cp := *n
+		cp.X = c.expr(n.X)
+		cp.Sel = c.ident(n.Sel)
+		return &cp
+	case *ast.IndexExpr:
+		cp := *n
+		cp.X = c.expr(n.X)
+		cp.Index = c.expr(n.Index)
+		return &cp
+	case *ast.IndexListExpr:
+		cp := *n
+		cp.X = c.expr(n.X)
+		cp.Indices = c.exprs(n.Indices)
+		return &cp
+	case *ast.SliceExpr:
+		cp := *n
+		cp.X = c.expr(n.X)
+		cp.Low = c.expr(n.Low)
+		cp.High = c.expr(n.High)
+		cp.Max = c.expr(n.Max)
+		return &cp
+	case *ast.TypeAssertExpr:
+		cp := *n
+		cp.X = c.expr(n.X)
+		cp.Type = c.expr(n.Type)
+		return &cp
+	case *ast.CallExpr:
+		cp := *n
+		cp.Fun = c.expr(n.Fun)
+		cp.Args = c.exprs(n.Args)
+		return &cp
+	case *ast.StarExpr:
+		cp := *n
+		cp.X = c.expr(n.X)
+		return &cp
+	case *ast.UnaryExpr:
+		cp := *n
+		cp.X = c.expr(n.X)
+		return &cp
+	case *ast.BinaryExpr:
+		cp := *n
+		cp.X = c.expr(n.X)
+		cp.Y = c.expr(n.Y)
+		return &cp
+	case *ast.KeyValueExpr:
+		cp := *n
+		cp.Key = c.expr(n.Key)
+		cp.Value = c.expr(n.Value)
+		return &cp
+	case *ast.ArrayType:
+		cp := *n
+		cp.Len = c.expr(n.Len)
+		cp.Elt = c.expr(n.Elt)
+		return &cp
+	case *ast.StructType:
+		cp := *n
+		cp.Fields = c.fieldList(n.Fields)
+		return &cp
+	case *ast.FuncType:
+		return c.funcType(n)
+	case *ast.InterfaceType:
+		cp := *n
+		cp.Methods = c.fieldList(n.Methods)
+		return &cp
+	case *ast.MapType:
+		cp := *n
+		cp.Key = c.expr(n.Key)
+		cp.Value = c.expr(n.Value)
+		return &cp
+	case *ast.ChanType:
+		cp := *n
+		cp.Value = c.expr(n.Value)
+		return &cp
+
+	case *ast.BadStmt:
+		cp := *n
+		return &cp
+	case *ast.DeclStmt:
+		cp := *n
+		cp.Decl = c.node(n.Decl).(ast.Decl)
+		return &cp
+	case *ast.EmptyStmt:
+		cp := *n
+		return &cp
+	case *ast.LabeledStmt:
+		cp := *n
+		cp.Label = c.ident(n.Label)
+		cp.Stmt = c.stmt(n.Stmt)
+		return &cp
+	case *ast.ExprStmt:
+		cp := *n
+		cp.X = c.expr(n.X)
+		return &cp
+	case *ast.SendStmt:
+		cp := *n
+		cp.Chan = c.expr(n.Chan)
+		cp.Value = c.expr(n.Value)
+		return &cp
+	case *ast.IncDecStmt:
+		cp := *n
+		cp.X = c.expr(n.X)
+		return &cp
+	case *ast.AssignStmt:
+		cp := *n
+		cp.Lhs = c.exprs(n.Lhs)
+		cp.Rhs = c.exprs(n.Rhs)
+		return &cp
+	case *ast.GoStmt:
+		cp := *n
+		cp.Call = c.node(n.Call).(*ast.CallExpr)
+		return &cp
+	case *ast.DeferStmt:
+		cp := *n
+		cp.Call = c.node(n.Call).(*ast.CallExpr)
+		return &cp
+	case *ast.ReturnStmt:
+		cp := *n
+		cp.Results = c.exprs(n.Results)
+		return &cp
+	case *ast.BranchStmt:
+		cp := *n
+		cp.Label = c.ident(n.Label)
+		return &cp
+	case *ast.BlockStmt:
+		cp := *n
+		cp.List = c.stmts(n.List)
+		return &cp
+	case *ast.IfStmt:
+		cp := *n
+		cp.Init = c.stmt(n.Init)
+		cp.Cond = c.expr(n.Cond)
+		cp.Body = c.block(n.Body)
+		cp.Else = c.stmt(n.Else)
+		return &cp
+	case *ast.CaseClause:
+		cp := *n
+		cp.List = c.exprs(n.List)
+		cp.Body = c.stmts(n.Body)
+		return &cp
+	case *ast.SwitchStmt:
+		cp := *n
+		cp.Init = c.stmt(n.Init)
+		cp.Tag = c.expr(n.Tag)
+		cp.Body = c.block(n.Body)
+		return &cp
+	case *ast.TypeSwitchStmt:
+		cp := *n
+		cp.Init = c.stmt(n.Init)
+		cp.Assign = c.stmt(n.Assign)
+		cp.Body = c.block(n.Body)
+		return &cp
+	case *ast.CommClause:
+		cp := *n
+		cp.Comm = c.stmt(n.Comm)
+		cp.Body = c.stmts(n.Body)
+		return &cp
+	case *ast.SelectStmt:
+		cp := *n
+		cp.Body = c.block(n.Body)
+		return &cp
+	case *ast.ForStmt:
+		cp := *n
+		cp.Init = c.stmt(n.Init)
+		cp.Cond = c.expr(n.Cond)
+		cp.Post = c.stmt(n.Post)
+		cp.Body = c.block(n.Body)
+		return &cp
+	case *ast.RangeStmt:
+		cp := *n
+		cp.Key = c.expr(n.Key)
+		cp.Value = c.expr(n.Value)
+		cp.X = c.expr(n.X)
+		cp.Body = c.block(n.Body)
+		return &cp
+
+	case *ast.ImportSpec:
+		return c.importSpec(n)
+	case *ast.ValueSpec:
+		cp := *n
+		cp.Doc = c.commentGroup(n.Doc)
+		cp.Names = c.idents(n.Names)
+		cp.Type = c.expr(n.Type)
+		cp.Values = c.exprs(n.Values)
+		cp.Comment = c.commentGroup(n.Comment)
+		return &cp
+	case *ast.TypeSpec:
+		cp := *n
+		cp.Doc = c.commentGroup(n.Doc)
+		cp.Name = c.ident(n.Name)
+		cp.TypeParams = c.fieldList(n.TypeParams)
+		cp.Type = c.expr(n.Type)
+		cp.Comment = c.commentGroup(n.Comment)
+		return &cp
+
+	case *ast.BadDecl:
+		cp := *n
+		return &cp
+	case *ast.GenDecl:
+		cp := *n
+		cp.Doc = c.commentGroup(n.Doc)
+		cp.Specs = make([]ast.Spec, len(n.Specs))
+		for i, spec := range n.Specs {
+			cp.Specs[i] = c.node(spec).(ast.Spec)
+		}
+		return &cp
+	case *ast.FuncDecl:
+		cp := *n
+		cp.Doc = c.commentGroup(n.Doc)
+		cp.Recv = c.fieldList(n.Recv)
+		cp.Name = c.ident(n.Name)
+		cp.Type = c.funcType(n.Type)
+		cp.Body = c.block(n.Body)
+		return &cp
+	}
+	panic(fmt.Sprintf("cloneFile: unexpected node %T", node))
+}
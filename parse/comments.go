@@ -0,0 +1,132 @@
+package parse
+
+import (
+	"bytes"
+	"go/ast"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// verbatimDirective marks a comment group that is copied as it is, without
+// substituting the specific types into it.
+const verbatimDirective = "//genny:verbatim"
+
+// reVerbatim matches the spans of a comment that are never rewritten:
+// backtick quoted code and URLs.
+var reVerbatim = regexp.MustCompile("`[^`]*`|\\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\\s`]+")
+
+// subIntoComment substitutes a specific type into the text of a comment. Only
+// whole words that are the name of the generic type or its plural, in either
+// case, and identifiers that contain them are rewritten, so that words such
+// as "typed" or "something" are left alone where the name is Type or Thing.
+// Backtick quoted spans and URLs are kept as they are.
+func subIntoComment(text string, spec replaceSpec) string {
+	var output strings.Builder
+	last := 0
+	for _, span := range reVerbatim.FindAllStringIndex(text, -1) {
+		output.WriteString(subIntoProse(text[last:span[0]], spec))
+		output.WriteString(text[span[0]:span[1]])
+		last = span[1]
+	}
+	output.WriteString(subIntoProse(text[last:], spec))
+	return output.String()
+}
+
+func subIntoProse(text string, spec replaceSpec) string {
+	if !spec.matcher.in(text) {
+		return text
+	}
+	return reWord.ReplaceAllStringFunc(text, func(w string) string {
+		if !isNameWord(w, spec.matcher) {
+			return w
+		}
+		return transformText(w, spec)
+	})
+}
+
+// isNameWord gets whether a word is the generic type name or its plural, or
+// an identifier that contains one of them as a camel case word, as in
+// NewItemQueue or itemsByKey, rather than an ordinary word that starts with
+// it.
+func isNameWord(w string, m *matcher) bool {
+	for _, name := range []string{m.name, m.plural} {
+		for i := 0; ; {
+			pos := indexBoundary(w[i:], name)
+			if pos < 0 {
+				break
+			}
+			i += pos + len(name)
+			if next, _ := utf8.DecodeRuneInString(w[i:]); !unicode.IsLower(next) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scanVerbatimLines finds the lines of the comment groups marked with the
+// verbatim directive. The first line of a group that trails code isn't one
+// of them.
+func (t *template) scanVerbatimLines() map[int]bool {
+	var lines map[int]bool
+	for _, group := range t.file.Comments {
+		if !hasDirective(group, verbatimDirective) {
+			continue
+		}
+		if lines == nil {
+			lines = make(map[int]bool)
+		}
+		start, end := t.fset.Position(group.Pos()), t.fset.Position(group.End())
+		for line := start.Line; line <= end.Line; line++ {
+			lines[line] = true
+		}
+		if strings.TrimSpace(t.lines[start.Line-1][:start.Column-1]) != "" {
+			delete(lines, start.Line)
+		}
+	}
+	return lines
+}
+
+// hasDirective gets whether the comment group contains the directive.
+func hasDirective(group *ast.CommentGroup, directive string) bool {
+	if group == nil {
+		return false
+	}
+	for _, comment := range group.List {
+		if strings.TrimSpace(comment.Text) == directive {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteDirectives deletes the directives from the output: the lines that are
+// only a directive, and directives at the end of a line.
+func deleteDirectives(output []byte, directives ...string) []byte {
+	var edits []edit
+	for start := 0; start < len(output); {
+		end := bytes.IndexByte(output[start:], '\n')
+		if end < 0 {
+			end = len(output)
+		} else {
+			end += start
+		}
+		line := bytes.TrimRight(output[start:end], " \t\r")
+		for _, directive := range directives {
+			if !bytes.HasSuffix(line, []byte(directive)) {
+				continue
+			}
+			code := bytes.TrimRight(line[:len(line)-len(directive)], " \t")
+			if len(bytes.TrimSpace(code)) == 0 {
+				edits = append(edits, deleteLine(output, start, end))
+			} else {
+				edits = append(edits, edit{start + len(code), end, ""})
+			}
+			break
+		}
+		start = end + 1
+	}
+	return applyEdits(output, edits)
+}
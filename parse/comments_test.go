@@ -0,0 +1,50 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tehbilly/genny/parse"
+)
+
+const commentsTemplate = `package comments
+
+import "github.com/tehbilly/genny/generic"
+
+type Item generic.Type
+
+// ItemQueue is a queue of items, typed or itemized. Make one with
+// ` + "`NewItemQueue()`" + `, as https://example.com/Item/itemQueue says.
+type ItemQueue struct {
+	items []Item // the Items, oldest first
+}
+
+//genny:verbatim
+// NewItemQueue makes an empty queue of Item.
+func NewItemQueue() *ItemQueue {
+	return &ItemQueue{}
+}
+`
+
+func TestGenerateComments(t *testing.T) {
+	typeSets, err := parse.TypeSet("Item=int")
+	require.NoError(t, err)
+	var outputs []string
+	for _, useAst := range []bool{true, false} {
+		out, err := parse.Generate("comments.go", strings.NewReader(commentsTemplate), typeSets, parse.Options{UseAst: useAst})
+		require.NoError(t, err)
+		for _, s := range []string{
+			"// IntQueue is a queue of ints, typed or itemized. Make one with\n",
+			"// `NewItemQueue()`, as https://example.com/Item/itemQueue says.\n",
+			"// the Ints, oldest first\n",
+			"\n// NewItemQueue makes an empty queue of Item.\nfunc NewIntQueue() *IntQueue {",
+		} {
+			assert.Contains(t, string(out), s, "ast: %v", useAst)
+		}
+		assert.NotContains(t, string(out), "genny:verbatim", "ast: %v", useAst)
+		outputs = append(outputs, string(out))
+	}
+	assert.Equal(t, outputs[0], outputs[1])
+}
@@ -0,0 +1,403 @@
+package parse
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// constantKinds maps the generic constant placeholders to the types of the
+// constants they are replaced with.
+var constantKinds = map[string]string{
+	"Int":    "int",
+	"Uint":   "uint",
+	"Float":  "float64",
+	"String": "string",
+	"Bool":   "bool",
+}
+
+// nameDirective marks a generic constant whose value takes part in the names
+// of the generated code. It goes in the doc comment or the line comment of
+// the constant, and is dropped from the output.
+const nameDirective = "//genny:name"
+
+// genericConstSpec is the declaration of a generic constant, like
+// `const Capacity generic.Int = 32`.
+type genericConstSpec struct {
+	name string
+	// kind is the generic placeholder, e.g. "Int".
+	kind string
+	// value is the default value in the template.
+	value string
+	// named is whether the value takes part in naming.
+	named bool
+}
+
+// scanGenericConsts finds the declarations of generic constants in a
+// template, in the order they are declared.
+func scanGenericConsts(fset *token.FileSet, file *ast.File, source []byte) []genericConstSpec {
+	var found []genericConstSpec
+	eachGenericConst(file, func(gd *ast.GenDecl, vs *ast.ValueSpec, kind string) {
+		named := hasNameDirective(vs.Doc) || hasNameDirective(vs.Comment) || len(gd.Specs) == 1 && hasNameDirective(gd.Doc)
+		for i, name := range vs.Names {
+			c := genericConstSpec{name: name.Name, kind: kind, named: named}
+			if i < len(vs.Values) {
+				c.value = string(source[fset.Position(vs.Values[i].Pos()).Offset:fset.Position(vs.Values[i].End()).Offset])
+			}
+			found = append(found, c)
+		}
+	})
+	return found
+}
+
+// eachGenericConst calls fn with every constant spec declared with a generic
+// constant placeholder.
+func eachGenericConst(file *ast.File, fn func(gd *ast.GenDecl, vs *ast.ValueSpec, kind string)) {
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			vs, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			sel, ok := vs.Type.(*ast.SelectorExpr)
+			if !ok {
+				continue
+			}
+			if kind, ok := genericPlaceholder(sel); ok && constantKinds[kind] != "" {
+				fn(gd, vs, kind)
+			}
+		}
+	}
+}
+
+func hasNameDirective(group *ast.CommentGroup) bool {
+	if group == nil {
+		return false
+	}
+	for _, comment := range group.List {
+		if comment.Text == nameDirective {
+			return true
+		}
+	}
+	return false
+}
+
+// literal gets the Go literal for a value given for the constant, which has
+// to be a constant expression of its kind. String values don't need to be
+// quoted.
+func (c genericConstSpec) literal(value string) (string, error) {
+	typ := constantKinds[c.kind]
+	if c.kind == "String" {
+		if _, err := strconv.Unquote(value); err != nil {
+			value = strconv.Quote(value)
+		}
+	}
+	expr, err := parser.ParseExpr(value)
+	if err != nil {
+		return "", &errBadConstant{Name: c.name, Value: value, Message: "not an expression"}
+	}
+	if c.kind == "Float" {
+		value = floatDivisions(value, expr)
+	}
+	tv, err := types.Eval(token.NewFileSet(), nil, token.NoPos, typ+"("+value+")")
+	if err != nil {
+		return "", &errBadConstant{Name: c.name, Value: value, Message: "not a valid " + typ + ": " + stripPosition(err.Error())}
+	}
+	if tv.Value == nil {
+		return "", &errBadConstant{Name: c.name, Value: value, Message: "not a constant"}
+	}
+	return value, nil
+}
+
+// floatDivisions makes the divisions of untyped integers in the value of a
+// float constant divide floats, so that 3/4 is 0.75 rather than 0. The value
+// is kept as it is if it has no such divisions.
+func floatDivisions(value string, expr ast.Expr) string {
+	fset := token.NewFileSet()
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	if err := types.CheckExpr(fset, nil, token.NoPos, expr, info); err != nil {
+		return value
+	}
+	changed := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		be, ok := n.(*ast.BinaryExpr)
+		if !ok || be.Op != token.QUO {
+			return true
+		}
+		if basic, ok := info.Types[be].Type.(*types.Basic); !ok || basic.Info()&(types.IsInteger|types.IsUntyped) != types.IsInteger|types.IsUntyped {
+			return true
+		}
+		if lit, ok := be.X.(*ast.BasicLit); ok && lit.Kind == token.INT {
+			lit.Kind, lit.Value = token.FLOAT, lit.Value+".0"
+		} else if lit, ok := be.Y.(*ast.BasicLit); ok && lit.Kind == token.INT {
+			lit.Kind, lit.Value = token.FLOAT, lit.Value+".0"
+		} else {
+			be.X = &ast.CallExpr{Fun: ast.NewIdent("float64"), Args: []ast.Expr{be.X}}
+		}
+		changed = true
+		return true
+	})
+	if !changed {
+		return value
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, expr); err != nil {
+		return value
+	}
+	if _, err := types.Eval(fset, nil, token.NoPos, "float64("+buf.String()+")"); err != nil {
+		return value
+	}
+	return buf.String()
+}
+
+// rePosition matches the position types.Eval puts in front of its errors.
+var rePosition = regexp.MustCompile(`^[^:]*:\d+:\d+: `)
+
+// stripPosition drops the position from an error of types.Eval.
+func stripPosition(msg string) string {
+	return rePosition.ReplaceAllString(msg, "")
+}
+
+// rename gets what an identifier is renamed to for the word of the value of
+// a named constant. The constant itself gets the word appended to its name,
+// other identifiers have the name of the constant replaced with it, or
+// appended if that would not be a valid identifier.
+func (c genericConstSpec) rename(name, word string) string {
+	if name == c.name {
+		return name + word
+	}
+	if !containsBoundary(name, c.name) {
+		return name
+	}
+	renamed := replaceBoundaryFunc(name, c.name, func(match string) string {
+		return wordify(word, startsUpper(match))
+	})
+	if !token.IsIdentifier(renamed) {
+		renamed = replaceBoundaryFunc(name, c.name, func(match string) string {
+			return match + word
+		})
+	}
+	return renamed
+}
+
+// constantWord gets the word for the value of a constant, which is used in
+// names. Only letters and digits are kept, and a leading minus becomes
+// "Neg". The alias is used instead if the value has one.
+func constantWord(ref TypeRef) string {
+	s := ref.Alias
+	word := strings.Map(func(r rune) rune {
+		if r != '_' && isAlphaNumeric(r) {
+			return r
+		}
+		return -1
+	}, s)
+	if strings.HasPrefix(strings.TrimSpace(s), "-") {
+		word = "Neg" + word
+	}
+	if word == "" {
+		return ""
+	}
+	return wordify(word, true)
+}
+
+// isConstName gets whether a name is that of a generic constant.
+func (t *template) isConstName(name string) bool {
+	for _, c := range t.consts {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// typesOnly gets the typeSet without the values of the generic constants,
+// which the engines don't substitute.
+func (t *template) typesOnly(typeSet map[string]TypeRef) map[string]TypeRef {
+	if len(t.consts) == 0 {
+		return typeSet
+	}
+	types := make(map[string]TypeRef, len(typeSet))
+	for name, ref := range typeSet {
+		if !t.isConstName(name) {
+			types[name] = ref
+		}
+	}
+	return types
+}
+
+// applyConsts gives the generic constants in the code generated for the
+// typeSet their types and values, and renames the identifiers of named
+// constants. Constants without a value in the typeSet keep their default.
+func (t *template) applyConsts(output []byte, typeSet map[string]TypeRef, trace *substitutionTrace) ([]byte, error) {
+	literals := make([]string, len(t.consts))
+	for i, c := range t.consts {
+		literals[i] = c.value
+		if ref, ok := typeSet[c.name]; ok {
+			literal, err := c.literal(ref.Type)
+			if err != nil {
+				return nil, err
+			}
+			literals[i] = literal
+		}
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", output, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		// leave it to goimports to report
+		return output, nil
+	}
+	offset := func(pos token.Pos) int {
+		return fset.Position(pos).Offset
+	}
+	replace := func(kind string, node ast.Node, before, after string) edit {
+		trace.recordPosition(kind, fset.Position(node.Pos()), before, after)
+		return edit{offset(node.Pos()), offset(node.End()), after}
+	}
+
+	var edits []edit
+	replaced := make(map[ast.Node]bool)
+	index := 0
+	eachGenericConst(file, func(_ *ast.GenDecl, vs *ast.ValueSpec, kind string) {
+		edits = append(edits, replace("CONST TYPE", vs.Type, genericPackage+"."+kind, constantKinds[kind]))
+		for i := range vs.Names {
+			if index < len(t.consts) && i < len(vs.Values) && literals[index] != t.consts[index].value {
+				edits = append(edits, replace("CONST VALUE", vs.Values[i], t.consts[index].value, literals[index]))
+				replaced[vs.Values[i]] = true
+			}
+			index++
+		}
+	})
+
+	// the words of the named constants, in the order they are declared
+	var named []genericConstSpec
+	var words []string
+	for _, c := range t.consts {
+		if ref, ok := typeSet[c.name]; ok && c.named {
+			named = append(named, c)
+			words = append(words, constantWord(ref))
+		}
+	}
+	renames := make(map[string]string)
+	for _, decl := range file.Decls {
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if replaced[n] {
+				return false
+			}
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				// neither the generic placeholders nor other packages
+				if x, ok := n.X.(*ast.Ident); ok && isImportName(file, x.Name) {
+					return false
+				}
+			case *ast.Ident:
+				to := n.Name
+				for i, c := range named {
+					to = c.rename(to, words[i])
+				}
+				if to != n.Name {
+					renames[n.Name] = to
+					edits = append(edits, replace("CONST NAME", n, n.Name, to))
+				}
+			}
+			return true
+		})
+	}
+
+	froms := make([]string, 0, len(renames))
+	for from := range renames {
+		froms = append(froms, from)
+	}
+	// longer names first, so that they are not renamed by their prefixes
+	sort.Slice(froms, func(i, j int) bool { return len(froms[i]) > len(froms[j]) })
+	var renamed *regexp.Regexp
+	if len(froms) > 0 {
+		quoted := make([]string, len(froms))
+		for i, from := range froms {
+			quoted[i] = regexp.QuoteMeta(from)
+		}
+		renamed = regexp.MustCompile(`\b(` + strings.Join(quoted, "|") + `)\b`)
+	}
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if comment.Text == nameDirective {
+				edits = append(edits, deleteLine(output, offset(comment.Pos()), offset(comment.End())))
+				continue
+			}
+			if renamed == nil {
+				continue
+			}
+			if text := renamed.ReplaceAllStringFunc(comment.Text, func(from string) string { return renames[from] }); text != comment.Text {
+				edits = append(edits, replace("COMMENT", comment, comment.Text, text))
+			}
+		}
+	}
+	return applyEdits(output, edits), trace.error()
+}
+
+// isImportName gets whether a name is that of an imported package.
+func isImportName(file *ast.File, name string) bool {
+	for _, spec := range file.Imports {
+		if spec.Name != nil {
+			if spec.Name.Name == name {
+				return true
+			}
+			continue
+		}
+		if importPath, err := strconv.Unquote(spec.Path.Value); err == nil && importPath[strings.LastIndex(importPath, "/")+1:] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteLine gets the edit that deletes a comment, along with its line if
+// there is nothing else on it.
+func deleteLine(source []byte, start, end int) edit {
+	lineStart := start
+	for lineStart > 0 && (source[lineStart-1] == ' ' || source[lineStart-1] == '\t') {
+		lineStart--
+	}
+	if (lineStart == 0 || source[lineStart-1] == '\n') && end < len(source) && source[end] == '\n' {
+		end++
+	}
+	return edit{lineStart, end, ""}
+}
+
+// withConstants gets copies of the typeSets with the values of the constants
+// added to those that don't give one.
+func withConstants(typeSets []map[string]TypeRef, constants map[string]string) ([]map[string]TypeRef, error) {
+	if len(constants) == 0 {
+		return typeSets, nil
+	}
+	refs := make(map[string]TypeRef, len(constants))
+	for name, value := range constants {
+		ref, err := ParseTypeRef(value)
+		if err != nil {
+			return nil, &errBadConstant{Name: name, Value: value, Message: err.Error()}
+		}
+		refs[name] = *ref
+	}
+	merged := make([]map[string]TypeRef, len(typeSets))
+	for i, typeSet := range typeSets {
+		merged[i] = make(map[string]TypeRef, len(typeSet)+len(refs))
+		for name, ref := range refs {
+			merged[i][name] = ref
+		}
+		for name, ref := range typeSet {
+			merged[i][name] = ref
+		}
+	}
+	return merged, nil
+}
@@ -0,0 +1,125 @@
+package parse_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tehbilly/genny/parse"
+)
+
+func TestGenerateConstants(t *testing.T) {
+	in, err := contents("test/constants/ring_generic.go")
+	require.NoError(t, err)
+	expectedOut, err := contents("test/constants/ring_expected.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("Item=int Degree=64,Big:1024")
+	require.NoError(t, err)
+
+	for _, useAst := range []bool{true, false} {
+		opts := parse.Options{UseAst: useAst, StripTags: []string{"genny"}, Constants: map[string]string{"Label": "buffer"}}
+		out, err := parse.Generate("test/constants/ring_generic.go", strings.NewReader(in), typeSets, opts)
+		require.NoError(t, err)
+		assert.Equal(t, expectedOut, string(out))
+
+		var streamed strings.Builder
+		err = parse.GenerateTo(&streamed, "test/constants/ring_generic.go", strings.NewReader(in), typeSets, opts)
+		require.NoError(t, err)
+		assert.Equal(t, expectedOut, streamed.String())
+	}
+}
+
+func TestGenerateConstantDefaults(t *testing.T) {
+	in, err := contents("test/constants/ring_generic.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("Item=int Label=queue")
+	require.NoError(t, err)
+
+	for _, useAst := range []bool{true, false} {
+		// the typeset wins over the constants, and the defaults are kept
+		opts := parse.Options{UseAst: useAst, Constants: map[string]string{"Label": "buffer"}}
+		out, err := parse.Generate("test/constants/ring_generic.go", strings.NewReader(in), typeSets, opts)
+		require.NoError(t, err)
+		assert.Contains(t, string(out), "const Label string = \"queue\"\n")
+		assert.Contains(t, string(out), "const Degree int = 32\n")
+		assert.Contains(t, string(out), "type IntRingDegree struct")
+		assert.NotContains(t, string(out), "genny:name")
+	}
+}
+
+func TestGenerateBadConstants(t *testing.T) {
+	in, err := contents("test/constants/ring_generic.go")
+	require.NoError(t, err)
+
+	for _, ts := range []string{
+		"Item=int Degree=1.5",
+		"Item=int Degree=99999999999999999999",
+		"Item=int Degree=abc",
+		"Item=int Degree=1)+(2",
+	} {
+		typeSets, err := parse.TypeSet(ts)
+		require.NoError(t, err)
+		for _, useAst := range []bool{true, false} {
+			_, err = parse.Generate("test/constants/ring_generic.go", strings.NewReader(in), typeSets, parse.Options{UseAst: useAst})
+			if assert.Error(t, err, ts) {
+				assert.Contains(t, err.Error(), "for constant 'Degree'", ts)
+			}
+		}
+	}
+}
+
+func TestGenerateConstantExpressions(t *testing.T) {
+	in := `package p
+
+import "github.com/tehbilly/genny/generic"
+
+const LoadFactor generic.Float = 0.75
+`
+	typeSets, err := parse.TypeSet("LoadFactor=3/4")
+	require.NoError(t, err)
+	for _, useAst := range []bool{true, false} {
+		out, err := parse.Generate("load.go", strings.NewReader(in), typeSets, parse.Options{UseAst: useAst})
+		require.NoError(t, err)
+		assert.Contains(t, string(out), "const LoadFactor float64 = 3.0 / 4\n", "ast: %v", useAst)
+		assert.Equal(t, "0.75", constantValue(t, out, "LoadFactor"), "ast: %v", useAst)
+	}
+}
+
+func TestGenerateFloatDivisions(t *testing.T) {
+	in := `package p
+
+import "github.com/tehbilly/genny/generic"
+
+const Ratio generic.Float = 0.5
+`
+	for value, want := range map[string]string{
+		"1/2/4":         "0.125",
+		"(1+2)/(3+1)":   "0.75",
+		"7/2.0":         "3.5",
+		"1<<3":          "8",
+		"1.5":           "1.5",
+		"10/4+1/4*2":    "3",
+		"3/4*(8/(1+1))": "3",
+	} {
+		typeSets, err := parse.TypeSet("Ratio=" + value)
+		require.NoError(t, err)
+		out, err := parse.Generate("ratio.go", strings.NewReader(in), typeSets, parse.Options{})
+		require.NoError(t, err, value)
+		assert.Equal(t, want, constantValue(t, out, "Ratio"), value)
+	}
+}
+
+// constantValue gets the value of a constant of generated code.
+func constantValue(t *testing.T, source []byte, name string) string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "out.go", source, 0)
+	require.NoError(t, err)
+	pkg, err := (&types.Config{}).Check("p", fset, []*ast.File{file}, nil)
+	require.NoError(t, err)
+	return pkg.Scope().Lookup(name).(*types.Const).Val().String()
+}
@@ -0,0 +1,122 @@
+package parse
+
+import (
+	"go/build/constraint"
+	"strings"
+)
+
+// constraintRewrite rewrites the build constraint of a template for the
+// output: the stripped tags are taken out of the expression, and the added
+// constraints are and-ed to it.
+type constraintRewrite struct {
+	strip map[string]bool
+	add   constraint.Expr
+}
+
+// newConstraintRewrite makes the rewrite for the tags to strip and the
+// constraint expressions to add, e.g. "!purego". It gets nil if there is
+// nothing to rewrite.
+func newConstraintRewrite(strip, add []string) (*constraintRewrite, error) {
+	if len(strip) == 0 && len(add) == 0 {
+		return nil, nil
+	}
+	r := &constraintRewrite{strip: make(map[string]bool)}
+	for _, tag := range strip {
+		r.strip[tag] = true
+	}
+	for _, text := range add {
+		expr, err := constraint.Parse("//go:build " + text)
+		if err != nil {
+			return nil, &errBuildConstraint{Constraint: text, Message: err.Error()}
+		}
+		r.add = andExpr(r.add, expr)
+	}
+	return r, nil
+}
+
+// isConstraintLine gets whether a line is a `//go:build` or `// +build`
+// constraint.
+func isConstraintLine(line string) bool {
+	line = strings.TrimSpace(line)
+	return constraint.IsGoBuild(line) || constraint.IsPlusBuild(line)
+}
+
+// rewrite gets the constraint lines of the output from those of the
+// template, which may be none. The `//go:build` line is used if there is
+// one, since it is the one the go tool reads; otherwise the `// +build` lines
+// are. Both forms are written.
+func (r *constraintRewrite) rewrite(lines []string) ([]string, error) {
+	var goBuild, plusBuild constraint.Expr
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		expr, err := constraint.Parse(line)
+		if err != nil {
+			return nil, &errBuildConstraint{Constraint: line, Message: err.Error()}
+		}
+		if constraint.IsGoBuild(line) {
+			goBuild = expr
+		} else {
+			plusBuild = andExpr(plusBuild, expr)
+		}
+	}
+	expr := goBuild
+	if expr == nil {
+		expr = plusBuild
+	}
+
+	if expr != nil {
+		expr = r.stripTags(expr)
+	}
+	expr = andExpr(expr, r.add)
+	if expr == nil {
+		return nil, nil
+	}
+
+	rewritten := []string{makeLine("//go:build " + expr.String())}
+	// some expressions are too complex for the old form, which go 1.17 and
+	// later don't need
+	if plusLines, err := constraint.PlusBuildLines(expr); err == nil {
+		for _, line := range plusLines {
+			rewritten = append(rewritten, makeLine(line))
+		}
+	}
+	return rewritten, nil
+}
+
+// stripTags takes the stripped tags out of an expression, along with the
+// negations of them, as if they had never been in it. It gets nil if nothing
+// is left.
+func (r *constraintRewrite) stripTags(expr constraint.Expr) constraint.Expr {
+	switch x := expr.(type) {
+	case *constraint.TagExpr:
+		if r.strip[x.Tag] {
+			return nil
+		}
+	case *constraint.NotExpr:
+		if sx := r.stripTags(x.X); sx != nil {
+			return &constraint.NotExpr{X: sx}
+		}
+		return nil
+	case *constraint.AndExpr:
+		return andExpr(r.stripTags(x.X), r.stripTags(x.Y))
+	case *constraint.OrExpr:
+		sx, sy := r.stripTags(x.X), r.stripTags(x.Y)
+		if sx == nil || sy == nil {
+			// what is left of the other side
+			return andExpr(sx, sy)
+		}
+		return &constraint.OrExpr{X: sx, Y: sy}
+	}
+	return expr
+}
+
+// andExpr ands two expressions, either of which may be nil.
+func andExpr(x, y constraint.Expr) constraint.Expr {
+	switch {
+	case x == nil:
+		return y
+	case y == nil:
+		return x
+	}
+	return &constraint.AndExpr{X: x, Y: y}
+}
@@ -0,0 +1,89 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraintRewrite(t *testing.T) {
+	for _, test := range []struct {
+		lines       []string
+		strip, add  []string
+		expected    []string
+		expectedErr bool
+	}{
+		{
+			lines:    []string{"//go:build genny && linux", "// +build genny,linux"},
+			strip:    []string{"genny"},
+			expected: []string{"//go:build linux\n", "// +build linux\n"},
+		},
+		{
+			// the go:build line wins
+			lines:    []string{"// +build genny", "//go:build genny && (linux || windows)"},
+			strip:    []string{"genny"},
+			expected: []string{"//go:build linux || windows\n", "// +build linux windows\n"},
+		},
+		{
+			lines:    []string{"// +build genny", "// +build !js"},
+			strip:    []string{"genny"},
+			expected: []string{"//go:build !js\n", "// +build !js\n"},
+		},
+		{
+			lines:    []string{"//go:build genny || gennymaps"},
+			strip:    []string{"genny"},
+			expected: []string{"//go:build gennymaps\n", "// +build gennymaps\n"},
+		},
+		{
+			lines: []string{"//go:build genny || gennymaps"},
+			strip: []string{"genny", "gennymaps"},
+		},
+		{
+			lines:    []string{"//go:build genny && !gennymaps && amd64"},
+			strip:    []string{"genny", "gennymaps"},
+			expected: []string{"//go:build amd64\n", "// +build amd64\n"},
+		},
+		{
+			// the negation of a stripped tag goes as well
+			lines: []string{"//go:build !genny", "// +build !genny"},
+			strip: []string{"genny"},
+		},
+		{
+			lines:       []string{"//go:build linux &&"},
+			expectedErr: true,
+		},
+		{
+			lines:    []string{"//go:build genny"},
+			strip:    []string{"genny"},
+			add:      []string{"!purego", "amd64 || arm64"},
+			expected: []string{"//go:build !purego && (amd64 || arm64)\n", "// +build !purego\n", "// +build amd64 arm64\n"},
+		},
+		{
+			add:      []string{"!purego"},
+			expected: []string{"//go:build !purego\n", "// +build !purego\n"},
+		},
+		{
+			lines:    []string{"//go:build linux"},
+			expected: []string{"//go:build linux\n", "// +build linux\n"},
+			strip:    []string{"genny"},
+		},
+	} {
+		r, err := newConstraintRewrite(test.strip, test.add)
+		require.NoError(t, err)
+		lines, err := r.rewrite(test.lines)
+		if test.expectedErr {
+			assert.IsType(t, &errBuildConstraint{}, err, "%v", test.lines)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, lines, "%v", test.lines)
+	}
+
+	_, err := newConstraintRewrite(nil, []string{"linux &&"})
+	assert.IsType(t, &errBuildConstraint{}, err)
+
+	r, err := newConstraintRewrite(nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+}
@@ -2,6 +2,8 @@ package parse
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 )
 
 // errMissingSpecificType represents an error when a generic type is not
@@ -18,6 +20,8 @@ func (e errMissingSpecificType) Error() string {
 // errImports represents an error from goimports.
 type errImports struct {
 	Err error
+	// Source is the code that failed to be formatted, if any.
+	Source []byte
 }
 
 // Error gets a human readable string describing this error.
@@ -25,6 +29,11 @@ func (e errImports) Error() string {
 	return "Failed to goimports the generated code: " + e.Err.Error()
 }
 
+// Unwrap gets the error from goimports.
+func (e errImports) Unwrap() error {
+	return e.Err
+}
+
 // errSource represents an error with the source file.
 type errSource struct {
 	Err error
@@ -45,3 +54,78 @@ func (e errBadTypeArgs) Error() string {
 }
 
 var errMissingTypeInformation = errors.New("No type arguments were specified and no \"// +gogen\" tag was found in the source.")
+
+// errBadSamples represents an error with the sample values used to
+// generate tests.
+type errBadSamples struct {
+	Message string
+}
+
+// Error gets a human readable string describing this error.
+func (e errBadSamples) Error() string {
+	return "Bad samples: " + e.Message
+}
+
+// errUnexportedReference represents an error when a template refers to
+// unexported declarations of its package, which can't be reached from the
+// package the code is generated into.
+type errUnexportedReference struct {
+	Package string
+	Names   []string
+}
+
+// Error gets a human readable string describing this error.
+func (e errUnexportedReference) Error() string {
+	return "Cannot refer to unexported declarations of " + e.Package + " from another package: " + strings.Join(e.Names, ", ")
+}
+
+// errBuildConstraint represents an error with a build constraint of the
+// template or one that is added to the output.
+type errBuildConstraint struct {
+	Constraint string
+	Message    string
+}
+
+// Error gets a human readable string describing this error.
+func (e errBuildConstraint) Error() string {
+	return "Bad build constraint \"" + e.Constraint + "\": " + e.Message
+}
+
+// errBadConstant represents an error when the value given for a generic
+// constant doesn't fit its kind.
+type errBadConstant struct {
+	Name    string
+	Value   string
+	Message string
+}
+
+// Error gets a human readable string describing this error.
+func (e errBadConstant) Error() string {
+	return "Bad value " + e.Value + " for constant '" + e.Name + "': " + e.Message
+}
+
+// errRenameConflict represents an error when renaming a top-level
+// declaration gives it the name of another one.
+type errRenameConflict struct {
+	Name  string
+	Other string
+	New   string
+}
+
+// Error gets a human readable string describing this error.
+func (e errRenameConflict) Error() string {
+	return "Cannot rename " + e.Name + " to " + e.New + ": it is the name of " + e.Other + " too"
+}
+
+// errPanic represents a panic while generating the code of a typeset, which
+// is recovered so that it doesn't take the other templates being generated
+// down with it.
+type errPanic struct {
+	TypeSet string
+	Value   interface{}
+}
+
+// Error gets a human readable string describing this error.
+func (e errPanic) Error() string {
+	return fmt.Sprintf("Panic while generating %s: %v", e.TypeSet, e.Value)
+}
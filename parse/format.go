@@ -0,0 +1,151 @@
+package parse
+
+import (
+	"bytes"
+	"errors"
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/printer"
+	"go/scanner"
+	"go/token"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/imports"
+)
+
+// FormatOptions controls how the output is formatted. The zero value
+// formats it like goimports does by default.
+type FormatOptions struct {
+	// LocalPrefix is a comma separated list of import path prefixes. Their
+	// imports are grouped after the third party ones, like goimports -local
+	// does.
+	LocalPrefix string
+	// FormatOnly only formats the output, like gofmt, without adding or
+	// removing imports. The imports of the template are kept, other than the
+	// generic package, so the packages of the specific types may need to be
+	// added with Options.Imports.
+	FormatOnly bool
+	// TabWidth is the width of a tab, which is used for alignment. It is 8
+	// if zero.
+	TabWidth int
+	// StripComments drops the comments from the output, including the
+	// header. The generated code marker, the build constraints, the
+	// provenance and directives such as //go:embed are kept.
+	StripComments bool
+}
+
+// localPrefixMu guards imports.LocalPrefix, which goimports reads from a
+// global. Processing without a local prefix only needs to read it.
+var localPrefixMu sync.RWMutex
+
+// process formats src with goimports. If formatOnly is true, imports are not
+// fixed whatever the options say.
+func (o FormatOptions) process(filename string, src []byte, formatOnly bool) ([]byte, error) {
+	opt := &imports.Options{
+		Comments:   true,
+		TabIndent:  true,
+		TabWidth:   o.TabWidth,
+		FormatOnly: o.FormatOnly || formatOnly,
+	}
+	if opt.TabWidth == 0 {
+		opt.TabWidth = 8
+	}
+
+	if o.LocalPrefix == "" {
+		localPrefixMu.RLock()
+		defer localPrefixMu.RUnlock()
+	} else {
+		localPrefixMu.Lock()
+		defer localPrefixMu.Unlock()
+		imports.LocalPrefix = o.LocalPrefix
+		defer func() { imports.LocalPrefix = "" }()
+	}
+	output, err := imports.Process(filename, src, opt)
+	if err != nil {
+		return nil, &errImports{Err: err, Source: src}
+	}
+	if o.StripComments {
+		return stripComments(filename, output, opt.TabWidth)
+	}
+	return output, nil
+}
+
+// reDirective matches the comments that are directives to tools, like
+// //go:embed and //line, which go/ast leaves out of the text of comments too.
+var reDirective = regexp.MustCompile(`^//(line |extern |export |[a-z0-9]+:[a-z0-9])`)
+
+// keepComment gets whether a comment is kept when comments are stripped.
+func keepComment(text string) bool {
+	return reGenerated.MatchString(text) || strings.HasPrefix(text, provenancePrefix) ||
+		constraint.IsGoBuild(text) || constraint.IsPlusBuild(text) || reDirective.MatchString(text)
+}
+
+// stripComments drops the comments of formatted code other than those that
+// keepComment keeps.
+func stripComments(filename string, src []byte, tabWidth int) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, &errImports{Err: err, Source: src}
+	}
+	var kept []*ast.CommentGroup
+	for _, group := range file.Comments {
+		var list []*ast.Comment
+		for _, c := range group.List {
+			if keepComment(c.Text) {
+				list = append(list, c)
+			}
+		}
+		if len(list) > 0 {
+			kept = append(kept, &ast.CommentGroup{List: list})
+		}
+	}
+	file.Comments = kept
+
+	var buf bytes.Buffer
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: tabWidth}
+	if err := cfg.Fprint(&buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// withoutGenericImport drops the import of the generic package, which
+// goimports would otherwise remove as unused.
+func withoutGenericImport(specs stringArraySet) stringArraySet {
+	var kept stringArraySet
+	for _, spec := range specs {
+		fields := strings.Fields(spec)
+		if len(fields) > 0 {
+			if importPath, err := strconv.Unquote(fields[len(fields)-1]); err == nil && path.Base(importPath) == genericPackage {
+				continue
+			}
+		}
+		kept = append(kept, spec)
+	}
+	return kept
+}
+
+// Unformatted gets the generated code that could not be formatted, and the
+// line of it where formatting failed, or 0 if that isn't known. ok is false
+// if err is not a formatting error.
+func Unformatted(err error) (source []byte, line int, ok bool) {
+	var e *errImports
+	if !errors.As(err, &e) || e.Source == nil {
+		return nil, 0, false
+	}
+	var list scanner.ErrorList
+	var single *scanner.Error
+	switch {
+	case errors.As(e.Err, &list) && len(list) > 0:
+		line = list[0].Pos.Line
+	case errors.As(e.Err, &single):
+		line = single.Pos.Line
+	}
+	return e.Source, line, true
+}
@@ -0,0 +1,97 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tehbilly/genny/parse"
+)
+
+func TestGenerateFormat(t *testing.T) {
+	in, err := contents("test/format/generic_check.go")
+	require.NoError(t, err)
+	expectedOut, err := contents("test/format/int_check.go")
+	require.NoError(t, err)
+	typeSets := []map[string]parse.TypeRef{{"Item": parse.TypeRef{Alias: "int", Type: "int"}}}
+
+	for _, useAst := range []bool{true, false} {
+		// local imports go in their own group
+		opts := parse.Options{UseAst: useAst, Format: parse.FormatOptions{LocalPrefix: "github.com/tehbilly/genny"}}
+		out, err := parse.Generate("test/format/generic_check.go", strings.NewReader(in), typeSets, opts)
+		require.NoError(t, err)
+		assert.Equal(t, expectedOut, string(out))
+
+		var streamed strings.Builder
+		err = parse.GenerateTo(&streamed, "test/format/generic_check.go", strings.NewReader(in), typeSets, opts)
+		require.NoError(t, err)
+		assert.Equal(t, expectedOut, streamed.String())
+
+		// without it they don't
+		out, err = parse.Generate("test/format/generic_check.go", strings.NewReader(in), typeSets, parse.Options{UseAst: useAst})
+		require.NoError(t, err)
+		assert.Contains(t, string(out), "\t\"github.com/stretchr/testify/assert\"\n\t\"github.com/tehbilly/genny/out\"\n")
+
+		// the generated code marker, the build constraints and the
+		// provenance are kept
+		opts = parse.Options{UseAst: useAst, Format: parse.FormatOptions{StripComments: true}, BuildConstraints: []string{"!purego"},
+			Provenance: &parse.Provenance{Args: []string{"-stripcomments", "gen", "Something=int"}}}
+		out, err = parse.Generate("test/format/generic_check.go", strings.NewReader(in), typeSets, opts)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(out), "// Code generated by genny. DO NOT EDIT.\n"), string(out))
+		assert.Contains(t, string(out), "\n// genny:args -stripcomments gen Something=int\n")
+		assert.Contains(t, string(out), "\n//go:build !purego\n// +build !purego\n\npackage ")
+		assert.NotContains(t, string(out), "automatically generated")
+		p, err := parse.ReadProvenance(strings.NewReader(string(out)))
+		require.NoError(t, err)
+		assert.Equal(t, opts.Provenance.Args, p.Args)
+		lines := 0
+		for _, line := range strings.Split(string(out), "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "//") {
+				lines++
+			}
+		}
+		assert.Equal(t, 5, lines, string(out))
+	}
+}
+
+func TestGenerateFormatOnly(t *testing.T) {
+	in, err := contents("test/queue/generic_queue.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("Something=time.Time")
+	require.NoError(t, err)
+
+	for _, useAst := range []bool{true, false} {
+		// the package of the type isn't imported
+		opts := parse.Options{UseAst: useAst, Format: parse.FormatOptions{FormatOnly: true}}
+		out, err := parse.Generate("generic_queue.go", strings.NewReader(in), typeSets, opts)
+		require.NoError(t, err)
+		assert.NotContains(t, string(out), "import")
+		assert.Contains(t, string(out), "func NewTimeTimeQueue() *TimeTimeQueue {\n")
+
+		opts.Imports = []string{"time"}
+		out, err = parse.Generate("generic_queue.go", strings.NewReader(in), typeSets, opts)
+		require.NoError(t, err)
+		assert.Contains(t, string(out), "import \"time\"\n")
+	}
+}
+
+func TestUnformatted(t *testing.T) {
+	in, err := contents("test/queue/generic_queue.go")
+	require.NoError(t, err)
+	typeSets := []map[string]parse.TypeRef{{"Something": parse.TypeRef{Alias: "Broken", Type: "map[int"}}}
+
+	for _, useAst := range []bool{true, false} {
+		_, err = parse.Generate("generic_queue.go", strings.NewReader(in), typeSets, parse.Options{UseAst: useAst})
+		require.Error(t, err)
+		source, line, ok := parse.Unformatted(err)
+		require.True(t, ok)
+		lines := strings.Split(string(source), "\n")
+		require.True(t, line > 0 && line <= len(lines), "line %d", line)
+		assert.Contains(t, lines[line-1], "map[int")
+	}
+
+	_, _, ok := parse.Unformatted(assert.AnError)
+	assert.False(t, ok)
+}
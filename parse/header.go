@@ -0,0 +1,232 @@
+package parse
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultHeader is the comment at the top of generated files.
+const DefaultHeader = `// Code generated by genny. DO NOT EDIT.
+// This file was automatically generated by genny.
+// Any changes will be lost if this file is regenerated.
+// see https://github.com/tehbilly/genny
+`
+
+// generatedMarker is the first line of DefaultHeader. A header has to have a
+// line like it for tools to recognise generated files.
+const generatedMarker = "// Code generated by genny. DO NOT EDIT."
+
+var reGenerated = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// provenancePrefix starts the lines of the header that record provenance.
+const provenancePrefix = "// genny:"
+
+// Provenance records how a file was generated, so that it can be generated
+// again.
+type Provenance struct {
+	// Template is the path of the template, relative to the directory of the
+	// generated file.
+	Template string
+	// Hash is the hash of the contents of the template. Generate fills it
+	// in.
+	Hash string
+	// Dir is the directory genny ran in, relative to the directory of the
+	// generated file.
+	Dir string
+	// Args are the command line arguments genny ran with.
+	Args []string
+	// Version is the version of genny.
+	Version string
+}
+
+// lines gets the header lines that record the provenance.
+func (p *Provenance) lines() []string {
+	var lines []string
+	add := func(key, value string) {
+		if value != "" {
+			lines = append(lines, provenancePrefix+key+" "+value+"\n")
+		}
+	}
+	add("template", p.Template)
+	add("hash", p.Hash)
+	add("dir", p.Dir)
+	add("args", quoteArgs(p.Args))
+	add("version", p.Version)
+	return lines
+}
+
+// TemplateChanged gets whether source, the template the file was generated
+// from, has changed since, by its hash. It hasn't if there is no hash.
+func (p *Provenance) TemplateChanged(source []byte) bool {
+	return p.Hash != "" && p.Hash != hashSource(source)
+}
+
+// quoteArgs joins command line arguments, quoting those that need it.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'`\\$") {
+			arg = strconv.Quote(arg)
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+// hashSource gets the hash of a template that is recorded as its
+// provenance.
+func hashSource(source []byte) string {
+	sum := sha256.Sum256(source)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// fileHeader is what goes at the top of a generated file.
+type fileHeader struct {
+	// lines are the lines of the header, ending with a blank line.
+	lines []string
+	// license is whether the template has a leading comment, which is
+	// moved to the top of the header.
+	license bool
+}
+
+// newFileHeader makes the header of a file generated from the template.
+func newFileHeader(file *ast.File, fset *token.FileSet, source []byte, opts Options) fileHeader {
+	var h fileHeader
+
+	if license := leadingComment(file); license != nil {
+		start := fset.Position(license.Pos()).Offset
+		end := fset.Position(license.End()).Offset
+		for _, line := range strings.Split(string(source[start:end]), "\n") {
+			h.lines = append(h.lines, makeLine(line))
+		}
+		h.lines = append(h.lines, fmt.Sprintln())
+		h.license = true
+	}
+
+	text := opts.Header
+	if text == "" {
+		text = DefaultHeader
+	}
+	hasMarker := false
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(text, "\r\n"), "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "//"):
+		case strings.TrimSpace(line) == "":
+			line = "//"
+		default:
+			line = "// " + line
+		}
+		hasMarker = hasMarker || reGenerated.MatchString(line)
+		lines = append(lines, makeLine(line))
+	}
+	if !hasMarker {
+		h.lines = append(h.lines, makeLine(generatedMarker))
+	}
+	h.lines = append(h.lines, lines...)
+
+	if opts.Provenance != nil {
+		p := *opts.Provenance
+		p.Hash = hashSource(source)
+		h.lines = append(h.lines, makeLine("//"))
+		h.lines = append(h.lines, p.lines()...)
+	}
+	h.lines = append(h.lines, fmt.Sprintln())
+	return h
+}
+
+// leadingComment gets the comment at the top of a template, such as a
+// license, if it has one. Build constraints and the package documentation
+// don't count.
+func leadingComment(file *ast.File) *ast.CommentGroup {
+	if len(file.Comments) == 0 {
+		return nil
+	}
+	group := file.Comments[0]
+	if group == file.Doc || group.Pos() > file.Package {
+		return nil
+	}
+	for _, comment := range group.List {
+		if strings.HasPrefix(comment.Text, "//go:build") || strings.HasPrefix(comment.Text, "// +build") ||
+			strings.HasPrefix(comment.Text, "//genny:") || reGenerated.MatchString(comment.Text) {
+			return nil
+		}
+	}
+	return group
+}
+
+// ReadProvenance reads the provenance recorded in the header of a generated
+// file. It gets nil if there is none.
+func ReadProvenance(r io.Reader) (*Provenance, error) {
+	var p *Provenance
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, string(packageKeyword)+" ") {
+			break
+		}
+		if !strings.HasPrefix(line, provenancePrefix) {
+			continue
+		}
+		if p == nil {
+			p = &Provenance{}
+		}
+		key, value := line[len(provenancePrefix):], ""
+		if i := strings.IndexByte(key, ' '); i >= 0 {
+			key, value = key[:i], strings.TrimSpace(key[i+1:])
+		}
+		switch key {
+		case "template":
+			p.Template = value
+		case "hash":
+			p.Hash = value
+		case "dir":
+			p.Dir = value
+		case "args":
+			args, err := unquoteArgs(value)
+			if err != nil {
+				return nil, fmt.Errorf("bad provenance: %v", err)
+			}
+			p.Args = args
+		case "version":
+			p.Version = value
+		}
+	}
+	return p, sc.Err()
+}
+
+// unquoteArgs splits command line arguments joined by quoteArgs.
+func unquoteArgs(s string) ([]string, error) {
+	var args []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		if s[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, err
+			}
+			arg, err := strconv.Unquote(quoted)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			s = s[len(quoted):]
+			continue
+		}
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		args = append(args, s[:end])
+		s = s[end:]
+	}
+	return args, nil
+}
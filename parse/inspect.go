@@ -0,0 +1,196 @@
+package parse
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// TemplateInfo describes a template.
+type TemplateInfo struct {
+	Filename string `json:"filename"`
+	Package  string `json:"package"`
+	// GenericTypes are the generic types the template declares, which each
+	// typeset has to give a specific type for.
+	GenericTypes []GenericTypeInfo `json:"genericTypes"`
+	// Constants are the generic constants the template declares, which
+	// typesets can give values for.
+	Constants []ConstantInfo `json:"constants"`
+	// Directives are the genny and go directives in the template.
+	Directives []DirectiveInfo `json:"directives"`
+}
+
+// GenericTypeInfo describes a generic type of a template.
+type GenericTypeInfo struct {
+	Name string `json:"name"`
+	// Kind is the generic placeholder the type is declared as, "Type",
+	// "Number" or "Name".
+	Kind string `json:"kind"`
+	// Interface is whether the type is an interface that embeds the
+	// placeholder, so that the specific types have to implement it.
+	Interface bool `json:"interface"`
+	// Embeds are the other interfaces the interface embeds.
+	Embeds []string `json:"embeds,omitempty"`
+	// Methods are the methods that the specific types have to have,
+	// including those of the embedded interfaces as far as they could be
+	// found.
+	Methods []string `json:"methods,omitempty"`
+	Doc     string   `json:"doc,omitempty"`
+}
+
+// ConstantInfo describes a generic constant of a template.
+type ConstantInfo struct {
+	Name string `json:"name"`
+	// Kind is the generic placeholder the constant is declared as, e.g.
+	// "Int".
+	Kind string `json:"kind"`
+	// Type is the type the constant gets, e.g. "int".
+	Type    string `json:"type"`
+	Default string `json:"default"`
+	// Named is whether the value takes part in naming.
+	Named bool `json:"named"`
+}
+
+// DirectiveInfo is a directive in a template.
+type DirectiveInfo struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// directivePrefixes are the comments that are reported as directives.
+var directivePrefixes = []string{"//genny:", "//go:generate ", "//go:build ", "// +build ", "// +gogen"}
+
+// Inspect describes the generic types and the directives of a template.
+func Inspect(filename string, in io.Reader) (*TemplateInfo, error) {
+	source, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, source, parser.ParseComments)
+	if err != nil {
+		return nil, &errSource{Err: err}
+	}
+
+	info := &TemplateInfo{
+		Filename:     filename,
+		Package:      file.Name.Name,
+		GenericTypes: []GenericTypeInfo{},
+		Constants:    []ConstantInfo{},
+		Directives:   []DirectiveInfo{},
+	}
+
+	var checked *types.Package
+	for _, gt := range scanGenericTypes(file) {
+		ti := GenericTypeInfo{Name: gt.name, Kind: gt.kind, Interface: gt.iface != nil}
+		if gt.spec.Doc != nil {
+			ti.Doc = strings.TrimSpace(gt.spec.Doc.Text())
+		} else if decl := declOf(file, gt.spec); decl != nil && decl.Doc != nil && len(decl.Specs) == 1 {
+			ti.Doc = strings.TrimSpace(decl.Doc.Text())
+		}
+		if gt.iface != nil {
+			if checked == nil {
+				checked = typeCheck(fset, file)
+			}
+			ti.Embeds, ti.Methods = interfaceRequirements(fset, checked, gt)
+		}
+		info.GenericTypes = append(info.GenericTypes, ti)
+	}
+
+	for _, c := range scanGenericConsts(fset, file, source) {
+		info.Constants = append(info.Constants, ConstantInfo{Name: c.name, Kind: c.kind, Type: constantKinds[c.kind], Default: c.value, Named: c.named})
+	}
+
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			for _, prefix := range directivePrefixes {
+				if strings.HasPrefix(comment.Text, prefix) {
+					info.Directives = append(info.Directives, DirectiveInfo{
+						Line: fset.Position(comment.Pos()).Line,
+						Text: comment.Text,
+					})
+					break
+				}
+			}
+		}
+	}
+	return info, nil
+}
+
+// declOf gets the declaration a type spec is in.
+func declOf(file *ast.File, spec *ast.TypeSpec) *ast.GenDecl {
+	for _, decl := range file.Decls {
+		if gd, ok := decl.(*ast.GenDecl); ok {
+			for _, s := range gd.Specs {
+				if s == spec {
+					return gd
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// typeCheck type checks a template as far as possible, so that the methods of
+// the interfaces it embeds can be found. Errors are ignored since the other
+// files of the template's package are not checked.
+func typeCheck(fset *token.FileSet, file *ast.File) *types.Package {
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+	pkg, _ := conf.Check(file.Name.Name, fset, []*ast.File{file}, nil)
+	return pkg
+}
+
+// interfaceRequirements gets the interfaces embedded in the interface of a
+// generic type, other than the generic placeholder, and its methods.
+func interfaceRequirements(fset *token.FileSet, pkg *types.Package, gt genericTypeSpec) (embeds, methods []string) {
+	for _, field := range gt.iface.Methods.List {
+		if len(field.Names) > 0 {
+			for _, name := range field.Names {
+				methods = append(methods, name.Name+strings.TrimPrefix(nodeString(fset, field.Type), "func"))
+			}
+			continue
+		}
+		if sel, ok := field.Type.(*ast.SelectorExpr); ok {
+			if _, ok := genericPlaceholder(sel); ok {
+				continue
+			}
+		}
+		embeds = append(embeds, nodeString(fset, field.Type))
+	}
+
+	if pkg == nil {
+		return embeds, methods
+	}
+	obj := pkg.Scope().Lookup(gt.name)
+	if obj == nil {
+		return embeds, methods
+	}
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok || iface.NumMethods() < len(methods) {
+		return embeds, methods
+	}
+	qualifier := types.RelativeTo(pkg)
+	methods = nil
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		methods = append(methods, m.Name()+strings.TrimPrefix(types.TypeString(m.Type(), qualifier), "func"))
+	}
+	return embeds, methods
+}
+
+// nodeString prints a node as Go source.
+func nodeString(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, fset, node)
+	return buf.String()
+}
@@ -0,0 +1,79 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tehbilly/genny/parse"
+)
+
+const inspectTemplate = `// +build ignore
+
+package sorted
+
+import (
+	"fmt"
+
+	"github.com/tehbilly/genny/generic"
+)
+
+//go:generate genny -in=$GOFILE -out=gen-$GOFILE gen "Key=int Elem=Name"
+
+// Key is what elements are sorted by.
+type Key generic.Number
+
+type (
+	// Elem is an element of a sorted list.
+	Elem interface {
+		generic.Type
+		fmt.Stringer
+		Key() Key
+	}
+)
+
+//genny:start
+
+type ElemList []Elem
+`
+
+func TestInspect(t *testing.T) {
+	info, err := parse.Inspect("sorted.go", strings.NewReader(inspectTemplate))
+	require.NoError(t, err)
+
+	assert.Equal(t, "sorted", info.Package)
+	assert.Equal(t, []parse.GenericTypeInfo{
+		{Name: "Key", Kind: "Number", Doc: "Key is what elements are sorted by."},
+		{
+			Name:      "Elem",
+			Kind:      "Type",
+			Interface: true,
+			Embeds:    []string{"fmt.Stringer"},
+			Methods:   []string{"Key() Key", "String() string"},
+			Doc:       "Elem is an element of a sorted list.",
+		},
+	}, info.GenericTypes)
+	assert.Equal(t, []parse.DirectiveInfo{
+		{Line: 1, Text: "// +build ignore"},
+		{Line: 11, Text: `//go:generate genny -in=$GOFILE -out=gen-$GOFILE gen "Key=int Elem=Name"`},
+		{Line: 25, Text: "//genny:start"},
+	}, info.Directives)
+}
+
+func TestInspectConstants(t *testing.T) {
+	in, err := contents("test/constants/ring_generic.go")
+	require.NoError(t, err)
+	info, err := parse.Inspect("ring_generic.go", strings.NewReader(in))
+	require.NoError(t, err)
+
+	assert.Equal(t, []parse.ConstantInfo{
+		{Name: "Label", Kind: "String", Type: "string", Default: `"ring"`},
+		{Name: "Degree", Kind: "Int", Type: "int", Default: "32", Named: true},
+	}, info.Constants)
+}
+
+func TestInspectBadSource(t *testing.T) {
+	_, err := parse.Inspect("bad.go", strings.NewReader("package bad\n\nfunc {"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,79 @@
+package parse
+
+import "strings"
+
+// LiteralPolicy is how the specific types are substituted into string
+// literals. Rune literals are never rewritten, and both implementations
+// follow the same policy.
+type LiteralPolicy int
+
+const (
+	// LiteralsNever leaves string literals as they are, so that JSON keys,
+	// SQL and messages in the template are never changed.
+	LiteralsNever LiteralPolicy = iota
+	// LiteralsMarked rewrites the literals marked with `//genny:literal`,
+	// either at the end of their line or on the line before, the way
+	// comments are rewritten.
+	LiteralsMarked
+	// LiteralsDescribe replaces the name of a generic type, as a whole word
+	// in the same case, with the specific type as %T would print it, so
+	// that "Item queue is empty" becomes "int queue is empty".
+	LiteralsDescribe
+)
+
+// literalDirective marks the string literals to rewrite with LiteralsMarked.
+const literalDirective = "//genny:literal"
+
+// ParseLiteralPolicy gets the LiteralPolicy for "never", "marked" or
+// "describe".
+func ParseLiteralPolicy(s string) (LiteralPolicy, error) {
+	switch s {
+	case "never", "":
+		return LiteralsNever, nil
+	case "marked":
+		return LiteralsMarked, nil
+	case "describe":
+		return LiteralsDescribe, nil
+	}
+	return LiteralsNever, &errBadTypeArgs{Arg: s, Message: "literals has to be never, marked or describe"}
+}
+
+// subIntoString substitutes a specific type into a string literal,
+// including its quotes, according to the policy. marked is whether the
+// literal is marked with the literal directive.
+func subIntoString(lit string, spec replaceSpec, marked bool) string {
+	switch spec.literals {
+	case LiteralsMarked:
+		if marked {
+			return subIntoProse(lit, spec)
+		}
+	case LiteralsDescribe:
+		if strings.Contains(lit, spec.genericType) {
+			return spec.matcher.exact.ReplaceAllLiteralString(lit, spec.specificType.Type)
+		}
+	}
+	return lit
+}
+
+// scanLiteralLines finds the lines whose string literals are marked with the
+// literal directive: that of the directive and, if it is on a line of its
+// own, the next one.
+func (t *template) scanLiteralLines() map[int]bool {
+	var lines map[int]bool
+	for _, group := range t.file.Comments {
+		for _, comment := range group.List {
+			if strings.TrimSpace(comment.Text) != literalDirective {
+				continue
+			}
+			if lines == nil {
+				lines = make(map[int]bool)
+			}
+			pos := t.fset.Position(comment.Pos())
+			lines[pos.Line] = true
+			if strings.TrimSpace(t.lines[pos.Line-1][:pos.Column-1]) == "" {
+				lines[pos.Line+1] = true
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,79 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tehbilly/genny/parse"
+)
+
+const literalsTemplate = `package literals
+
+import (
+	"errors"
+
+	"github.com/tehbilly/genny/generic"
+)
+
+type Item generic.Type
+
+// ItemQueue is a queue.
+type ItemQueue struct {
+	items []Item ` + "`json:\"items\"`" + `
+}
+
+var errItemEmpty = errors.New("Item queue is empty")
+
+const itemQuery = "SELECT item FROM items" //genny:literal
+
+//genny:literal
+var itemName, itemSep = "ItemQueue of items", 'I'
+`
+
+func TestGenerateLiterals(t *testing.T) {
+	typeSets, err := parse.TypeSet("Item=*time.Time")
+	require.NoError(t, err)
+	policies := []struct {
+		literals parse.LiteralPolicy
+		contains []string
+	}{
+		{parse.LiteralsNever, []string{
+			"`json:\"items\"`", `errors.New("Item queue is empty")`,
+			`"SELECT item FROM items"`, `"ItemQueue of items", 'I'`,
+		}},
+		{parse.LiteralsMarked, []string{
+			"`json:\"items\"`", `errors.New("Item queue is empty")`,
+			`"SELECT timeTime FROM timeTimes"` + "\n", `"TimeTimeQueue of timeTimes", 'I'`,
+		}},
+		{parse.LiteralsDescribe, []string{
+			"`json:\"items\"`", `errors.New("*time.Time queue is empty")`,
+			`"SELECT item FROM items"`, `"ItemQueue of items", 'I'`,
+		}},
+	}
+	for _, policy := range policies {
+		var outputs []string
+		for _, useAst := range []bool{true, false} {
+			out, err := parse.Generate("literals.go", strings.NewReader(literalsTemplate), typeSets, parse.Options{UseAst: useAst, Literals: policy.literals})
+			require.NoError(t, err)
+			for _, s := range policy.contains {
+				assert.Contains(t, string(out), s, "literals: %v, ast: %v", policy.literals, useAst)
+			}
+			assert.NotContains(t, string(out), "genny:literal")
+			outputs = append(outputs, string(out))
+		}
+		assert.Equal(t, outputs[0], outputs[1], "literals: %v", policy.literals)
+	}
+}
+
+func TestParseLiteralPolicy(t *testing.T) {
+	for s, want := range map[string]parse.LiteralPolicy{"never": parse.LiteralsNever, "": parse.LiteralsNever, "marked": parse.LiteralsMarked, "describe": parse.LiteralsDescribe} {
+		got, err := parse.ParseLiteralPolicy(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, want, got, s)
+		}
+	}
+	_, err := parse.ParseLiteralPolicy("always")
+	assert.Error(t, err)
+}
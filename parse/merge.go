@@ -0,0 +1,186 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// merger merges the instantiations of a template into the lines of a
+// single file. It keeps the package clause, and anything before
+// `//genny:start`, of the first instantiation only, collects the imports of
+// all of them, drops genny directives and rewrites the build constraint.
+type merger struct {
+	unwantedLinePrefixes [][]byte
+
+	// constraints rewrites the build constraint of the first instantiation,
+	// if not nil. The constraint lines are collected in constraintLines, and
+	// the rewritten ones go at constraintIndex.
+	constraints     *constraintRewrite
+	constraintLines []string
+	constraintIndex int
+
+	// files is the number of instantiations merged so far, and lines the
+	// number of lines they produced.
+	files int
+	lines int
+
+	// license is whether the leading comment of the first instantiation,
+	// which the header has instead, has yet to be skipped.
+	license bool
+
+	packageFound bool
+	// Whether to wait for the "genny:start" comment to start copying. This will be set to true
+	// after we have went through the first generated type, so subsequent generated types will
+	// not copy anything before that line
+	fileHasGennyStart bool
+	// importLineIndex is the line where the imports go, or -1 if no
+	// instantiation had any.
+	importLineIndex int
+	imports         stringArraySet
+}
+
+func newMerger(constraints *constraintRewrite, license bool) *merger {
+	m := &merger{constraints: constraints, constraintIndex: -1, importLineIndex: -1, license: license}
+	m.unwantedLinePrefixes = append(m.unwantedLinePrefixes, unwantedLinePrefixes...)
+	return m
+}
+
+// merge gets the clean lines of the next instantiation.
+func (m *merger) merge(transformedOutput []byte) ([]string, error) {
+	var cleanOutputLines []string
+	insideImportBlock := false
+	packageFoundForFile := false
+	if m.license {
+		// skip the leading comment and the blank lines around it
+		transformedOutput = bytes.TrimLeft(transformedOutput[leadingCommentEnd(transformedOutput):], " \t\r\n")
+		m.license = false
+	}
+	bs := bufio.NewScanner(bytes.NewReader(transformedOutput))
+	pastGennyStart := false
+
+FORSCAN:
+	for bs.Scan() {
+
+		if m.constraints != nil && m.files == 0 && !packageFoundForFile && isConstraintLine(bs.Text()) {
+			if m.constraintIndex == -1 {
+				m.constraintIndex = len(cleanOutputLines)
+			}
+			m.constraintLines = append(m.constraintLines, bs.Text())
+			continue
+		}
+
+		if bytes.HasPrefix(bs.Bytes(), []byte(gennyStart)) {
+			pastGennyStart = true
+			m.fileHasGennyStart = true
+			continue
+		}
+
+		// end of imports block?
+		if insideImportBlock {
+			if bytes.HasSuffix(bs.Bytes(), closeBrace) {
+				insideImportBlock = false
+			} else {
+				m.imports = m.imports.append(makeLine(bs.Text()))
+			}
+			continue
+		}
+
+		if bytes.HasPrefix(bs.Bytes(), packageKeyword) {
+			packageFoundForFile = true
+			if !m.packageFound {
+				m.packageFound = true
+				if m.constraints != nil {
+					var err error
+					if cleanOutputLines, err = m.insertConstraint(cleanOutputLines); err != nil {
+						return nil, err
+					}
+				}
+				cleanOutputLines = append(cleanOutputLines, makeLine(bs.Text()))
+			}
+			continue
+		} else if bytes.HasPrefix(bs.Bytes(), importKeyword) {
+			if m.importLineIndex == -1 {
+				m.importLineIndex = m.lines + len(cleanOutputLines)
+			}
+			if bytes.HasSuffix(bs.Bytes(), openBrace) {
+				insideImportBlock = true
+			} else {
+				importLine := strings.TrimSpace(makeLine(bs.Text()))
+				importLine = strings.TrimSpace(importLine[6:])
+				m.imports = m.imports.append(makeLine(importLine))
+			}
+
+			continue
+		}
+
+		if m.files != 0 && !packageFoundForFile {
+			continue
+		}
+
+		if m.fileHasGennyStart && !pastGennyStart {
+			continue
+		}
+
+		// check all unwantedLinePrefixes - and skip them
+		for _, prefix := range m.unwantedLinePrefixes {
+			if bytes.HasPrefix(bs.Bytes(), prefix) {
+				continue FORSCAN
+			}
+		}
+
+		cleanOutputLines = append(cleanOutputLines, makeLine(bs.Text()))
+	}
+
+	m.files++
+	m.lines += len(cleanOutputLines)
+	return cleanOutputLines, nil
+}
+
+// insertConstraint puts the rewritten build constraint where that of the
+// template was, or before the package clause if it had none.
+func (m *merger) insertConstraint(lines []string) ([]string, error) {
+	rewritten, err := m.constraints.rewrite(m.constraintLines)
+	if err != nil {
+		return nil, err
+	}
+	if len(rewritten) == 0 {
+		return lines, nil
+	}
+	index := m.constraintIndex
+	if index == -1 {
+		index = len(lines)
+		rewritten = append(rewritten, fmt.Sprintln())
+	}
+	inserted := append([]string{}, lines[:index]...)
+	inserted = append(inserted, rewritten...)
+	return append(inserted, lines[index:]...), nil
+}
+
+// importBlock gets the lines of an import declaration with the imports
+// collected so far.
+func (m *merger) importBlock() []string {
+	var lines []string
+	lines = append(lines, fmt.Sprintln("import ("))
+	lines = append(lines, m.imports...)
+	lines = append(lines, fmt.Sprintln(")"))
+	return lines
+}
+
+// leadingCommentEnd gets the offset of the end of the leading comment of a
+// file, or 0 if it has none.
+func leadingCommentEnd(source []byte) int {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", source, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil && file == nil {
+		return 0
+	}
+	license := leadingComment(file)
+	if license == nil {
+		return 0
+	}
+	return fset.Position(license.End()).Offset
+}
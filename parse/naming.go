@@ -0,0 +1,282 @@
+package parse
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Naming is a strategy for the words that replace the names of generic types
+// in identifiers and comments, e.g. "Int" in IntQueue.
+type Naming interface {
+	// Word gets the word for a specific type, with its first letter in
+	// upper case. It is put in lower case where the name of the generic
+	// type was.
+	Word(specific TypeRef) (string, error)
+}
+
+// PluralNaming is a Naming that also gets the plurals of its words, for
+// those that the English rules would get wrong. Other strategies have their
+// words put in the plural by the rules.
+type PluralNaming interface {
+	Naming
+	// Plural gets the plural of the word for a specific type, with its
+	// first letter in upper case.
+	Plural(specific TypeRef) (string, error)
+}
+
+// NamingOptions is the Naming genny provides. The zero value names the
+// specific types the way genny always has: the alias, or the type with `*`,
+// `&`, braces and dots dropped, so that pkg.Foo and *pkg.Foo become PkgFoo.
+// An alias given in the typeset, as in Name:Type, is used as it is whatever
+// the options.
+type NamingOptions struct {
+	// Unqualified drops the package of qualified types, so that pkg.Foo
+	// becomes Foo.
+	Unqualified bool
+	// KindSuffixes names pointers, slices, arrays, maps and channels after
+	// their kind, so that *Foo becomes FooPtr, []Foo FooSlice, [4]Foo
+	// FooArray4, map[string]Foo StringFooMap and chan Foo FooChan. Without
+	// it the pointers are dropped, and composite types get Foos, Foos4 and
+	// StringToFoo.
+	KindSuffixes bool
+	// AliasOnly only names types after their aliases. A specific type that
+	// is not a plain identifier has to be given one.
+	AliasOnly bool
+}
+
+// Word gets the word for a specific type.
+func (o NamingOptions) Word(specific TypeRef) (string, error) {
+	if specific.Alias != specific.Type || o == (NamingOptions{}) {
+		return wordify(specific.Alias, true), nil
+	}
+	if o.AliasOnly {
+		if !token.IsIdentifier(specific.Type) {
+			return "", &errBadTypeArgs{Arg: specific.Type, Message: "a type that is not an identifier has to be given an alias, as in Name:" + specific.Type}
+		}
+		return wordify(specific.Type, true), nil
+	}
+	expr, err := parser.ParseExpr(specific.Type)
+	if err != nil {
+		return wordify(specific.Alias, true), nil
+	}
+	if word := o.exprWord(expr); word != "" {
+		return word, nil
+	}
+	return wordify(specific.Alias, true), nil
+}
+
+// Plural gets the plural of the word for a specific type. Without
+// KindSuffixes slices and arrays are named in the plural already, so []int
+// is Ints whether one or more of them are meant.
+func (o NamingOptions) Plural(specific TypeRef) (string, error) {
+	if specific.Plural != "" {
+		return wordify(specific.Plural, true), nil
+	}
+	word, err := o.Word(specific)
+	if err != nil {
+		return "", err
+	}
+	if specific.Alias == specific.Type && !o.AliasOnly && !o.KindSuffixes && o != (NamingOptions{}) {
+		if expr, err := parser.ParseExpr(specific.Type); err == nil && isPluralExpr(expr) {
+			return word, nil
+		}
+	}
+	return pluralize(word), nil
+}
+
+// isPluralExpr reports whether the word for a type expression is in the
+// plural without KindSuffixes, as that of a slice, an array or a pointer to
+// one is.
+func isPluralExpr(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.ArrayType:
+		return true
+	case *ast.ParenExpr:
+		return isPluralExpr(e.X)
+	case *ast.StarExpr:
+		return isPluralExpr(e.X)
+	}
+	return false
+}
+
+// exprWord gets the word for a type expression, or "" if it has none.
+func (o NamingOptions) exprWord(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return upperFirst(e.Name)
+	case *ast.SelectorExpr:
+		if o.Unqualified {
+			return upperFirst(e.Sel.Name)
+		}
+		return o.exprWord(e.X) + upperFirst(e.Sel.Name)
+	case *ast.ParenExpr:
+		return o.exprWord(e.X)
+	case *ast.StarExpr:
+		return o.composite(o.exprWord(e.X), "Ptr", "")
+	case *ast.ArrayType:
+		elem := o.exprWord(e.Elt)
+		if e.Len == nil {
+			return o.composite(elem, "Slice", "s")
+		}
+		lit, ok := e.Len.(*ast.BasicLit)
+		if !ok || lit.Kind != token.INT {
+			return ""
+		}
+		return o.composite(elem, "Array"+lit.Value, "s"+lit.Value)
+	case *ast.MapType:
+		key, value := o.exprWord(e.Key), o.exprWord(e.Value)
+		if key == "" || value == "" {
+			return ""
+		}
+		if o.KindSuffixes {
+			return key + value + "Map"
+		}
+		return key + "To" + value
+	case *ast.ChanType:
+		return o.composite(o.exprWord(e.Value), "Chan", "Chan")
+	case *ast.InterfaceType:
+		return "Interface"
+	case *ast.StructType:
+		return "Struct"
+	}
+	return ""
+}
+
+// composite gets the word for a composite type from that of its element,
+// with the suffix for its kind or, without KindSuffixes, the plain suffix.
+func (o NamingOptions) composite(elem, kindSuffix, plainSuffix string) string {
+	if elem == "" {
+		return ""
+	}
+	if o.KindSuffixes {
+		return elem + kindSuffix
+	}
+	return elem + plainSuffix
+}
+
+// ParseNaming gets the NamingOptions for a comma separated list of presets:
+// "qualified", the default, "unqualified", "suffixes" for KindSuffixes and
+// "aliasonly".
+func ParseNaming(s string) (NamingOptions, error) {
+	var o NamingOptions
+	for _, preset := range strings.Split(s, ",") {
+		switch strings.TrimSpace(preset) {
+		case "qualified", "":
+			o.Unqualified = false
+		case "unqualified":
+			o.Unqualified = true
+		case "suffixes":
+			o.KindSuffixes = true
+		case "aliasonly":
+			o.AliasOnly = true
+		default:
+			return o, &errBadTypeArgs{Arg: preset, Message: "naming has to be qualified, unqualified, suffixes or aliasonly"}
+		}
+	}
+	return o, nil
+}
+
+// Placement is where the word for a specific type goes in the identifiers
+// that contain the name of a generic type, other than the name itself.
+type Placement int
+
+const (
+	// InPlace puts the word where the name was, so that NewItemQueue
+	// becomes NewIntQueue.
+	InPlace Placement = iota
+	// PlacePrefix puts the word in front, so that NewItemQueue becomes
+	// IntNewQueue.
+	PlacePrefix
+	// PlaceSuffix puts the word at the end, so that NewItemQueue becomes
+	// NewQueueInt.
+	PlaceSuffix
+)
+
+// ParsePlacement gets the Placement for "inplace", "prefix" or "suffix".
+func ParsePlacement(s string) (Placement, error) {
+	switch s {
+	case "inplace", "":
+		return InPlace, nil
+	case "prefix":
+		return PlacePrefix, nil
+	case "suffix":
+		return PlaceSuffix, nil
+	}
+	return InPlace, &errBadTypeArgs{Arg: s, Message: "placement has to be inplace, prefix or suffix"}
+}
+
+// reWord matches the words of a text that could be identifiers.
+var reWord = regexp.MustCompile(`[\pL\pN_]+`)
+
+// placeWords substitutes a specific type into the words of a text for a
+// placement other than InPlace. Words that are the name of the generic type
+// are replaced with the alias, and plurals are put in place.
+func placeWords(text string, spec replaceSpec) string {
+	name := spec.genericType
+	return reWord.ReplaceAllStringFunc(text, func(w string) string {
+		if w == name {
+			return spec.specificType.Alias
+		}
+		if plural := spec.replacePlurals(w); plural != w {
+			return plural
+		}
+		if !containsBoundary(w, name) {
+			return w
+		}
+		return placeWord(w, name, spec.word, spec.placement)
+	})
+}
+
+// placeWord substitutes the word into an identifier that contains the name
+// of the generic type, moving it to the front or the end. The identifier
+// keeps whether it is exported. If the name is followed by more of a word,
+// as in the plural Items, the word is put in place.
+func placeWord(ident, name, word string, placement Placement) string {
+	if next, _ := utf8.DecodeRuneInString(ident[indexBoundary(ident, name)+len(name):]); unicode.IsLower(next) {
+		return replaceBoundaryFunc(ident, name, func(match string) string {
+			return wordify(word, startsUpper(match))
+		})
+	}
+	exported := isExported(ident)
+	rest := replaceBoundary(ident, name, "")
+	if rest == "" {
+		return wordify(word, exported)
+	}
+	if placement == PlacePrefix {
+		return wordify(word, exported) + upperFirst(rest)
+	}
+	return wordify(rest, exported) + word
+}
+
+// words gets the words for the specific types of the typeSet.
+func (t *template) words(typeSet map[string]TypeRef) (map[string]string, error) {
+	naming := t.naming
+	if naming == nil {
+		naming = NamingOptions{}
+	}
+	words := make(map[string]string, len(typeSet))
+	for name, specific := range t.typesOnly(typeSet) {
+		word, err := naming.Word(specific)
+		if err != nil {
+			return nil, err
+		}
+		words[name] = word
+	}
+	return words, nil
+}
+
+// plural gets the plural of word, the word for a specific type, from the
+// naming if it is a PluralNaming, or by the English rules.
+func (t *template) plural(specific TypeRef, word string) string {
+	if naming, ok := t.naming.(PluralNaming); ok {
+		if plural, err := naming.Plural(specific); err == nil {
+			return plural
+		}
+	}
+	return pluralize(word)
+}
@@ -0,0 +1,188 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tehbilly/genny/parse"
+)
+
+func TestNamingOptionsWord(t *testing.T) {
+	words := []struct {
+		naming   parse.NamingOptions
+		specific string
+		word     string
+	}{
+		{parse.NamingOptions{}, "int", "Int"},
+		{parse.NamingOptions{}, "pkg.Foo", "PkgFoo"},
+		{parse.NamingOptions{}, "*pkg.Foo", "PkgFoo"},
+		{parse.NamingOptions{}, "interface{}", "Interface"},
+		{parse.NamingOptions{}, "Thing:*pkg.Foo", "Thing"},
+		{parse.NamingOptions{Unqualified: true}, "pkg.Foo", "Foo"},
+		{parse.NamingOptions{Unqualified: true}, "*pkg.Foo", "Foo"},
+		{parse.NamingOptions{Unqualified: true}, "Thing:*pkg.Foo", "Thing"},
+		{parse.NamingOptions{KindSuffixes: true}, "*pkg.Foo", "PkgFooPtr"},
+		{parse.NamingOptions{KindSuffixes: true}, "[]byte", "ByteSlice"},
+		{parse.NamingOptions{KindSuffixes: true}, "[4]int", "IntArray4"},
+		{parse.NamingOptions{KindSuffixes: true}, "map[string]int", "StringIntMap"},
+		{parse.NamingOptions{KindSuffixes: true}, "chan int", "IntChan"},
+		{parse.NamingOptions{Unqualified: true}, "[]byte", "Bytes"},
+		{parse.NamingOptions{Unqualified: true}, "map[string]time.Time", "StringToTime"},
+		{parse.NamingOptions{AliasOnly: true}, "int", "Int"},
+		{parse.NamingOptions{AliasOnly: true}, "Stamp:*time.Time", "Stamp"},
+	}
+	for _, w := range words {
+		ref, err := parse.ParseTypeRef(w.specific)
+		require.NoError(t, err)
+		word, err := w.naming.Word(*ref)
+		require.NoError(t, err, w.specific)
+		assert.Equal(t, w.word, word, "%+v %s", w.naming, w.specific)
+	}
+
+	_, err := parse.NamingOptions{AliasOnly: true}.Word(parse.TypeRef{Alias: "*time.Time", Type: "*time.Time"})
+	assert.Error(t, err)
+}
+
+func TestParseNaming(t *testing.T) {
+	naming, err := parse.ParseNaming("unqualified,suffixes")
+	require.NoError(t, err)
+	assert.Equal(t, parse.NamingOptions{Unqualified: true, KindSuffixes: true}, naming)
+	naming, err = parse.ParseNaming("qualified")
+	require.NoError(t, err)
+	assert.Equal(t, parse.NamingOptions{}, naming)
+	_, err = parse.ParseNaming("short")
+	assert.Error(t, err)
+
+	placement, err := parse.ParsePlacement("suffix")
+	require.NoError(t, err)
+	assert.Equal(t, parse.PlaceSuffix, placement)
+	_, err = parse.ParsePlacement("middle")
+	assert.Error(t, err)
+}
+
+func TestGenerateNaming(t *testing.T) {
+	in, err := contents("test/queue/generic_queue.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("Something=*time.Time")
+	require.NoError(t, err)
+
+	naming := []struct {
+		opts     parse.Options
+		contains []string
+	}{
+		{parse.Options{}, []string{"type TimeTimeQueue struct", "func NewTimeTimeQueue()", "a queue of TimeTimes."}},
+		{parse.Options{Naming: parse.NamingOptions{Unqualified: true, KindSuffixes: true}}, []string{"type TimePtrQueue struct", "func NewTimePtrQueue()", "a queue of TimePtrs."}},
+		{parse.Options{Naming: parse.NamingOptions{Unqualified: true}, Placement: parse.PlaceSuffix}, []string{"type QueueTime struct", "func NewQueueTime()", "// QueueTime is a queue of Times."}},
+		{parse.Options{Naming: parse.NamingOptions{Unqualified: true}, Placement: parse.PlacePrefix}, []string{"type TimeQueue struct", "func TimeNewQueue()"}},
+	}
+	for _, n := range naming {
+		var outputs []string
+		for _, useAst := range []bool{true, false} {
+			n.opts.UseAst = useAst
+			out, err := parse.Generate("generic_queue.go", strings.NewReader(in), typeSets, n.opts)
+			require.NoError(t, err)
+			for _, s := range n.contains {
+				assert.Contains(t, string(out), s, "ast: %v", useAst)
+			}
+			outputs = append(outputs, string(out))
+		}
+		// both implementations name things the same way
+		assert.Equal(t, outputs[0], outputs[1])
+	}
+}
+
+const casingTemplate = `package casing
+
+import "github.com/tehbilly/genny/generic"
+
+type Key generic.Type
+
+// keyIndex finds the Key in keys.
+func keyIndex(keys []Key, k Key) int {
+	for i, key := range keys {
+		if key == k {
+			return i
+		}
+	}
+	return -1
+}
+
+// KeyIndex finds the Key in keys.
+func KeyIndex(keys []Key, k Key) int {
+	return keyIndex(keys, k)
+}
+`
+
+func TestGenerateInitialisms(t *testing.T) {
+	typeSets, err := parse.TypeSet("Key=ID:string,URL:string,HTTPServer:string,Ölçü:string")
+	require.NoError(t, err)
+	for _, useAst := range []bool{true, false} {
+		out, err := parse.Generate("casing.go", strings.NewReader(casingTemplate), typeSets, parse.Options{UseAst: useAst})
+		require.NoError(t, err)
+		for _, s := range []string{
+			"func idIndex(", "func IDIndex(",
+			"func urlIndex(", "func URLIndex(",
+			"func httpServerIndex(", "func HTTPServerIndex(",
+			"func ölçüIndex(", "func ÖlçüIndex(",
+		} {
+			assert.Contains(t, string(out), s, "ast: %v", useAst)
+		}
+	}
+}
+
+const pluralTemplate = `package plurals
+
+import "github.com/tehbilly/genny/generic"
+
+type Entry generic.Type
+
+// EntrySet holds Entries; entries are kept in order.
+type EntrySet struct {
+	entries []Entry
+}
+
+// Entries gets all the Entries in the set.
+func (s *EntrySet) Entries() []Entry {
+	return s.entries
+}
+`
+
+func TestGeneratePlurals(t *testing.T) {
+	typeSets, err := parse.TypeSet("Entry=Box:string,Person@People:string,Key:string,ID:string")
+	require.NoError(t, err)
+	for _, useAst := range []bool{true, false} {
+		out, err := parse.Generate("plurals.go", strings.NewReader(pluralTemplate), typeSets, parse.Options{UseAst: useAst})
+		require.NoError(t, err)
+		for _, s := range []string{
+			"// BoxSet holds Boxes; boxes are kept in order.", "boxes []string", "func (s *BoxSet) Boxes() []string",
+			"// PersonSet holds People; people are kept in order.", "func (s *PersonSet) People() []string",
+			"func (s *KeySet) Keys() []string",
+			"func (s *IDSet) IDs() []string", "ids []string",
+		} {
+			assert.Contains(t, string(out), s, "ast: %v", useAst)
+		}
+	}
+}
+
+func TestGeneratePluralNaming(t *testing.T) {
+	typeSets, err := parse.TypeSet("Entry=[]int,*[]string,int")
+	require.NoError(t, err)
+	for _, useAst := range []bool{true, false} {
+		out, err := parse.Generate("plurals.go", strings.NewReader(pluralTemplate), typeSets, parse.Options{UseAst: useAst, Naming: parse.NamingOptions{Unqualified: true}})
+		require.NoError(t, err)
+		for _, s := range []string{
+			"// IntsSet holds Ints; ints are kept in order.", "func (s *IntsSet) Ints() [][]int",
+			"// StringsSet holds Strings; strings are kept in order.",
+			"// IntSet holds Ints; ints are kept in order.",
+		} {
+			assert.Contains(t, string(out), s, "ast: %v", useAst)
+		}
+		assert.NotContains(t, string(out), "Intses", "ast: %v", useAst)
+	}
+
+	plural, err := parse.NamingOptions{KindSuffixes: true}.Plural(parse.TypeRef{Alias: "[]int", Type: "[]int"})
+	require.NoError(t, err)
+	assert.Equal(t, "IntSlices", plural)
+}
@@ -5,30 +5,17 @@ import (
 	"bytes"
 	"fmt"
 	"go/ast"
-	"go/build/constraint"
-	"go/parser"
 	"go/printer"
 	"go/scanner"
 	"go/token"
 	"io"
-	"reflect"
+	"io/ioutil"
 	"regexp"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/tools/go/ast/astutil"
-	"golang.org/x/tools/imports"
-)
-
-var header = `// Code generated by genny. DO NOT EDIT.
-// This file was automatically generated by genny.
-// Any changes will be lost if this file is regenerated.
-// see https://github.com/tehbilly/genny
-
-`
-
-const (
-	debug = false
 )
 
 var (
@@ -39,23 +26,53 @@ var (
 	genericPackage = "generic"
 	genericType    = "generic.Type"
 	genericNumber  = "generic.Number"
+	genericName    = "generic.Name"
 	linefeed       = "\r\n"
 )
 var unwantedLinePrefixes = [][]byte{
 	[]byte("//go:generate genny "),
 	[]byte("//go:generate $GOPATH/bin/genny "),
+	[]byte("//go:generate go run github.com/tehbilly/genny "),
+	// followed by a version, e.g. genny@latest
+	[]byte("//go:generate go run github.com/tehbilly/genny@"),
+}
+
+// GenerateDirective gets the genny arguments of a `//go:generate genny ...`
+// line. ok is false if the line is not a genny directive.
+func GenerateDirective(line string) (args string, ok bool) {
+	for _, prefix := range unwantedLinePrefixes {
+		if !strings.HasPrefix(line, string(prefix)) {
+			continue
+		}
+		args = line[len(prefix):]
+		if bytes.HasSuffix(prefix, []byte("@")) {
+			// skip the version
+			end := strings.IndexAny(args, " \t")
+			if end < 0 {
+				return "", true
+			}
+			args = args[end:]
+		}
+		return strings.TrimSpace(args), true
+	}
+	return "", false
 }
 
-func subIntoLiteral(lit, typeTemplate string, specificType TypeRef) string {
+func subIntoLiteral(lit string, spec replaceSpec) string {
+	m, specificType := spec.matcher, spec.specificType
+	typeTemplate := m.name
 	// print("l >> %s ... tt >> %s", lit, typeTemplate)
 	if lit == typeTemplate {
 		return specificType.Type
 	}
-	if !containsFold(lit, typeTemplate) {
+	if !m.in(lit) {
 		return lit
 	}
-	specificLg := wordify(specificType.Alias, true)
-	specificSm := wordify(specificType.Alias, false)
+	if spec.placement != InPlace {
+		return placeWords(lit, spec)
+	}
+	specificLg := spec.toWord(true)
+	specificSm := spec.toWord(false)
 	var replacer string
 	if isExported(typeTemplate) {
 		replacer = specificLg
@@ -63,8 +80,8 @@ func subIntoLiteral(lit, typeTemplate string, specificType TypeRef) string {
 		replacer = specificSm
 	}
 	// result := lit //replaceBoundary(lit, typeTemplate, specificType)
-	typeregex := regexp.MustCompile("\\b" + typeTemplate + "\\b")
-	result := typeregex.ReplaceAllString(lit, specificType.Alias)
+	result := spec.replacePlurals(lit)
+	result = m.exact.ReplaceAllString(result, specificType.Alias)
 	result = strings.Replace(result, typeTemplate, replacer, -1)
 	if strings.HasPrefix(result, specificLg) && !isExported(lit) {
 		result = strings.Replace(result, specificLg, specificSm, 1)
@@ -74,95 +91,55 @@ func subIntoLiteral(lit, typeTemplate string, specificType TypeRef) string {
 	return result
 }
 
-func subTypeIntoComment(line, typeTemplate string, specificType TypeRef) string {
-	var subbed string
-	for _, w := range strings.Fields(line) {
-		subbed = subbed + subIntoLiteral(w, typeTemplate, specificType) + " "
-	}
-	return subbed
-}
-
 // Does the heavy lifting of taking a line of our code and
 // sbustituting a type into there for our generic type
-func subTypeIntoLine(line, typeTemplate string, specificType TypeRef) string {
+func subTypeIntoLine(line string, spec replaceSpec, marked bool) string {
 	src := []byte(line)
 	var s scanner.Scanner
 	fset := token.NewFileSet()
 	file := fset.AddFile("", fset.Base(), len(src))
 	s.Init(file, src, nil, scanner.ScanComments)
-	output := ""
+	var output strings.Builder
 	for {
 		_, tok, lit := s.Scan()
 		// print("%s -> %s", lit, tok)
 		if tok == token.EOF {
 			break
+		} else if tok == token.SEMICOLON && lit == "\n" {
+			// inserted by the scanner at the end of the line
+			continue
 		} else if tok == token.COMMENT {
-			subbed := subTypeIntoComment(lit, typeTemplate, specificType)
-			output = output + subbed + " "
+			subbed := subIntoComment(lit, spec)
+			output.WriteString(subbed + " ")
+		} else if tok == token.STRING {
+			output.WriteString(subIntoString(lit, spec, marked) + " ")
+		} else if tok == token.CHAR {
+			output.WriteString(lit + " ")
 		} else if tok.IsLiteral() {
 			// print("LITERAL %s ---> %s", line, lit)
-			subbed := subIntoLiteral(lit, typeTemplate, specificType)
-			output = output + subbed + " "
+			subbed := subIntoLiteral(lit, spec)
+			output.WriteString(subbed + " ")
 		} else {
-			output = output + tok.String() + " "
+			output.WriteString(tok.String() + " ")
 		}
 	}
-	return output
+	return output.String()
 }
 
-// typeSet looks like "KeyType: int, ValueType: string"
-func generateSpecific(filename string, in io.ReadSeeker, typeSet map[string]TypeRef) ([]byte, error) {
-	// ensure we are at the beginning of the file
-	if _, err := in.Seek(0, io.SeekStart); err != nil {
-		return nil, err
-	}
-
-	// parse the source file
-	fs := token.NewFileSet()
-	file, err := parser.ParseFile(fs, filename, in, 0)
-	if err != nil {
-		return nil, &errSource{Err: err}
-	}
-
-	// make sure every generic.Type is represented in the types
-	// argument.
-	for _, decl := range file.Decls {
-		switch it := decl.(type) {
-		case *ast.GenDecl:
-			for _, spec := range it.Specs {
-				ts, ok := spec.(*ast.TypeSpec)
-				if !ok {
-					continue
-				}
-				switch tt := ts.Type.(type) {
-				case *ast.SelectorExpr:
-					if name, ok := tt.X.(*ast.Ident); ok {
-						if name.Name == genericPackage {
-							if _, ok := typeSet[ts.Name.Name]; !ok {
-								return nil, &errMissingSpecificType{GenericType: ts.Name.Name}
-							}
-						}
-					}
-				}
-			}
-		}
-	}
-
-	if _, err := in.Seek(0, io.SeekStart); err != nil {
-		return nil, err
-	}
+var (
+	reInterfaceBegin = regexp.MustCompile(`^\s*type\s+\w+\s+interface\s*\{`)
+	reInterfaceEnd   = regexp.MustCompile(`^\s*\}`)
+)
 
+// typeSet looks like "KeyType: int, ValueType: string"
+func generateSpecific(tmpl *template, typeSet map[string]TypeRef, words map[string]string, trace *substitutionTrace) ([]byte, error) {
 	var buf bytes.Buffer
 
 	comment := ""
-	bs := bufio.NewScanner(in)
-	reInterfaceBegin := regexp.MustCompile(`^\s*type\s+\w+\s+interface\s*\{`)
-	reInterfaceEnd := regexp.MustCompile(`^\s*\}`)
 	var interfaceLines []string
 	interfaceContainsType := false
-	for bs.Scan() {
-
-		line := bs.Text()
+	for i, line := range tmpl.lines {
+		pos := token.Position{Filename: tmpl.filename, Line: i + 1, Column: 1}
 
 		if reInterfaceBegin.MatchString(line) {
 			interfaceLines = []string{""}
@@ -179,7 +156,8 @@ func generateSpecific(filename string, in io.ReadSeeker, typeSet map[string]Type
 		}
 
 		// does this line contain generic.Type?
-		if strings.Contains(line, genericType) || strings.Contains(line, genericNumber) {
+		if strings.Contains(line, genericType) || strings.Contains(line, genericNumber) || strings.Contains(line, genericName) {
+			trace.recordPosition("GENERIC TYPE", pos, line, "")
 			comment = ""
 			if len(interfaceLines) > 0 {
 				interfaceContainsType = true
@@ -188,8 +166,9 @@ func generateSpecific(filename string, in io.ReadSeeker, typeSet map[string]Type
 		}
 
 		for t, specificType := range typeSet {
-			if containsFold(line, t) {
-				newLine := subTypeIntoLine(line, t, specificType)
+			if m := tmpl.matchers[t]; m.in(line) && !tmpl.verbatimLines[i+1] {
+				newLine := subTypeIntoLine(line, tmpl.replaceSpec(t, specificType, words, nil), tmpl.literalLines[i+1])
+				trace.recordPosition("LINE", pos, line, newLine)
 				line = newLine
 			}
 		}
@@ -225,159 +204,168 @@ func generateSpecific(filename string, in io.ReadSeeker, typeSet map[string]Type
 	return buf.Bytes(), nil
 }
 
+// Options holds the settings used by Generate.
+type Options struct {
+	// PackageName, if not empty, replaces the package name of the template.
+	PackageName string
+	// Imports are import paths that are explicitly added to the output.
+	Imports []string
+	// StripTags are build tags that are taken out of the build constraint
+	// of the output, as if they were set.
+	StripTags []string
+	// BuildConstraints are build constraint expressions, e.g. "!purego",
+	// that are added to the build constraint of the output.
+	BuildConstraints []string
+	// UseAst selects the AST based transformation instead of the line based
+	// one.
+	UseAst bool
+	// Workers is the number of typesets that are generated in parallel.
+	// GOMAXPROCS is used if zero.
+	Workers int
+	// Samples provides the values that fill in `//genny:samples`
+	// declarations. Samples declared in the template with `//genny:sample`
+	// take precedence. BuiltinSamples is used if nil.
+	Samples SampleSource
+	// TemplatePackage, if not empty, is the import path of the template's
+	// package, for generating into another package. References to the
+	// declarations in the other files of the template's directory are
+	// qualified with an import of it.
+	TemplatePackage string
+	// Header replaces DefaultHeader at the top of the output. Lines that
+	// are not comments are turned into comments, and the "Code generated"
+	// line is added if it is missing.
+	Header string
+	// Provenance, if not nil, is recorded in the header.
+	Provenance *Provenance
+	// Format controls how the output is formatted.
+	Format FormatOptions
+	// Trace, if not nil, records every substitution and the code generated
+	// for each typeset before it is merged and formatted.
+	Trace Tracer
+	// Constants are values for the generic constants of the template, like
+	// "Capacity": "64", for the typesets that don't give one. A value can
+	// have an alias for naming, like "Big:1024".
+	Constants map[string]string
+	// Naming gets the words for the specific types in identifiers and
+	// comments. NamingOptions{} is used if nil.
+	Naming Naming
+	// Placement is where the words go in identifiers.
+	Placement Placement
+	// Literals is the policy for substituting into string literals.
+	Literals LiteralPolicy
+	// Rename renames the top-level declarations of the output. It can't be
+	// used with GenerateTo.
+	Rename RenameOptions
+}
+
+// sampleSource gets the source of the samples, BuiltinSamples if the options
+// give none.
+func (opts Options) sampleSource() SampleSource {
+	if opts.Samples == nil {
+		return BuiltinSamples
+	}
+	return opts.Samples
+}
+
 // Generics parses the source file and generates the bytes replacing the
 // generic types for the keys map with the specific types (its value).
 func Generics(filename, pkgName string, in io.ReadSeeker, typeSets []map[string]TypeRef, importPaths []string, stripTag string, useAstImpl bool) ([]byte, error) {
-	var localUnwantedLinePrefixes [][]byte
-	localUnwantedLinePrefixes = append(localUnwantedLinePrefixes, unwantedLinePrefixes...)
-
+	opts := Options{
+		PackageName: pkgName,
+		Imports:     importPaths,
+		UseAst:      useAstImpl,
+	}
 	if stripTag != "" {
-		localUnwantedLinePrefixes = append(localUnwantedLinePrefixes, []byte(fmt.Sprintf("// +build %s", stripTag)))
-		localUnwantedLinePrefixes = append(localUnwantedLinePrefixes, []byte(fmt.Sprintf("//go:build %s", stripTag)))
+		opts.StripTags = []string{stripTag}
 	}
+	return Generate(filename, in, typeSets, opts)
+}
 
-	var totalOutput [][]byte
-
-	for _, typeSet := range typeSets {
-
-		// generate the specifics
-		var parsed []byte
-		var err error
-		if useAstImpl {
-			parsed, err = generateSpecificAst(filename, in, typeSet)
-		} else {
-			parsed, err = generateSpecific(filename, in, typeSet)
-		}
-		if err != nil {
-			return nil, err
-		}
-
-		totalOutput = append(totalOutput, parsed)
-	}
-
-	// clean up the code line by line
-
-	packageFound := false
-	// Whether to wait for the "genny:start" comment to start copying. This will be set to true
-	// after we have went through the first generated type, so subsequent generated types will
-	// not copy anything before that line
-	fileHasGennyStart := false
-	importLineIndex := -1
-	var collectedImports stringArraySet
-	cleanOutputLines := []string{header}
-	for fileIndex, transformedOutput := range totalOutput {
-		insideImportBlock := false
-		packageFoundForFile := false
-		bs := bufio.NewScanner(bytes.NewReader(transformedOutput))
-		pastGennyStart := false
-
-	FORSCAN:
-		for bs.Scan() {
-			// TODO: Determine if there's a more elegant way to do this. Right now the constraint package doesn't provide
-			//       any easy way to strip out specific tags on complex lines.
-			if stripTag != "" && (bytes.HasPrefix(bs.Bytes(), []byte("//go:build")) || bytes.HasPrefix(bs.Bytes(), []byte("// +build"))) {
-				expr, err := constraint.Parse(bs.Text())
-				if err == nil {
-					buildLines, err := constraint.PlusBuildLines(expr)
-					if err == nil {
-						for _, line := range buildLines {
-							if strings.Contains(line, stripTag) {
-								continue FORSCAN
-							}
-						}
-					}
-				}
-			}
-
-			if bytes.HasPrefix(bs.Bytes(), []byte("//genny:start")) {
-				pastGennyStart = true
-				fileHasGennyStart = true
-				continue
-			}
-
-			// end of imports block?
-			if insideImportBlock {
-				if bytes.HasSuffix(bs.Bytes(), closeBrace) {
-					insideImportBlock = false
-					// cleanOutputLines = append(cleanOutputLines, fmt.Sprintln(")"))
-				} else {
-					collectedImports = collectedImports.append(makeLine(bs.Text()))
-					// cleanOutputLines = append(cleanOutputLines, makeLine(bs.Text()))
-				}
-				continue
-			}
-
-			if bytes.HasPrefix(bs.Bytes(), packageKeyword) {
-				packageFoundForFile = true
-				if !packageFound {
-					packageFound = true
-					cleanOutputLines = append(cleanOutputLines, makeLine(bs.Text()))
-				}
-				continue
-			} else if bytes.HasPrefix(bs.Bytes(), importKeyword) {
-				if importLineIndex == -1 {
-					importLineIndex = len(cleanOutputLines)
-				}
-				if bytes.HasSuffix(bs.Bytes(), openBrace) {
-					insideImportBlock = true
-					// cleanOutputLines = append(cleanOutputLines, fmt.Sprintln("import ("))
-				} else {
-					importLine := strings.TrimSpace(makeLine(bs.Text()))
-					importLine = strings.TrimSpace(importLine[6:])
-					collectedImports = collectedImports.append(importLine)
-					// cleanOutputLines = append(cleanOutputLines, importLine)
-				}
-
-				continue
-			}
+// Generate parses the source file and generates the bytes replacing the
+// generic types for the keys map with the specific types (its value), using
+// the given options.
+func Generate(filename string, in io.ReadSeeker, typeSets []map[string]TypeRef, opts Options) ([]byte, error) {
+	importPaths := opts.Imports
+	samples := opts.sampleSource()
 
-			if fileIndex != 0 && !packageFoundForFile {
-				continue
-			}
+	typeSets, err := withConstants(typeSets, opts.Constants)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.Rename.Check(); err != nil {
+		return nil, err
+	}
 
-			if fileHasGennyStart && !pastGennyStart {
-				continue
-			}
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	source, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, sampleTmpl, err := prepareTemplate(filename, source, typeSets, opts)
+	if err != nil {
+		return nil, err
+	}
 
-			// check all unwantedLinePrefixes - and skip them
-			for _, prefix := range localUnwantedLinePrefixes {
-				if bytes.HasPrefix(bs.Bytes(), prefix) {
-					continue FORSCAN
-				}
-			}
+	constraints, err := newConstraintRewrite(opts.StripTags, opts.BuildConstraints)
+	if err != nil {
+		return nil, err
+	}
 
-			cleanOutputLines = append(cleanOutputLines, makeLine(bs.Text()))
-		}
+	// generate the specifics and clean up the code line by line
+	h := newFileHeader(tmpl.file, tmpl.fset, source, opts)
+	m := newMerger(constraints, h.license)
+	cleanOutputLines := append([]string{}, h.lines...)
+	err = generateEach(tmpl, typeSets, opts, sampleTmpl, samples, func(_ int, transformedOutput []byte) error {
+		lines, err := m.merge(transformedOutput)
+		cleanOutputLines = append(cleanOutputLines, lines...)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	var linesWithImport []string
-	linesWithImport = append(linesWithImport, cleanOutputLines[:importLineIndex]...)
-	linesWithImport = append(linesWithImport, fmt.Sprintln("import ("))
-	linesWithImport = append(linesWithImport, collectedImports...)
-	linesWithImport = append(linesWithImport, fmt.Sprintln(")"))
-	linesWithImport = append(linesWithImport, cleanOutputLines[importLineIndex+1:]...)
+	if opts.Format.FormatOnly {
+		m.imports = withoutGenericImport(m.imports)
+	}
+	linesWithImport := cleanOutputLines
+	if m.importLineIndex >= 0 && len(m.imports) > 0 {
+		importLineIndex := m.importLineIndex + len(h.lines) // after the header
+		linesWithImport = nil
+		linesWithImport = append(linesWithImport, cleanOutputLines[:importLineIndex]...)
+		linesWithImport = append(linesWithImport, m.importBlock()...)
+		linesWithImport = append(linesWithImport, cleanOutputLines[importLineIndex:]...)
+	}
 
 	cleanOutput := strings.Join(linesWithImport, "")
 
 	output := []byte(cleanOutput)
 
-	// change package name
-	if pkgName != "" {
-		output = changePackage(bytes.NewReader(output), pkgName)
+	if output, err = renameOutput(filename, output, opts); err != nil {
+		return nil, err
 	}
 	if len(importPaths) > 0 {
 		output = addImports(bytes.NewReader(output), importPaths)
 	}
 	// fix the imports
-	var err error
-	output, err = imports.Process(filename, output, nil)
+	output, err = opts.Format.process(filename, output, false)
 	if err != nil {
-		return nil, &errImports{Err: err}
+		return nil, err
 	}
 
 	return output, nil
 }
 
+// renameOutput renames the package and the top-level declarations of
+// generated code, as the options say.
+func renameOutput(filename string, output []byte, opts Options) ([]byte, error) {
+	if opts.PackageName != "" {
+		output = changePackage(bytes.NewReader(output), opts.PackageName)
+	}
+	return opts.Rename.apply(filename, output)
+}
+
 func makeLine(s string) string {
 	return fmt.Sprintln(strings.TrimRight(s, linefeed))
 }
@@ -398,10 +386,7 @@ func wordify(s string, exported bool) string {
 		s = strings.TrimLeft(s, "*&")
 		s = strings.Replace(s, ".", "", -1)
 	}
-	if !exported {
-		return strings.ToLower(string(s[0])) + s[1:]
-	}
-	return strings.ToUpper(string(s[0])) + s[1:]
+	return casing(s, exported)
 }
 
 // typify gets type name from string.
@@ -461,6 +446,34 @@ func addImports(r io.Reader, importPaths []string) []byte {
 type replaceSpec struct {
 	genericType  string
 	specificType TypeRef
+	matcher      *matcher
+	// trace records the substitutions, if not nil.
+	trace *substitutionTrace
+	// word is the word for the specific type in identifiers, in upper
+	// case, plural its plural, and placement where they go in them.
+	word      string
+	plural    string
+	placement Placement
+	// literals is the policy for string literals, and literalLines the
+	// lines whose literals are marked.
+	literals     LiteralPolicy
+	literalLines map[int]bool
+}
+
+// replaceSpec gets the spec for substituting a specific type for a generic
+// type of the template, given the words of the specific types.
+func (t *template) replaceSpec(genericType string, specificType TypeRef, words map[string]string, trace *substitutionTrace) replaceSpec {
+	return replaceSpec{
+		genericType:  genericType,
+		specificType: specificType,
+		matcher:      t.matchers[genericType],
+		trace:        trace,
+		word:         words[genericType],
+		plural:       t.plural(specificType, words[genericType]),
+		placement:    t.placement,
+		literals:     t.literals,
+		literalLines: t.literalLines,
+	}
 }
 
 func (rs replaceSpec) toType() string {
@@ -468,7 +481,26 @@ func (rs replaceSpec) toType() string {
 }
 
 func (rs replaceSpec) toWord(uppercase bool) string {
-	return wordify(rs.specificType.Alias, uppercase)
+	return wordify(rs.word, uppercase)
+}
+
+// toPlural gets the plural of the word for the specific type.
+func (rs replaceSpec) toPlural(uppercase bool) string {
+	if rs.specificType.Plural != "" {
+		return wordify(rs.specificType.Plural, uppercase)
+	}
+	if rs.plural == "" {
+		return casing(pluralize(rs.word), uppercase)
+	}
+	return casing(rs.plural, uppercase)
+}
+
+// replacePlurals replaces the plurals of the generic type name in text with
+// the plural of the word for the specific type.
+func (rs replaceSpec) replacePlurals(text string) string {
+	return replacePlural(text, rs.matcher.plural, func(match string) string {
+		return rs.toPlural(startsUpper(match))
+	})
 }
 
 func (rs replaceSpec) String() string {
@@ -494,10 +526,13 @@ func deleteComment(file *ast.File, comment *ast.CommentGroup) {
 }
 
 func transformText(text string, spec replaceSpec) string {
-	reExact := regexp.MustCompile("\\b" + spec.genericType + "\\b")
-	text = reExact.ReplaceAllString(text, spec.specificType.Alias)
+	if spec.placement != InPlace {
+		return placeWords(text, spec)
+	}
+	text = spec.replacePlurals(text)
+	text = spec.matcher.exact.ReplaceAllString(text, spec.specificType.Alias)
 	return replaceBoundaryFunc(text, spec.genericType, func(match string) string {
-		return spec.toWord(unicode.IsUpper(rune(match[0])))
+		return spec.toWord(startsUpper(match))
 	})
 }
 
@@ -509,6 +544,7 @@ func transformType(ident *ast.Ident, spec replaceSpec, log string) *ast.Ident {
 	}
 	output := *ident
 	output.Name = spec.toType()
+	spec.trace.record(log, ident.Pos(), ident.Name, output.Name)
 	return &output
 }
 
@@ -517,6 +553,7 @@ func transformIdentifier(ident *ast.Ident, spec replaceSpec, log string) *ast.Id
 
 	output := *ident
 	output.Name = transformed
+	spec.trace.record(log, ident.Pos(), ident.Name, output.Name)
 	return &output
 }
 
@@ -526,15 +563,32 @@ func generateSpecificType(fs *token.FileSet, file *ast.File, spec replaceSpec) {
 			switch v := c.Node().(type) {
 			case *ast.File:
 				for _, commentGroup := range v.Comments {
+					if hasDirective(commentGroup, verbatimDirective) {
+						continue
+					}
 					for _, cmt := range commentGroup.List {
 						// Replace the comments
-						cmt.Text = transformText(cmt.Text, spec)
+						text := subIntoComment(cmt.Text, spec)
+						if text != cmt.Text {
+							spec.trace.record("COMMENT", cmt.Pos(), cmt.Text, text)
+						}
+						cmt.Text = text
 					}
 				}
+			case *ast.BasicLit:
+				if v.Kind != token.STRING {
+					break
+				}
+				value := subIntoString(v.Value, spec, spec.literalLines[fs.Position(v.Pos()).Line])
+				if value != v.Value {
+					spec.trace.record("STRING", v.Pos(), v.Value, value)
+					output := *v
+					output.Value = value
+					c.Replace(&output)
+				}
 			case *ast.Ident:
 				var newIdent *ast.Ident
-				if containsFold(v.Name, spec.genericType) {
-					// print("    >>>>>", v.Name, spec, reflect.TypeOf(c.Parent()))
+				if spec.matcher.in(v.Name) {
 					switch p := c.Parent().(type) {
 					case *ast.ArrayType:
 						// []generic
@@ -607,19 +661,31 @@ func generateSpecificType(fs *token.FileSet, file *ast.File, spec replaceSpec) {
 						// MyStruct{ field: genericVal }
 						// MyStruct{ genericVal: field }
 						newIdent = transformType(v, spec, "KEY VALUE EXPR")
+					case *ast.RangeStmt:
+						// for _, v := range genericValues
+						newIdent = transformIdentifier(v, spec, "RANGE")
+					case *ast.IndexExpr:
+						// genericValues[i]
+						newIdent = transformIdentifier(v, spec, "INDEX EXPR")
 					case *ast.BranchStmt:
 						// ignore
 					case *ast.TypeAssertExpr:
 						// a.(generic)
 						newIdent = transformType(v, spec, "TYPE ASSERT EXPR")
-					case *ast.File:
-						if debug {
-							print("UNRESOLVED???", v.Name, spec, reflect.TypeOf(c.Parent()))
-						}
+					case *ast.SliceExpr:
+						// genericValues[genericStart:genericEnd]
+						newIdent = transformType(v, spec, "SLICE EXPR")
+					case *ast.UnaryExpr, *ast.ParenExpr, *ast.Ellipsis, *ast.ChanType:
+						// &genericValue, (generic), ...generic, chan generic
+						newIdent = transformType(v, spec, "EXPR")
+					case *ast.ReturnStmt, *ast.IncDecStmt, *ast.SendStmt, *ast.CaseClause,
+						*ast.SwitchStmt, *ast.IfStmt, *ast.ForStmt:
+						// return genericValue, genericCount++, ch <- genericValue,
+						// case genericValue:, switch genericValue, if genericOK,
+						// for genericOK
+						newIdent = transformType(v, spec, "STMT")
 					default:
-						if debug {
-							print(">>>>>", v.Name, spec, reflect.TypeOf(c.Parent()))
-						}
+						spec.trace.record(fmt.Sprintf("UNHANDLED %T", c.Parent()), v.Pos(), v.Name, v.Name)
 					}
 				}
 				if newIdent != nil {
@@ -627,11 +693,10 @@ func generateSpecificType(fs *token.FileSet, file *ast.File, spec replaceSpec) {
 				}
 			case *ast.TypeSpec:
 				if isGenericTypeDefinition(v) {
+					spec.trace.record("GENERIC TYPE", v.Pos(), v.Name.Name, "")
 					deleteAllComments(file, v)
 					c.Delete()
 				}
-			default:
-				print("UNKNOWN:", v, reflect.TypeOf(v))
 			}
 			return true
 		},
@@ -670,57 +735,22 @@ func isGenericTypeDefinition(typeSpec *ast.TypeSpec) bool {
 func isGenericTypeSelector(selector *ast.SelectorExpr) bool {
 	if ident, ok := selector.X.(*ast.Ident); ok {
 		if ident.Name == "generic" &&
-			(selector.Sel.Name == "Type" || selector.Sel.Name == "Number") {
+			(selector.Sel.Name == "Type" || selector.Sel.Name == "Number" || selector.Sel.Name == "Name") {
 			return true
 		}
 	}
 	return false
 }
 
-func generateSpecificAst(filename string, in io.ReadSeeker, typeSet map[string]TypeRef) ([]byte, error) {
-
-	// ensure we are at the beginning of the file
-	if _, err := in.Seek(0, io.SeekStart); err != nil {
-		return nil, err
-	}
-
-	// parse the source file
-	fs := token.NewFileSet()
-	file, err := parser.ParseFile(fs, filename, in, parser.ParseComments)
-	if err != nil {
-		return nil, &errSource{Err: err}
-	}
-
-	// make sure every generic.Type is represented in the types
-	// argument.
-	for _, decl := range file.Decls {
-		switch it := decl.(type) {
-		case *ast.GenDecl:
-			for _, spec := range it.Specs {
-				ts, ok := spec.(*ast.TypeSpec)
-				if !ok {
-					continue
-				}
-				switch tt := ts.Type.(type) {
-				case *ast.SelectorExpr:
-					if name, ok := tt.X.(*ast.Ident); ok {
-						if name.Name == genericPackage {
-							if _, ok := typeSet[ts.Name.Name]; !ok {
-								return nil, &errMissingSpecificType{GenericType: ts.Name.Name}
-							}
-						}
-					}
-				}
-			}
-		}
-	}
+func generateSpecificAst(tmpl *template, typeSet map[string]TypeRef, words map[string]string, trace *substitutionTrace) ([]byte, error) {
+	file := cloneFile(tmpl.file)
 
 	var buf bytes.Buffer
 	for t, specificType := range typeSet {
-		generateSpecificType(fs, file, replaceSpec{t, specificType})
+		generateSpecificType(tmpl.fset, file, tmpl.replaceSpec(t, specificType, words, trace))
 	}
 
-	err = printer.Fprint(&buf, fs, file)
+	err := printer.Fprint(&buf, tmpl.fset, file)
 	return buf.Bytes(), err
 }
 
@@ -728,15 +758,21 @@ func containsFold(s, substring string) bool {
 	return strings.Contains(strings.ToLower(s), strings.ToLower(substring))
 }
 
+// indexFold gets the index of substring in s, ignoring case.
 func indexFold(s, substring string) int {
-	return strings.Index(strings.ToLower(s), strings.ToLower(substring))
+	for i := range s {
+		if len(s)-i < len(substring) {
+			break
+		}
+		if strings.EqualFold(s[i:i+len(substring)], substring) {
+			return i
+		}
+	}
+	return -1
 }
 
 func isExported(lit string) bool {
-	if len(lit) == 0 {
-		return false
-	}
-	return unicode.IsUpper(rune(lit[0]))
+	return startsUpper(lit)
 }
 
 func containsBoundary(s, substring string) bool {
@@ -748,7 +784,9 @@ func indexBoundary(s, substring string) int {
 	if pos == -1 {
 		return -1
 	}
-	startIsBoundary := pos == 0 || !unicode.IsLetter(rune(s[pos-1])) || !unicode.IsLetter(rune(s[pos])) || unicode.IsUpper(rune(s[pos]))
+	prev, _ := utf8.DecodeLastRuneInString(s[:pos])
+	first, size := utf8.DecodeRuneInString(s[pos:])
+	startIsBoundary := pos == 0 || !unicode.IsLetter(prev) || !unicode.IsLetter(first) || unicode.IsUpper(first)
 	// endPos := pos + len(substring)
 	endIsBoundary := true
 	// TODO: Find a way to deal with "-s", "-ed", etc
@@ -756,49 +794,32 @@ func indexBoundary(s, substring string) int {
 	if startIsBoundary && endIsBoundary {
 		return pos
 	}
-	recursive := indexBoundary(s[pos+1:], substring)
+	recursive := indexBoundary(s[pos+size:], substring)
 	if recursive == -1 {
 		return -1
 	}
-	return recursive + pos + 1
+	return recursive + pos + size
 }
 
 func replaceBoundary(s, old string, newstring string) string {
-	i := 0
-	output := ""
-	for {
-		pos := indexBoundary(s[i:], old)
-		if pos == -1 {
-			break
-		}
-		pos += i
-		output += s[i:pos]
-		output += newstring
-		i = pos + len(old)
-	}
-	output += s[i:]
-	return output
+	return replaceBoundaryFunc(s, old, func(string) string {
+		return newstring
+	})
 }
 
 func replaceBoundaryFunc(s, old string, replace func(string) string) string {
 	i := 0
-	output := ""
+	var output strings.Builder
 	for {
 		pos := indexBoundary(s[i:], old)
 		if pos == -1 {
 			break
 		}
 		pos += i
-		output += s[i:pos]
-		output += replace(s[pos : pos+len(old)])
+		output.WriteString(s[i:pos])
+		output.WriteString(replace(s[pos : pos+len(old)]))
 		i = pos + len(old)
 	}
-	output += s[i:]
-	return output
-}
-
-func print(a ...interface{}) {
-	if debug {
-		fmt.Println(a...)
-	}
+	output.WriteString(s[i:])
+	return output.String()
 }
@@ -0,0 +1,61 @@
+package parse_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/tehbilly/genny/parse"
+)
+
+func benchmarkGenerics(b *testing.B, template, types string, useAst bool) {
+	in, err := contents(template)
+	if err != nil {
+		b.Fatal(err)
+	}
+	typeSets, err := parse.TypeSet(types)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parse.Generics("generic.go", "", strings.NewReader(in), typeSets, nil, "", useAst); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGenericsBuiltinsSquared(b *testing.B) {
+	benchmarkGenerics(b, "test/multipletypes/generic_simplemap.go", "KeyType=BUILTINS ValueType=BUILTINS", false)
+}
+
+func BenchmarkGenericsBuiltinsSquaredAst(b *testing.B) {
+	benchmarkGenerics(b, "test/multipletypes/generic_simplemap.go", "KeyType=BUILTINS ValueType=BUILTINS", true)
+}
+
+func BenchmarkGenericsLargeTemplate(b *testing.B) {
+	benchmarkGenerics(b, "../examples/btree/btree.go", "key=int,string value=NUMBERS", false)
+}
+
+func BenchmarkGenericsLargeTemplateAst(b *testing.B) {
+	benchmarkGenerics(b, "../examples/btree/btree.go", "key=int,string value=NUMBERS", true)
+}
+
+func BenchmarkGenerateToLargeTemplateAst(b *testing.B) {
+	in, err := contents("../examples/btree/btree.go")
+	if err != nil {
+		b.Fatal(err)
+	}
+	typeSets, err := parse.TypeSet("key=int,string value=NUMBERS")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := parse.GenerateTo(io.Discard, "generic.go", strings.NewReader(in), typeSets, parse.Options{UseAst: true}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
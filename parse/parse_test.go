@@ -7,6 +7,7 @@ import (
 	"github.com/tehbilly/genny/parse"
 	"io/ioutil"
 	"log"
+	"os"
 	"strings"
 	"testing"
 )
@@ -184,6 +185,17 @@ var tests = []struct {
 		types:       []map[string]parse.TypeRef{{"SomeThing": parse.TypeRef{Alias: "string", Type: "string"}}},
 		expectedOut: `test/bugreports/negation_string.go`,
 	},
+	{
+		filename: "receiver_generic.go",
+		in:       `test/bugreports/receiver_generic.go`,
+		tag:      "genny",
+		types: []map[string]parse.TypeRef{
+			{"TA": parse.TypeRef{Alias: "string", Type: "string"}, "TB": parse.TypeRef{Alias: "int", Type: "int"}},
+			{"TA": parse.TypeRef{Alias: "string", Type: "string"}, "TB": parse.TypeRef{Alias: "float64", Type: "float64"}},
+			{"TA": parse.TypeRef{Alias: "string", Type: "string"}, "TB": parse.TypeRef{Alias: "bool", Type: "bool"}},
+		},
+		expectedOut: `test/bugreports/receiver_expected.go`,
+	},
 	{
 		filename:    "buildtags.go",
 		in:          `test/buildtags/buildtags.go`,
@@ -198,6 +210,37 @@ var tests = []struct {
 		expectedOut: `test/buildtags/buildtags_expected_nostrip.go`,
 		tag:         "",
 	},
+	{
+		filename: "generic_stack.go",
+		in:       `test/samples/generic_stack.go`,
+		types: []map[string]parse.TypeRef{
+			{"Item": parse.TypeRef{Alias: "int", Type: "int"}},
+			{"Item": parse.TypeRef{Alias: "string", Type: "string"}},
+			{"Item": parse.TypeRef{Alias: "Point", Type: "Point"}},
+			{"Item": parse.TypeRef{Alias: "Celsius", Type: "Celsius"}},
+		},
+		expectedOut: `test/samples/stacks.go`,
+	},
+	{
+		filename: "generic_stack_test.go",
+		in:       `test/samples/generic_stack_test.go`,
+		types: []map[string]parse.TypeRef{
+			{"Item": parse.TypeRef{Alias: "int", Type: "int"}},
+			{"Item": parse.TypeRef{Alias: "string", Type: "string"}},
+			{"Item": parse.TypeRef{Alias: "Point", Type: "Point"}},
+			{"Item": parse.TypeRef{Alias: "Celsius", Type: "Celsius"}},
+		},
+		expectedOut: `test/samples/stacks_test.go`,
+	},
+	{
+		filename: "generic_cache.go",
+		in:       `test/names/generic_cache.go`,
+		types: []map[string]parse.TypeRef{
+			{"Prefix": parse.TypeRef{Alias: "Session", Type: "Session"}, "Elem": parse.TypeRef{Alias: "string", Type: "string"}},
+			{"Prefix": parse.TypeRef{Alias: "Request", Type: "Request"}, "Elem": parse.TypeRef{Alias: "string", Type: "string"}},
+		},
+		expectedOut: `test/names/string_caches.go`,
+	},
 }
 
 func TestParse(t *testing.T) {
@@ -242,6 +285,21 @@ func TestParse(t *testing.T) {
 
 }
 
+func TestGenerateWorkers(t *testing.T) {
+	in, err := contents(`test/multipletypesets/generic_simplemap.go`)
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("KeyType=BUILTINS ValueType=int,string")
+	require.NoError(t, err)
+
+	for _, useAst := range []bool{true, false} {
+		serial, err := parse.Generate("generic_simplemap.go", strings.NewReader(in), typeSets, parse.Options{UseAst: useAst, Workers: 1})
+		require.NoError(t, err)
+		parallel, err := parse.Generate("generic_simplemap.go", strings.NewReader(in), typeSets, parse.Options{UseAst: useAst, Workers: 8})
+		require.NoError(t, err)
+		assert.Equal(t, string(serial), string(parallel))
+	}
+}
+
 func contents(s string) (string, error) {
 	if strings.HasSuffix(s, "go") || strings.HasSuffix(s, "go.nobuild") {
 		bytes, err := ioutil.ReadFile(s)
@@ -252,3 +310,258 @@ func contents(s string) (string, error) {
 	}
 	return s, nil
 }
+
+func TestGenerateTo(t *testing.T) {
+	for testNo, test := range tests {
+		if test.expectedErr != nil {
+			continue
+		}
+		for _, useAst := range []bool{true, false} {
+			if (useAst && test.suppressForAstImpl) || (!useAst && test.suppressForLegacyImpl) {
+				continue
+			}
+			t.Run(fmt.Sprintf("%d:%s/(ast:%v)", testNo, test.expectedOut, useAst), func(t *testing.T) {
+				in, err := contents(test.in)
+				require.NoError(t, err)
+				expectedOut, err := contents(test.expectedOut)
+				require.NoError(t, err)
+
+				opts := parse.Options{
+					PackageName: test.pkgName,
+					Imports:     test.imports,
+					UseAst:      useAst,
+				}
+				if test.tag != "" {
+					opts.StripTags = []string{test.tag}
+				}
+				var out strings.Builder
+				err = parse.GenerateTo(&out, test.filename, strings.NewReader(in), test.types, opts)
+				require.NoError(t, err)
+				assert.Equal(t, expectedOut, out.String())
+			})
+		}
+	}
+}
+
+func TestGenerateTemplatePackage(t *testing.T) {
+	const templatePackage = "github.com/tehbilly/genny/parse/test/crosspkg"
+	typeSets, err := parse.TypeSet("Item=int,string")
+	require.NoError(t, err)
+	in, err := contents("test/crosspkg/generic_list.go")
+	require.NoError(t, err)
+	expectedOut, err := contents("test/crosspkg/other/lists.go")
+	require.NoError(t, err)
+
+	for _, useAst := range []bool{true, false} {
+		opts := parse.Options{PackageName: "other", UseAst: useAst, TemplatePackage: templatePackage}
+		out, err := parse.Generate("test/crosspkg/generic_list.go", strings.NewReader(in), typeSets, opts)
+		require.NoError(t, err)
+		assert.Equal(t, expectedOut, string(out))
+
+		var streamed strings.Builder
+		err = parse.GenerateTo(&streamed, "test/crosspkg/generic_list.go", strings.NewReader(in), typeSets, opts)
+		require.NoError(t, err)
+		assert.Equal(t, expectedOut, streamed.String())
+	}
+
+	in, err = contents("test/crosspkg/generic_reset.go")
+	require.NoError(t, err)
+	typeSets, err = parse.TypeSet("Value=int")
+	require.NoError(t, err)
+	_, err = parse.Generate("test/crosspkg/generic_reset.go", strings.NewReader(in), typeSets, parse.Options{PackageName: "other", TemplatePackage: templatePackage})
+	assert.EqualError(t, err, "Cannot refer to unexported declarations of "+templatePackage+" from another package: reset")
+}
+
+func TestGenerateDirective(t *testing.T) {
+	for line, expected := range map[string]string{
+		`//go:generate genny -in=a.go gen "T=int"`:                                     `-in=a.go gen "T=int"`,
+		`//go:generate $GOPATH/bin/genny -in=a.go gen "T=int"`:                         `-in=a.go gen "T=int"`,
+		`//go:generate go run github.com/tehbilly/genny -in=a.go gen "T=int"`:          `-in=a.go gen "T=int"`,
+		`//go:generate go run github.com/tehbilly/genny@v1.2.0 -in=a.go gen "T=int"`:   `-in=a.go gen "T=int"`,
+		`//go:generate go run github.com/tehbilly/genny@latest   -in=a.go gen "T=int"`: `-in=a.go gen "T=int"`,
+	} {
+		args, ok := parse.GenerateDirective(line)
+		assert.True(t, ok, line)
+		assert.Equal(t, expected, args, line)
+	}
+
+	for _, line := range []string{
+		`//go:generate stringer -type=T`,
+		`// go:generate genny gen "T=int"`,
+		`//go:generate go run github.com/tehbilly/gennyx gen "T=int"`,
+	} {
+		_, ok := parse.GenerateDirective(line)
+		assert.False(t, ok, line)
+	}
+}
+
+func TestGenerateOptionalInterfaceType(t *testing.T) {
+	in, err := contents("test/interfaces/join.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("Other=MyStr")
+	require.NoError(t, err)
+	out, err := parse.Generate("join.go", strings.NewReader(in), typeSets, parse.Options{})
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "func JoinStringers(list []Stringer, sep string) (result string) {")
+}
+
+func TestGenerateBadName(t *testing.T) {
+	in, err := contents("test/names/generic_cache.go")
+	require.NoError(t, err)
+	for _, arg := range []string{"Prefix=a.b Elem=string", "Prefix=[]int Elem=string"} {
+		typeSets, err := parse.TypeSet(arg)
+		require.NoError(t, err)
+		_, err = parse.Generate("generic_cache.go", strings.NewReader(in), typeSets, parse.Options{})
+		assert.Error(t, err, arg)
+	}
+}
+
+func TestGenerateHeader(t *testing.T) {
+	in, err := contents("test/header/generic_list.go")
+	require.NoError(t, err)
+	expectedOut, err := contents("test/header/lists.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("Something=int,string")
+	require.NoError(t, err)
+
+	for _, useAst := range []bool{true, false} {
+		opts := parse.Options{
+			UseAst: useAst,
+			Header: "Licensed to you.\n\nDo not edit.",
+			Provenance: &parse.Provenance{
+				Template: "generic_list.go",
+				Args:     []string{"-in=generic_list.go", "-out=lists.go", "gen", "Something=int,string"},
+				Version:  "v1.0.0",
+			},
+		}
+		out, err := parse.Generate("generic_list.go", strings.NewReader(in), typeSets, opts)
+		require.NoError(t, err)
+		assert.Equal(t, expectedOut, string(out))
+
+		var streamed strings.Builder
+		require.NoError(t, parse.GenerateTo(&streamed, "generic_list.go", strings.NewReader(in), typeSets, opts))
+		assert.Equal(t, expectedOut, streamed.String())
+
+		// the license stays at the top with the default header too
+		out, err = parse.Generate("generic_list.go", strings.NewReader(in), typeSets, parse.Options{UseAst: useAst})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(out), "// Copyright 2020 The Genny Authors. All rights reserved.\n"+
+			"// Use of this source code is governed by an MIT license.\n\n"+parse.DefaultHeader+"\n"+
+			"// Package header tests the headers of generated files.\npackage header\n"), string(out))
+	}
+}
+
+func TestGenerateBlockLicense(t *testing.T) {
+	in, err := contents("test/license/generic_block.go")
+	require.NoError(t, err)
+	expectedOut, err := contents("test/license/blocks.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("Something=int,string")
+	require.NoError(t, err)
+
+	for _, useAst := range []bool{true, false} {
+		out, err := parse.Generate("generic_block.go", strings.NewReader(in), typeSets, parse.Options{UseAst: useAst})
+		require.NoError(t, err)
+		assert.Equal(t, expectedOut, string(out))
+
+		var streamed strings.Builder
+		require.NoError(t, parse.GenerateTo(&streamed, "generic_block.go", strings.NewReader(in), typeSets, parse.Options{UseAst: useAst}))
+		assert.Equal(t, expectedOut, streamed.String())
+	}
+}
+
+func TestReadProvenance(t *testing.T) {
+	f, err := os.Open("test/header/lists.go")
+	require.NoError(t, err)
+	defer f.Close()
+	p, err := parse.ReadProvenance(f)
+	require.NoError(t, err)
+	assert.Equal(t, &parse.Provenance{
+		Template: "generic_list.go",
+		Hash:     "sha256:76ae3876f2d560028cdad135aade2d5652c28416abb4e421a7bde2b42452e93f",
+		Args:     []string{"-in=generic_list.go", "-out=lists.go", "gen", "Something=int,string"},
+		Version:  "v1.0.0",
+	}, p)
+
+	// arguments are quoted as needed
+	in, err := contents("test/header/generic_list.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("Something=int")
+	require.NoError(t, err)
+	args := []string{"-imp", "", "gen", "Something=int Other=$X"}
+	out, err := parse.Generate("generic_list.go", strings.NewReader(in), typeSets, parse.Options{Provenance: &parse.Provenance{Args: args}})
+	require.NoError(t, err)
+	p, err = parse.ReadProvenance(strings.NewReader(string(out)))
+	require.NoError(t, err)
+	assert.Equal(t, args, p.Args)
+
+	p, err = parse.ReadProvenance(strings.NewReader(parse.DefaultHeader + "\npackage p\n// genny:args gen T=int\n"))
+	require.NoError(t, err)
+	assert.Nil(t, p)
+}
+
+func TestGenerateBuildConstraints(t *testing.T) {
+	typeSets := []map[string]parse.TypeRef{{"_t_": parse.TypeRef{Alias: "int", Type: "int"}}}
+	in, err := contents("test/buildtags/buildtags_complex.go")
+	require.NoError(t, err)
+	expectedOut, err := contents("test/buildtags/buildtags_complex_expected.go")
+	require.NoError(t, err)
+	queue, err := contents("test/queue/generic_queue.go")
+	require.NoError(t, err)
+
+	for _, useAst := range []bool{true, false} {
+		opts := parse.Options{UseAst: useAst, StripTags: []string{"genny", "gennylist"}, BuildConstraints: []string{"!purego"}}
+		out, err := parse.Generate("buildtags_complex.go", strings.NewReader(in), typeSets, opts)
+		require.NoError(t, err)
+		assert.Equal(t, expectedOut, string(out))
+
+		var streamed strings.Builder
+		err = parse.GenerateTo(&streamed, "buildtags_complex.go", strings.NewReader(in), typeSets, opts)
+		require.NoError(t, err)
+		assert.Equal(t, expectedOut, streamed.String())
+
+		// a template without a constraint gets one
+		opts = parse.Options{UseAst: useAst, BuildConstraints: []string{"!purego"}}
+		out, err = parse.Generate("generic_queue.go", strings.NewReader(queue), []map[string]parse.TypeRef{{"Something": parse.TypeRef{Alias: "int", Type: "int"}}}, opts)
+		require.NoError(t, err)
+		assert.Contains(t, string(out), "\n\n//go:build !purego\n// +build !purego\n\npackage queue\n")
+	}
+}
+
+func TestGenerateExpressions(t *testing.T) {
+	in := `package p
+
+import "github.com/tehbilly/genny/generic"
+
+type Something generic.Type
+
+func somethingHalves(somethingItems []Something, somethingCh chan Something) (int, *[]Something) {
+	somethingN := len(somethingItems) / 2
+	somethingCh <- somethingItems[somethingN]
+	somethingN++
+	switch somethingN {
+	case somethingN:
+	}
+	somethingOK := somethingN > 0
+	if somethingOK {
+		somethingOK = false
+	}
+	for somethingOK {
+	}
+	const somethingSize = 2
+	var somethingPair [somethingSize]Something
+	somethingRest := append(somethingPair[:], somethingItems[somethingN:]...)
+	return (somethingN), &somethingRest
+}
+`
+	typeSets := []map[string]parse.TypeRef{{"Something": parse.TypeRef{Alias: "int", Type: "int"}}}
+	var outputs []string
+	for _, useAst := range []bool{true, false} {
+		out, err := parse.Generate("expressions.go", strings.NewReader(in), typeSets, parse.Options{UseAst: useAst})
+		require.NoError(t, err)
+		assert.NotContains(t, string(out), "something", "ast: %v", useAst)
+		assert.Contains(t, string(out), "intRest := append(intPair[:], intItems[intN:]...)\n\treturn (intN), &intRest\n", "ast: %v", useAst)
+		outputs = append(outputs, string(out))
+	}
+	assert.Equal(t, outputs[0], outputs[1])
+}
@@ -0,0 +1,338 @@
+package parse
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Plan describes what generating a template for a typeset would do,
+// without generating any code.
+type Plan struct {
+	TypeSet map[string]TypeRef `json:"typeSet"`
+	// Renames are the identifiers that are renamed, in the order they first
+	// appear in the template.
+	Renames []Rename `json:"renames"`
+	// API is the exported API of the generated code.
+	API []string `json:"api"`
+}
+
+// Rename is an identifier that is renamed by generating a template.
+type Rename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Line is the first line of the template it appears on.
+	Line int `json:"line"`
+	// Count is the number of times it appears.
+	Count int `json:"count"`
+}
+
+// Preview gets the plans for generating a template for each of the
+// typeSets. The code is generated with the options as Generate would,
+// without being merged or formatted.
+func Preview(filename string, in io.Reader, typeSets []map[string]TypeRef, opts Options) ([]Plan, error) {
+	typeSets, err := withConstants(typeSets, opts.Constants)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.Rename.Check(); err != nil {
+		return nil, err
+	}
+	source, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, samples, err := prepareTemplate(filename, source, typeSets, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var plans []Plan
+	for i, typeSet := range typeSets {
+		if err := tmpl.checkTypeSet(typeSet); err != nil {
+			return nil, err
+		}
+		t, err := tmpl.forTypeSet(typeSets, i, samples, opts.sampleSource())
+		if err != nil {
+			return nil, err
+		}
+		output, err := tmpl.generateTypeSet(typeSets, i, opts.UseAst, samples, opts.sampleSource(), nil)
+		if err != nil {
+			return nil, err
+		}
+		final, err := tmpl.finalNames(filename, typeSets, i, output, opts)
+		if err != nil {
+			return nil, err
+		}
+		if output, err = renameOutput(filename, output, opts); err != nil {
+			return nil, err
+		}
+
+		plan := Plan{TypeSet: typeSet}
+		if opts.UseAst {
+			plan.Renames = t.astRenames(typeSet, final)
+		} else {
+			plan.Renames = t.lineRenames(typeSet, final)
+		}
+		if plan.API, err = exportedAPI(filename, output); err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// finalNames gets what the names in the code generated for typeSets[i]
+// become once it is renamed: the shared methods get the words of the specific
+// types, and the package and the top-level declarations are renamed as the
+// options say.
+func (t *template) finalNames(filename string, typeSets []map[string]TypeRef, i int, output []byte, opts Options) (func(string) string, error) {
+	words, err := t.words(typeSets[i])
+	if err != nil {
+		return nil, err
+	}
+	renames, err := opts.Rename.renames(filename, output)
+	if err != nil {
+		return nil, err
+	}
+	return func(name string) string {
+		if t.shared != nil && len(typeSets) > 1 {
+			if _, ok := t.shared.methods[name]; ok {
+				return name + t.shared.suffix(name, words)
+			}
+		}
+		if name == t.file.Name.Name && opts.PackageName != "" {
+			return opts.PackageName
+		}
+		if newName, ok := renames[name]; ok {
+			return newName
+		}
+		return name
+	}, nil
+}
+
+// renameList collects renames.
+type renameList struct {
+	renames []Rename
+	index   map[[2]string]int
+}
+
+func (l *renameList) add(from, to string, line int) {
+	if from == to {
+		return
+	}
+	key := [2]string{from, to}
+	if i, ok := l.index[key]; ok {
+		l.renames[i].Count++
+		return
+	}
+	if l.index == nil {
+		l.index = make(map[[2]string]int)
+	}
+	l.index[key] = len(l.renames)
+	l.renames = append(l.renames, Rename{From: from, To: to, Line: line, Count: 1})
+}
+
+// astRenames gets the renames made by the AST based implementation, with the
+// names it makes turned into their final ones. The transformed identifiers
+// keep their positions, so they can be matched with those of the template.
+func (t *template) astRenames(typeSet map[string]TypeRef, final func(string) string) []Rename {
+	original := make(map[token.Pos]string)
+	ast.Inspect(t.file, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			original[ident.NamePos] = ident.Name
+		}
+		return true
+	})
+
+	words, _ := t.words(typeSet)
+	file := cloneFile(t.file)
+	for name, specificType := range t.typesOnly(typeSet) {
+		generateSpecificType(t.fset, file, t.replaceSpec(name, specificType, words, nil))
+	}
+
+	var renames renameList
+	ast.Inspect(file, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			if from, ok := original[ident.NamePos]; ok {
+				renames.add(from, final(ident.Name), t.fset.Position(ident.NamePos).Line)
+			}
+		}
+		return true
+	})
+	return renames.renames
+}
+
+// lineRenames gets the renames made by the line based implementation, which
+// substitutes each identifier on its own, with the names it makes turned
+// into their final ones.
+func (t *template) lineRenames(typeSet map[string]TypeRef, final func(string) string) []Rename {
+	// the declarations of the generic types are left out
+	skipped := make(map[int]bool)
+	for _, gt := range scanGenericTypes(t.file) {
+		for line := t.fset.Position(gt.spec.Pos()).Line; line <= t.fset.Position(gt.spec.End()).Line; line++ {
+			skipped[line] = true
+		}
+	}
+
+	types := t.typesOnly(typeSet)
+	words, _ := t.words(typeSet)
+	var renames renameList
+	for i, line := range t.lines {
+		if skipped[i+1] {
+			continue
+		}
+		src := []byte(line)
+		var s scanner.Scanner
+		fset := token.NewFileSet()
+		s.Init(fset.AddFile("", fset.Base(), len(src)), src, nil, 0)
+		for {
+			_, tok, lit := s.Scan()
+			if tok == token.EOF {
+				break
+			}
+			if tok != token.IDENT {
+				continue
+			}
+			to := lit
+			for name, specificType := range types {
+				if m := t.matchers[name]; m.in(to) {
+					to = subIntoLiteral(to, t.replaceSpec(name, specificType, words, nil))
+				}
+			}
+			renames.add(lit, final(to), i+1)
+		}
+	}
+	return renames.renames
+}
+
+// exportedAPI lists the exported declarations of generated code.
+func exportedAPI(filename string, output []byte) ([]string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, output, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, &errSource{Err: err}
+	}
+
+	api := []string{}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if !decl.Name.IsExported() {
+				continue
+			}
+			if decl.Recv != nil && !ast.IsExported(receiverTypeName(decl.Recv)) {
+				continue
+			}
+			fn := *decl
+			fn.Doc, fn.Body = nil, nil
+			api = append(api, nodeString(fset, &fn))
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if !spec.Name.IsExported() {
+						continue
+					}
+					api = append(api, typeAPI(fset, spec)...)
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						if !name.IsExported() {
+							continue
+						}
+						line := decl.Tok.String() + " " + name.Name
+						if spec.Type != nil {
+							line += " " + nodeString(fset, spec.Type)
+						}
+						api = append(api, line)
+					}
+				}
+			}
+		}
+	}
+	return api, nil
+}
+
+// typeAPI lists an exported type declaration with its exported fields or
+// methods.
+func typeAPI(fset *token.FileSet, spec *ast.TypeSpec) []string {
+	var fields *ast.FieldList
+	var api []string
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		api = append(api, "type "+spec.Name.Name+" struct")
+		fields = t.Fields
+	case *ast.InterfaceType:
+		api = append(api, "type "+spec.Name.Name+" interface")
+		fields = t.Methods
+	default:
+		api = append(api, "type "+spec.Name.Name+" "+nodeString(fset, spec.Type))
+		return api
+	}
+	for _, field := range fields.List {
+		typ := nodeString(fset, field.Type)
+		if len(field.Names) == 0 {
+			// embedded
+			if ast.IsExported(strings.TrimPrefix(typ[strings.LastIndex(typ, ".")+1:], "*")) {
+				api = append(api, "    "+typ)
+			}
+			continue
+		}
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+			if _, ok := field.Type.(*ast.FuncType); ok {
+				api = append(api, "    "+name.Name+strings.TrimPrefix(typ, "func"))
+			} else {
+				api = append(api, "    "+name.Name+" "+typ)
+			}
+		}
+	}
+	return api
+}
+
+// receiverTypeName gets the name of the type of a method receiver.
+func receiverTypeName(recv *ast.FieldList) string {
+	if len(recv.List) == 0 {
+		return ""
+	}
+	typ := recv.List[0].Type
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+		case *ast.IndexExpr:
+			typ = t.X
+		case *ast.IndexListExpr:
+			typ = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// TypeSetString gets a typeset in the format TypeSet reads.
+func TypeSetString(typeSet map[string]TypeRef) string {
+	var pairs []string
+	for name, ref := range typeSet {
+		value := ref.Type
+		if ref.Alias != ref.Type || ref.Plural != "" {
+			value = ref.Alias
+			if ref.Plural != "" {
+				value += pluralSep + ref.Plural
+			}
+			value += aliasSep + ref.Type
+		}
+		pairs = append(pairs, name+keyValueSep+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, typeSep)
+}
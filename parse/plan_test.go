@@ -0,0 +1,94 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tehbilly/genny/parse"
+)
+
+func TestPreview(t *testing.T) {
+	in, err := contents("test/queue/generic_queue.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("Something=interface{},Ptr:*time.Time")
+	require.NoError(t, err)
+
+	for _, useAst := range []bool{true, false} {
+		plans, err := parse.Preview("generic_queue.go", strings.NewReader(in), typeSets, parse.Options{UseAst: useAst})
+		require.NoError(t, err)
+		require.Len(t, plans, 2)
+
+		assert.Equal(t, "Something=interface{}", parse.TypeSetString(plans[0].TypeSet))
+		assert.Equal(t, []parse.Rename{
+			{From: "SomethingQueue", To: "InterfaceQueue", Line: 9, Count: 5},
+			{From: "Something", To: "interface{}", Line: 10, Count: 4},
+			{From: "NewSomethingQueue", To: "NewInterfaceQueue", Line: 13, Count: 1},
+		}, plans[0].Renames)
+
+		assert.Equal(t, "Something=Ptr:*time.Time", parse.TypeSetString(plans[1].TypeSet))
+		assert.Equal(t, []string{
+			"type PtrQueue struct",
+			"func NewPtrQueue() *PtrQueue",
+			"func (q *PtrQueue) Push(item *time.Time)",
+			"func (q *PtrQueue) Pop() *time.Time",
+		}, plans[1].API)
+	}
+}
+
+func TestPreviewAPI(t *testing.T) {
+	in, err := contents("test/interface-template/generic.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("TypeParam=string")
+	require.NoError(t, err)
+
+	plans, err := parse.Preview("generic.go", strings.NewReader(in), typeSets, parse.Options{UseAst: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"type PrinterStringInterface interface",
+		"    Print(value string) string",
+		"type PrinterString struct",
+		"func (p *PrinterString) Print(value string) string",
+	}, plans[0].API)
+}
+
+func TestPreviewMissingType(t *testing.T) {
+	in, err := contents("test/queue/generic_queue.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("Other=int")
+	require.NoError(t, err)
+	_, err = parse.Preview("generic_queue.go", strings.NewReader(in), typeSets, parse.Options{})
+	assert.EqualError(t, err, "Missing specific type for 'Something' generic type")
+}
+
+func TestPreviewOptions(t *testing.T) {
+	in, err := contents("test/queue/generic_queue.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("Something=int")
+	require.NoError(t, err)
+
+	for _, useAst := range []bool{true, false} {
+		plans, err := parse.Preview("generic_queue.go", strings.NewReader(in), typeSets, parse.Options{
+			UseAst:      useAst,
+			PackageName: "queues",
+			Placement:   parse.PlaceSuffix,
+			Rename:      parse.RenameOptions{Prefix: "Fast"},
+		})
+		require.NoError(t, err)
+		require.Len(t, plans, 1)
+
+		assert.Equal(t, []parse.Rename{
+			{From: "queue", To: "queues", Line: 1, Count: 1},
+			{From: "SomethingQueue", To: "FastQueueInt", Line: 9, Count: 5},
+			{From: "Something", To: "int", Line: 10, Count: 4},
+			{From: "NewSomethingQueue", To: "FastNewQueueInt", Line: 13, Count: 1},
+		}, plans[0].Renames)
+		assert.Equal(t, []string{
+			"type FastQueueInt struct",
+			"func FastNewQueueInt() *FastQueueInt",
+			"func (q *FastQueueInt) Push(item int)",
+			"func (q *FastQueueInt) Pop() int",
+		}, plans[0].API)
+	}
+}
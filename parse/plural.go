@@ -0,0 +1,80 @@
+package parse
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// irregularPlurals are the English plurals that don't follow the rules of
+// pluralize. More can be given per typeset, as in Child@Children.
+var irregularPlurals = map[string]string{
+	"child":  "children",
+	"datum":  "data",
+	"foot":   "feet",
+	"goose":  "geese",
+	"man":    "men",
+	"mouse":  "mice",
+	"person": "people",
+	"tooth":  "teeth",
+	"woman":  "women",
+}
+
+// pluralize gets the plural of a word, which may be made of several words in
+// camel case, of which the last one is put in the plural: Box becomes
+// Boxes, Entry Entries, UserPerson UserPeople and ID IDs.
+func pluralize(word string) string {
+	if word == "" {
+		return word
+	}
+	last, _ := utf8.DecodeLastRuneInString(word)
+	if !unicode.IsLetter(last) || unicode.IsUpper(last) {
+		// Int64s, IDs
+		return word + "s"
+	}
+
+	start := strings.LastIndexFunc(word, unicode.IsUpper)
+	if start < 0 {
+		start = 0
+	}
+	head, tail := word[:start], word[start:]
+	if plural, ok := irregularPlurals[strings.ToLower(tail)]; ok {
+		return head + casing(plural, startsUpper(tail))
+	}
+
+	lower := strings.ToLower(word)
+	switch {
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return word + "es"
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsAny(lower[len(lower)-2:len(lower)-1], "aeiou"):
+		return word[:len(word)-1] + "ies"
+	}
+	return word + "s"
+}
+
+// replacePlural replaces the plural of a name in s, where it isn't followed
+// by more of a word, with what replace gets for the match.
+func replacePlural(s, plural string, replace func(match string) string) string {
+	var output strings.Builder
+	i := 0
+	for {
+		pos := indexBoundary(s[i:], plural)
+		if pos == -1 {
+			break
+		}
+		pos += i
+		end := pos + len(plural)
+		if next, _ := utf8.DecodeRuneInString(s[end:]); unicode.IsLower(next) {
+			// e.g. Somethingsmith
+			output.WriteString(s[i:end])
+			i = end
+			continue
+		}
+		output.WriteString(s[i:pos])
+		output.WriteString(replace(s[pos:end]))
+		i = end
+	}
+	output.WriteString(s[i:])
+	return output.String()
+}
@@ -0,0 +1,37 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPluralize(t *testing.T) {
+	plurals := map[string]string{
+		"Item":       "Items",
+		"Box":        "Boxes",
+		"Bus":        "Buses",
+		"Match":      "Matches",
+		"Entry":      "Entries",
+		"Key":        "Keys",
+		"Person":     "People",
+		"UserPerson": "UserPeople",
+		"child":      "children",
+		"ID":         "IDs",
+		"Int64":      "Int64s",
+		"":           "",
+	}
+	for word, plural := range plurals {
+		assert.Equal(t, plural, pluralize(word), word)
+	}
+}
+
+func TestReplacePlural(t *testing.T) {
+	replace := func(match string) string { return "<" + match + ">" }
+	assert.Equal(t, "all <Entries> and <entries>", replacePlural("all Entries and entries", "Entries", replace))
+	assert.Equal(t, "<Entries>ByKey", replacePlural("EntriesByKey", "Entries", replace))
+	assert.Equal(t, "Entriesque", replacePlural("Entriesque", "Entries", replace))
+	assert.Equal(t, "Entry", replacePlural("Entry", "Entries", replace))
+	assert.Equal(t, "no plural", replacePlural("no plural", "Entries", strings.ToUpper))
+}
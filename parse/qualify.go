@@ -0,0 +1,180 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// templatePackage is the package a template belongs to. When code is
+// generated into another package, references to the declarations of the
+// template's package have to be qualified with an import of it.
+type templatePackage struct {
+	name string
+	path string
+	// decls are the package level declarations of the package, other than
+	// those of the template itself, and whether they are exported.
+	decls map[string]bool
+}
+
+// loadTemplatePackage reads the declarations of the other files in the
+// directory of the template that belong to its package.
+func loadTemplatePackage(filename string, file *ast.File, importPath string) (*templatePackage, error) {
+	p := &templatePackage{
+		name:  file.Name.Name,
+		path:  importPath,
+		decls: make(map[string]bool),
+	}
+
+	dir := filepath.Dir(filename)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	isTest := strings.HasSuffix(filename, "_test.go")
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, ".go") || name == filepath.Base(filename) {
+			continue
+		}
+		if strings.HasSuffix(name, "_test.go") && !isTest {
+			continue
+		}
+		if match, err := build.Default.MatchFile(dir, name); err != nil || !match {
+			continue
+		}
+		other, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, &errSource{Err: err}
+		}
+		if other.Name.Name != p.name {
+			continue
+		}
+		for _, name := range declNames(other) {
+			p.decls[name] = ast.IsExported(name)
+		}
+	}
+
+	// the template's own declarations are generated along with the code
+	// that uses them
+	for _, name := range declNames(file) {
+		delete(p.decls, name)
+	}
+	return p, nil
+}
+
+// declNames gets the names of the package level declarations of a file.
+func declNames(file *ast.File) []string {
+	var names []string
+	for _, decl := range file.Decls {
+		switch it := decl.(type) {
+		case *ast.FuncDecl:
+			if it.Recv == nil && it.Name.Name != "init" {
+				names = append(names, it.Name.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range it.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, spec.Name.Name)
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						names = append(names, name.Name)
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// qualify qualifies the references in a generated file to the declarations
+// of the template's package, and imports it. It fails if any of them are
+// unexported, since they can't be reached from another package.
+func (p *templatePackage) qualify(output []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", output, 0)
+	if err != nil {
+		// leave it to goimports to report
+		return output, nil
+	}
+
+	var edits []edit
+	unexported := make(map[string]bool)
+	for _, ident := range file.Unresolved {
+		exported, ok := p.decls[ident.Name]
+		if !ok {
+			continue
+		}
+		if !exported {
+			unexported[ident.Name] = true
+			continue
+		}
+		edits = append(edits, insertion(fset.Position(ident.Pos()).Offset, p.name+"."))
+	}
+	if len(unexported) > 0 {
+		var names []string
+		for name := range unexported {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, &errUnexportedReference{Package: p.path, Names: names}
+	}
+	if len(edits) == 0 {
+		return output, nil
+	}
+
+	// the import goes on the line after the package clause
+	importOffset := len(output)
+	end := fset.Position(file.Name.End()).Offset
+	if nl := bytes.IndexByte(output[end:], '\n'); nl >= 0 {
+		importOffset = end + nl + 1
+	}
+	importSpec := strconv.Quote(p.path)
+	if path.Base(p.path) != p.name {
+		importSpec = p.name + " " + importSpec
+	}
+	edits = append(edits, insertion(importOffset, fmt.Sprintf("import %s\n", importSpec)))
+	return applyEdits(output, edits), nil
+}
+
+// edit replaces the source between two offsets with text. Text is inserted
+// if they are the same.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// insertion is an edit that inserts text at an offset.
+func insertion(offset int, text string) edit {
+	return edit{offset, offset, text}
+}
+
+// applyEdits applies edits, which must not overlap, to source. Insertions at
+// the same offset keep their order.
+func applyEdits(source []byte, edits []edit) []byte {
+	if len(edits) == 0 {
+		return source
+	}
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+	var edited bytes.Buffer
+	last := 0
+	for _, e := range edits {
+		edited.Write(source[last:e.start])
+		edited.WriteString(e.text)
+		last = e.end
+	}
+	edited.Write(source[last:])
+	return edited.Bytes()
+}
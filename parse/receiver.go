@@ -0,0 +1,194 @@
+package parse
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// gennyStart is the comment that starts the part of a template that is
+// generated for every typeset. What comes before it is generated once.
+const gennyStart = "//genny:start"
+
+// sharedMethods are the methods that are generated for every typeset on a
+// receiver that is generated once, i.e. declared after `//genny:start` on a
+// type declared before it. Those whose names don't refer to a generic type
+// would be declared again by every typeset, so they get the words of the
+// specific types appended to their names.
+type sharedMethods struct {
+	receivers map[string]bool
+	// methods maps the names of the methods that have to be renamed to the
+	// generic types they refer to, in the order they are declared.
+	methods map[string][]string
+}
+
+// findSharedMethods finds the shared methods of a template, or gets nil if
+// there are none.
+func findSharedMethods(t *template) *sharedMethods {
+	start := gennyStartPos(t.file)
+	if !start.IsValid() {
+		return nil
+	}
+
+	s := &sharedMethods{receivers: make(map[string]bool), methods: make(map[string][]string)}
+	generic := make(map[string]bool)
+	for _, name := range t.genericTypes {
+		generic[name] = true
+	}
+	for _, decl := range t.file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Pos() > start {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			if ts, ok := spec.(*ast.TypeSpec); ok && !generic[ts.Name.Name] {
+				s.receivers[ts.Name.Name] = true
+			}
+		}
+	}
+
+	for _, decl := range t.file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv == nil || fd.Pos() < start || !s.receivers[receiverTypeName(fd.Recv)] {
+			continue
+		}
+		if refersToGenericType(fd.Name.Name, t.genericTypes) {
+			continue
+		}
+		used := make(map[string]bool)
+		ast.Inspect(fd, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok && generic[ident.Name] {
+				used[ident.Name] = true
+			}
+			return true
+		})
+		var refs []string
+		for _, name := range t.genericTypes {
+			if used[name] || len(used) == 0 {
+				refs = append(refs, name)
+			}
+		}
+		s.methods[fd.Name.Name] = refs
+	}
+	if len(s.methods) == 0 {
+		return nil
+	}
+	return s
+}
+
+// gennyStartPos gets the position of the `//genny:start` comment, if any.
+func gennyStartPos(file *ast.File) token.Pos {
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if strings.HasPrefix(comment.Text, gennyStart) {
+				return comment.Pos()
+			}
+		}
+	}
+	return token.NoPos
+}
+
+// refersToGenericType gets whether substituting the generic types would
+// change a name.
+func refersToGenericType(name string, genericTypes []string) bool {
+	for _, gt := range genericTypes {
+		if newMatcher(gt).exact.MatchString(name) || containsBoundary(name, gt) {
+			return true
+		}
+	}
+	return false
+}
+
+// rename appends the words of the specific types to the names of the shared
+// methods in the code generated for a typeset: their declarations, the first
+// word of their doc comments and the selectors that call them. Selectors of
+// methods of other types with the same names, such as those of fields, are
+// left alone.
+func (s *sharedMethods) rename(output []byte, words map[string]string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", output, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		// leave it to goimports to report
+		return output, nil
+	}
+	start := gennyStartPos(file)
+	shared := s.sharedSelections(fset, file)
+
+	suffix := func(name string) string {
+		return s.suffix(name, words)
+	}
+	var edits []edit
+	offset := func(pos token.Pos) int {
+		return fset.Position(pos).Offset
+	}
+	for _, decl := range file.Decls {
+		if decl.Pos() < start {
+			continue
+		}
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv != nil && s.receivers[receiverTypeName(fd.Recv)] {
+			if _, ok := s.methods[fd.Name.Name]; ok {
+				edits = append(edits, insertion(offset(fd.Name.End()), suffix(fd.Name.Name)))
+				if fd.Doc != nil && strings.HasPrefix(fd.Doc.List[0].Text, "// "+fd.Name.Name+" ") {
+					edits = append(edits, insertion(offset(fd.Doc.Pos())+len("// "+fd.Name.Name), suffix(fd.Name.Name)))
+				}
+			}
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok && shared[sel] {
+				if _, ok := s.methods[sel.Sel.Name]; ok {
+					edits = append(edits, insertion(offset(sel.Sel.End()), suffix(sel.Sel.Name)))
+				}
+			}
+			return true
+		})
+	}
+	return applyEdits(output, edits), nil
+}
+
+// suffix gets what is appended to the name of a shared method, the words of
+// the specific types of the generic types it refers to.
+func (s *sharedMethods) suffix(name string, words map[string]string) string {
+	var suffixes []string
+	for _, gt := range s.methods[name] {
+		suffixes = append(suffixes, words[gt])
+	}
+	return strings.Join(suffixes, "")
+}
+
+// sharedSelections type checks the code generated for a typeset to find the
+// selectors of the methods of the shared receivers. Other packages aren't
+// imported, so the selectors of their types are never among them.
+func (s *sharedMethods) sharedSelections(fset *token.FileSet, file *ast.File) map[*ast.SelectorExpr]bool {
+	info := &types.Info{Selections: make(map[*ast.SelectorExpr]*types.Selection)}
+	conf := types.Config{Importer: noImporter{}, Error: func(error) {}}
+	conf.Check(file.Name.Name, fset, []*ast.File{file}, info)
+
+	shared := make(map[*ast.SelectorExpr]bool)
+	for sel, selection := range info.Selections {
+		if selection.Kind() == types.FieldVal {
+			continue
+		}
+		recv := selection.Obj().(*types.Func).Type().(*types.Signature).Recv()
+		if recv == nil {
+			continue
+		}
+		typ := recv.Type()
+		if ptr, ok := typ.(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+		if named, ok := typ.(*types.Named); ok && named.Obj().Parent() == named.Obj().Pkg().Scope() && s.receivers[named.Obj().Name()] {
+			shared[sel] = true
+		}
+	}
+	return shared
+}
+
+// noImporter fails to import any package.
+type noImporter struct{}
+
+func (noImporter) Import(path string) (*types.Package, error) {
+	return nil, fmt.Errorf("%s is not imported", path)
+}
@@ -0,0 +1,74 @@
+package parse
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindSharedMethods(t *testing.T) {
+	source, err := ioutil.ReadFile("test/bugreports/receiver_generic.go")
+	require.NoError(t, err)
+	typeSets, err := TypeSet("TA=string TB=int,bool")
+	require.NoError(t, err)
+	tmpl, err := newTemplate("receiver_generic.go", source, typeSets)
+	require.NoError(t, err)
+
+	require.NotNil(t, tmpl.shared)
+	assert.Equal(t, map[string]bool{"Receiver": true}, tmpl.shared.receivers)
+	assert.Equal(t, map[string][]string{"Len": {"TB"}, "First": {"TA", "TB"}}, tmpl.shared.methods)
+
+	// with a single typeset the names are left alone
+	out, err := Generate("receiver_generic.go", strings.NewReader(string(source)), typeSets[:1], Options{StripTags: []string{"genny"}})
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "func (Receiver) Len(values []int) int {")
+	assert.Contains(t, string(out), "func (r Receiver) First(values []string) (first int, ok bool) {")
+
+	// templates without //genny:start have none
+	source, err = ioutil.ReadFile("test/queue/generic_queue.go")
+	require.NoError(t, err)
+	tmpl, err = newTemplate("generic_queue.go", source, nil)
+	require.NoError(t, err)
+	assert.Nil(t, tmpl.shared)
+}
+
+func TestSharedMethodsOfFields(t *testing.T) {
+	source := `package p
+
+import (
+	"bytes"
+
+	"github.com/tehbilly/genny/generic"
+)
+
+// Receiver is generated once.
+type Receiver struct {
+	buf   bytes.Buffer
+	inner *Receiver
+}
+
+//genny:start
+
+type Item generic.Type
+
+// Len gets the length of the buffer.
+func (r *Receiver) Len(values []Item) int {
+	if r.inner != nil {
+		return r.inner.Len(values)
+	}
+	return r.buf.Len()
+}
+`
+	typeSets, err := TypeSet("Item=int,string")
+	require.NoError(t, err)
+	for _, useAst := range []bool{true, false} {
+		out, err := Generate("receiver.go", strings.NewReader(source), typeSets, Options{UseAst: useAst})
+		require.NoError(t, err)
+		assert.Contains(t, string(out), "func (r *Receiver) LenInt(values []int) int {\n\tif r.inner != nil {\n\t\treturn r.inner.LenInt(values)\n\t}\n\treturn r.buf.Len()\n}", "ast: %v", useAst)
+		assert.Contains(t, string(out), "return r.inner.LenString(values)", "ast: %v", useAst)
+		assert.NotContains(t, string(out), "buf.LenInt", "ast: %v", useAst)
+	}
+}
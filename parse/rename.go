@@ -0,0 +1,206 @@
+package parse
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// RenameOptions renames the top-level declarations of the output, along with
+// every reference to them and their mentions in comments, e.g. so that a
+// template can be generated into a package that already has declarations of
+// the same names. Methods, fields, init and the functions that go test runs
+// keep their names.
+type RenameOptions struct {
+	// Prefix and Suffix are added to the names, so that the prefix Fast
+	// turns IntQueue into FastIntQueue and intQueue into fastIntQueue.
+	Prefix string
+	Suffix string
+	// Export exports the declarations that are not, and Unexport unexports
+	// those that are. The names keep their initialisms in one case, so
+	// idIndex becomes IDIndex.
+	Export   bool
+	Unexport bool
+	// Declared are the names, already renamed, of the top-level declarations
+	// in the other files generated into the package, such as the file a test
+	// file goes with. References to them are renamed too.
+	Declared []string
+}
+
+// Enabled gets whether the options rename anything.
+func (o RenameOptions) Enabled() bool {
+	return o.Prefix != "" || o.Suffix != "" || o.Export || o.Unexport
+}
+
+// Check gets an error if the options can't be used.
+func (o RenameOptions) Check() error {
+	if o.Export && o.Unexport {
+		return &errBadTypeArgs{Arg: "export", Message: "declarations can't be both exported and unexported"}
+	}
+	for _, affix := range []string{o.Prefix, o.Suffix} {
+		if strings.IndexFunc(affix, func(r rune) bool { return !isAlphaNumeric(r) }) >= 0 {
+			return &errBadTypeArgs{Arg: affix, Message: "a prefix or suffix has to be made of letters, digits and underscores"}
+		}
+	}
+	return nil
+}
+
+// newName gets the new name of a top-level declaration.
+func (o RenameOptions) newName(name string) string {
+	exported := isExported(name)
+	if o.Export {
+		exported = true
+	} else if o.Unexport {
+		exported = false
+	}
+	if o.Prefix != "" {
+		name = o.Prefix + upperFirst(name)
+	}
+	if o.Suffix != "" {
+		name += upperFirst(o.Suffix)
+	}
+	return casing(name, exported)
+}
+
+// keepsName gets whether a top-level declaration keeps its name.
+func keepsName(filename string, obj *ast.Object) bool {
+	if obj.Name == "_" || obj.Name == "init" || obj.Name == "main" {
+		return true
+	}
+	if obj.Kind != ast.Fun || !strings.HasSuffix(filename, "_test.go") {
+		return false
+	}
+	for _, prefix := range []string{"Test", "Benchmark", "Example", "Fuzz"} {
+		if rest := strings.TrimPrefix(obj.Name, prefix); rest != obj.Name && !startsLower(rest) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply renames the top-level declarations of the source, which is left as
+// it is if it can't be parsed, for formatting to report the error.
+func (o RenameOptions) apply(filename string, source []byte) ([]byte, error) {
+	if !o.Enabled() {
+		return source, nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, source, parser.ParseComments)
+	if err != nil {
+		return source, nil
+	}
+	renames, err := o.declRenames(filename, file)
+	if err != nil {
+		return nil, err
+	}
+	declared := make(map[string]bool, len(o.Declared))
+	for _, name := range o.Declared {
+		declared[name] = true
+	}
+
+	var edits []edit
+	rename := func(ident *ast.Ident, newName string) {
+		offset := fset.Position(ident.Pos()).Offset
+		edits = append(edits, edit{offset, offset + len(ident.Name), newName})
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok || ident.Obj == nil || file.Scope.Lookup(ident.Name) != ident.Obj {
+			return true
+		}
+		if newName, ok := renames[ident.Name]; ok {
+			rename(ident, newName)
+		}
+		return true
+	})
+	for _, ident := range file.Unresolved {
+		if newName := o.newName(ident.Name); declared[newName] {
+			renames[ident.Name] = newName
+			rename(ident, newName)
+		}
+	}
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			offset := fset.Position(comment.Pos()).Offset
+			for _, loc := range reWord.FindAllStringIndex(comment.Text, -1) {
+				if newName, ok := renames[comment.Text[loc[0]:loc[1]]]; ok {
+					edits = append(edits, edit{offset + loc[0], offset + loc[1], newName})
+				}
+			}
+		}
+	}
+	return applyEdits(source, edits), nil
+}
+
+// renames gets the new names of the top-level declarations of the source,
+// or nil if it can't be parsed.
+func (o RenameOptions) renames(filename string, source []byte) (map[string]string, error) {
+	if !o.Enabled() {
+		return nil, nil
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), filename, source, 0)
+	if err != nil {
+		return nil, nil
+	}
+	return o.declRenames(filename, file)
+}
+
+// declRenames gets the new names of the top-level declarations of a file,
+// or an error if two of them would get the same name.
+func (o RenameOptions) declRenames(filename string, file *ast.File) (map[string]string, error) {
+	var names []string
+	for name := range file.Scope.Objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	renames := make(map[string]string)
+	taken := make(map[string]string)
+	for _, name := range names {
+		newName := name
+		if !keepsName(filename, file.Scope.Objects[name]) {
+			newName = o.newName(name)
+			renames[name] = newName
+		}
+		if other, ok := taken[newName]; ok {
+			if newName == name {
+				name, other = other, name
+			}
+			return nil, &errRenameConflict{Name: name, Other: other, New: newName}
+		}
+		taken[newName] = name
+	}
+	return renames, nil
+}
+
+// DeclaredNames gets the names of the top-level declarations of a Go source
+// file, such as one generated with RenameOptions, for the Declared names of
+// the files generated with it.
+func DeclaredNames(filename string, source []byte) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), filename, source, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, &errSource{Err: err}
+	}
+	var names []string
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				names = append(names, d.Name.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, s.Name.Name)
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						names = append(names, name.Name)
+					}
+				}
+			}
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,105 @@
+package parse_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tehbilly/genny/parse"
+)
+
+const renameTemplate = `package rename
+
+import "github.com/tehbilly/genny/generic"
+
+type Item generic.Type
+
+// ItemQueue is a queue made with newItemQueue.
+type ItemQueue struct {
+	items []Item
+}
+
+func newItemQueue() *ItemQueue {
+	return &ItemQueue{}
+}
+
+// Len gets the number of items.
+func (q *ItemQueue) Len() int {
+	ItemQueue := len(q.items)
+	return ItemQueue
+}
+
+var itemIDs = map[string]*ItemQueue{}
+`
+
+func TestGenerateRename(t *testing.T) {
+	typeSets, err := parse.TypeSet("Item=int")
+	require.NoError(t, err)
+	renames := []struct {
+		rename   parse.RenameOptions
+		contains []string
+	}{
+		{parse.RenameOptions{Prefix: "fast"}, []string{
+			"// FastIntQueue is a queue made with fastNewIntQueue.\ntype FastIntQueue struct {",
+			"func fastNewIntQueue() *FastIntQueue {\n\treturn &FastIntQueue{}",
+			"func (q *FastIntQueue) Len() int {\n\tIntQueue := len(q.ints)\n\treturn IntQueue",
+			"var fastIntIDs = map[string]*FastIntQueue{}",
+		}},
+		{parse.RenameOptions{Suffix: "v2", Unexport: true}, []string{
+			"type intQueueV2 struct {", "func newIntQueueV2() *intQueueV2 {", "var intIDsV2 =",
+		}},
+		{parse.RenameOptions{Export: true}, []string{
+			"type IntQueue struct {", "func NewIntQueue() *IntQueue {", "var IntIDs =",
+		}},
+	}
+	for _, r := range renames {
+		out, err := parse.Generate("rename.go", strings.NewReader(renameTemplate), typeSets, parse.Options{Rename: r.rename})
+		require.NoError(t, err)
+		for _, s := range r.contains {
+			assert.Contains(t, string(out), s, "%+v", r.rename)
+		}
+	}
+}
+
+func TestGenerateRenameDeclared(t *testing.T) {
+	typeSets, err := parse.TypeSet("Item=int")
+	require.NoError(t, err)
+	rename := parse.RenameOptions{Prefix: "Fast"}
+	out, err := parse.Generate("rename.go", strings.NewReader(renameTemplate), typeSets, parse.Options{Rename: rename})
+	require.NoError(t, err)
+	rename.Declared, err = parse.DeclaredNames("rename.go", out)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"FastIntQueue", "fastNewIntQueue", "fastIntIDs"}, rename.Declared)
+
+	test := `package rename
+
+import "testing"
+
+func TestItemQueue(t *testing.T) {
+	if newItemQueue().Len() != 0 {
+		t.Error("a new ItemQueue should be empty")
+	}
+}
+`
+	out, err = parse.Generate("rename_test.go", strings.NewReader(test), typeSets, parse.Options{Rename: rename})
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "func TestIntQueue(t *testing.T) {\n\tif fastNewIntQueue().Len() != 0 {")
+}
+
+func TestGenerateRenameErrors(t *testing.T) {
+	typeSets, err := parse.TypeSet("Item=int")
+	require.NoError(t, err)
+	conflict := renameTemplate + "\ntype intQueue struct{}\n"
+	_, err = parse.Generate("rename.go", strings.NewReader(conflict), typeSets, parse.Options{Rename: parse.RenameOptions{Unexport: true}})
+	assert.EqualError(t, err, "Cannot rename IntQueue to intQueue: it is the name of intQueue too")
+
+	for _, rename := range []parse.RenameOptions{{Export: true, Unexport: true}, {Prefix: "my-"}} {
+		_, err = parse.Generate("rename.go", strings.NewReader(renameTemplate), typeSets, parse.Options{Rename: rename})
+		assert.Error(t, err, "%+v", rename)
+	}
+
+	err = parse.GenerateTo(&bytes.Buffer{}, "rename.go", strings.NewReader(renameTemplate), typeSets, parse.Options{Rename: parse.RenameOptions{Prefix: "Fast"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,394 @@
+package parse
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	samplesDirective = "//genny:samples"
+	sampleDirective  = "//genny:sample "
+	samplerDirective = "//genny:sampler "
+)
+
+// SampleSource provides sample values for specific types. The values are
+// Go expressions, written as they would appear in source code, and are used
+// to fill in `//genny:samples` declarations when generating tests.
+type SampleSource interface {
+	// Samples gets the sample values for the specific type, or nil if the
+	// source has none.
+	Samples(specificType string) []string
+}
+
+// SampleMap is a SampleSource backed by a map of specific types to their
+// sample values.
+type SampleMap map[string][]string
+
+// Samples gets the sample values for the specific type.
+func (m SampleMap) Samples(specificType string) []string {
+	return m[specificType]
+}
+
+// Samples combines sample sources. The first source that has samples for a
+// type wins.
+type Samples []SampleSource
+
+// Samples gets the sample values for the specific type from the first
+// source that has any.
+func (s Samples) Samples(specificType string) []string {
+	for _, source := range s {
+		if source == nil {
+			continue
+		}
+		if samples := source.Samples(specificType); len(samples) > 0 {
+			return samples
+		}
+	}
+	return nil
+}
+
+// BuiltinSamples contains sample values for every type in Builtins: five
+// different ones, except for bool, which has only two. A template that uses
+// more different samples of a type than it has fails to generate.
+var BuiltinSamples = SampleMap{
+	"bool":       {"true", "false"},
+	"byte":       {"1", "2", "3", "4", "5"},
+	"complex128": {"complex(1, 1)", "complex(2, 2)", "complex(3, 3)", "complex(4, 4)", "complex(5, 5)"},
+	"complex64":  {"complex(1, 1)", "complex(2, 2)", "complex(3, 3)", "complex(4, 4)", "complex(5, 5)"},
+	"error":      {`errors.New("a")`, `errors.New("b")`, `errors.New("c")`, `errors.New("d")`, `errors.New("e")`},
+	"float32":    {"1.5", "2.5", "3.5", "4.5", "5.5"},
+	"float64":    {"1.5", "2.5", "3.5", "4.5", "5.5"},
+	"int":        {"1", "2", "3", "4", "5"},
+	"int16":      {"1", "2", "3", "4", "5"},
+	"int32":      {"1", "2", "3", "4", "5"},
+	"int64":      {"1", "2", "3", "4", "5"},
+	"int8":       {"1", "2", "3", "4", "5"},
+	"rune":       {"'a'", "'b'", "'c'", "'d'", "'e'"},
+	"string":     {`"a"`, `"b"`, `"c"`, `"d"`, `"e"`},
+	"uint":       {"1", "2", "3", "4", "5"},
+	"uint16":     {"1", "2", "3", "4", "5"},
+	"uint32":     {"1", "2", "3", "4", "5"},
+	"uint64":     {"1", "2", "3", "4", "5"},
+	"uint8":      {"1", "2", "3", "4", "5"},
+	"uintptr":    {"1", "2", "3", "4", "5"},
+}
+
+// sampleEdit replaces the source between start and end with text.
+type sampleEdit struct {
+	start, end int
+	text       string
+}
+
+// sampleList is a `//genny:samples` declaration found in a template. Its
+// elements are replaced with samples of the specific type that replaces
+// genericType.
+type sampleList struct {
+	genericType string
+	start, end  int
+	count       int
+}
+
+// sampleCall is a call to generic.Sample or generic.Zero found in a
+// template. It is replaced with a sample or the zero value of the specific
+// type that replaces typeName.
+type sampleCall struct {
+	typeName   string
+	index      string
+	zero       bool
+	inHeader   bool
+	start, end int
+}
+
+// sampleTemplate is a template that contains sample declarations.
+type sampleTemplate struct {
+	source   []byte
+	declared SampleMap
+	// samplers are the types marked with `//genny:sampler`, which implement
+	// generic.Sampler.
+	samplers map[string]bool
+	lists    []sampleList
+	calls    []sampleCall
+	removed  []sampleEdit
+}
+
+// newSampleTemplate scans the source for `//genny:sample` directives,
+// `//genny:samples` declarations and calls to generic.Sample and
+// generic.Zero. It returns nil if the template uses none of them.
+func newSampleTemplate(filename string, source []byte) (*sampleTemplate, error) {
+	if !strings.Contains(string(source), "//genny:sample") &&
+		!strings.Contains(string(source), "generic.Sample[") &&
+		!strings.Contains(string(source), "generic.Zero[") {
+		return nil, nil
+	}
+	fs := token.NewFileSet()
+	file, err := parser.ParseFile(fs, filename, source, parser.ParseComments)
+	if err != nil {
+		return nil, &errSource{Err: err}
+	}
+	offset := func(p token.Pos) int {
+		return fs.Position(p).Offset
+	}
+
+	st := &sampleTemplate{source: source, declared: SampleMap{}, samplers: make(map[string]bool)}
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			switch {
+			case strings.HasPrefix(c.Text, sampleDirective):
+				typ, samples, err := parseSampleDirective(c.Text)
+				if err != nil {
+					return nil, err
+				}
+				st.declared[typ] = append(st.declared[typ], samples...)
+			case strings.HasPrefix(c.Text, samplerDirective):
+				for _, typ := range strings.Fields(strings.TrimPrefix(c.Text, samplerDirective)) {
+					st.samplers[typ] = true
+				}
+			case strings.TrimSpace(c.Text) != samplesDirective:
+				continue
+			}
+			st.removed = append(st.removed, sampleEdit{start: offset(c.Pos()), end: offset(c.End())})
+		}
+	}
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			if !hasSamplesDirective(gen.Doc) && !hasSamplesDirective(vs.Doc) {
+				continue
+			}
+			for _, value := range vs.Values {
+				lit, ok := value.(*ast.CompositeLit)
+				if !ok {
+					return nil, &errBadSamples{Message: "samples must be declared with a slice or array literal"}
+				}
+				arr, ok := lit.Type.(*ast.ArrayType)
+				if !ok {
+					return nil, &errBadSamples{Message: "samples must be declared with a slice or array literal"}
+				}
+				elt, ok := arr.Elt.(*ast.Ident)
+				if !ok {
+					return nil, &errBadSamples{Message: "samples must be a slice of a generic type"}
+				}
+				st.lists = append(st.lists, sampleList{
+					genericType: elt.Name,
+					start:       offset(lit.Lbrace) + 1,
+					end:         offset(lit.Rbrace),
+					count:       len(lit.Elts),
+				})
+			}
+		}
+	}
+
+	// composite literals need parentheses in the headers of if, for and
+	// switch statements
+	var headers []sampleEdit
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch stmt := n.(type) {
+		case *ast.IfStmt:
+			headers = append(headers, sampleEdit{start: offset(stmt.Pos()), end: offset(stmt.Body.Lbrace)})
+		case *ast.ForStmt:
+			headers = append(headers, sampleEdit{start: offset(stmt.Pos()), end: offset(stmt.Body.Lbrace)})
+		case *ast.RangeStmt:
+			headers = append(headers, sampleEdit{start: offset(stmt.Pos()), end: offset(stmt.Body.Lbrace)})
+		case *ast.SwitchStmt:
+			headers = append(headers, sampleEdit{start: offset(stmt.Pos()), end: offset(stmt.Body.Lbrace)})
+		}
+		return true
+	})
+	inHeader := func(pos int) bool {
+		for _, h := range headers {
+			if pos >= h.start && pos < h.end {
+				return true
+			}
+		}
+		return false
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		index, ok := call.Fun.(*ast.IndexExpr)
+		if !ok {
+			return true
+		}
+		selector, ok := index.X.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if pkg, ok := selector.X.(*ast.Ident); !ok || pkg.Name != genericPackage {
+			return true
+		}
+		sc := sampleCall{
+			typeName: string(source[offset(index.Index.Pos()):offset(index.Index.End())]),
+			inHeader: inHeader(offset(call.Pos())),
+			start:    offset(call.Pos()),
+			end:      offset(call.End()),
+		}
+		switch {
+		case selector.Sel.Name == "Zero" && len(call.Args) == 0:
+			sc.zero = true
+		case selector.Sel.Name == "Sample" && len(call.Args) == 1:
+			sc.index = string(source[offset(call.Args[0].Pos()):offset(call.Args[0].End())])
+		default:
+			return true
+		}
+		st.calls = append(st.calls, sc)
+		return false
+	})
+	return st, nil
+}
+
+// hasSamplesDirective gets whether the comment group contains the
+// `//genny:samples` directive.
+func hasSamplesDirective(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == samplesDirective {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSampleDirective parses a directive that looks like
+//
+//	//genny:sample MyType MyType{1}, MyType{2}
+func parseSampleDirective(text string) (string, []string, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(text, sampleDirective))
+	sepIdx := strings.IndexAny(rest, " \t")
+	if sepIdx < 0 {
+		return "", nil, &errBadSamples{Message: "expected a type followed by sample values: " + text}
+	}
+	typ, values := rest[:sepIdx], strings.TrimSpace(rest[sepIdx:])
+	src := "[]sample{" + values + "}"
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		return "", nil, &errBadSamples{Message: "bad sample values: " + err.Error()}
+	}
+	var samples []string
+	for _, elt := range expr.(*ast.CompositeLit).Elts {
+		samples = append(samples, src[elt.Pos()-1:elt.End()-1])
+	}
+	return typ, samples, nil
+}
+
+// apply fills in the sample declarations and calls for the typeSet,
+// preferring samples declared in the template over the given source. Types
+// without samples have to be marked with `//genny:sampler`, as implementing
+// generic.Sampler.
+func (st *sampleTemplate) apply(typeSet map[string]TypeRef, source SampleSource) ([]byte, error) {
+	edits := append([]sampleEdit(nil), st.removed...)
+	samples := Samples{st.declared, source}
+	for _, list := range st.lists {
+		specific, ok := typeSet[list.genericType]
+		if !ok {
+			continue
+		}
+		available := samples.Samples(specific.Type)
+		if len(available) == 0 && !st.samplers[specific.Type] {
+			return nil, errNoSamples(specific.Type)
+		}
+		if len(available) > 0 && list.count > len(available) {
+			return nil, errFewSamples(specific.Type, len(available), list.count)
+		}
+		values := make([]string, list.count)
+		for i := range values {
+			if len(available) == 0 {
+				values[i] = samplerCall(specific.Type, strconv.Itoa(i))
+			} else {
+				values[i] = available[i%len(available)]
+			}
+		}
+		edits = append(edits, sampleEdit{start: list.start, end: list.end, text: strings.Join(values, ", ")})
+	}
+	for _, call := range st.calls {
+		typ := call.typeName
+		if specific, ok := typeSet[typ]; ok {
+			typ = specific.Type
+		}
+		var text string
+		if call.zero {
+			text = zeroValue(typ)
+		} else {
+			available := samples.Samples(typ)
+			if len(available) == 0 && !st.samplers[typ] {
+				return nil, errNoSamples(typ)
+			}
+			if i, err := strconv.Atoi(call.index); err == nil && len(available) > 0 && i >= len(available) {
+				return nil, errFewSamples(typ, len(available), i+1)
+			}
+			text = sampleValue(typ, call.index, available)
+		}
+		if call.inHeader && strings.Contains(text, "{") {
+			text = "(" + text + ")"
+		}
+		edits = append(edits, sampleEdit{start: call.start, end: call.end, text: text})
+	}
+
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	output := append([]byte(nil), st.source...)
+	for _, edit := range edits {
+		output = append(output[:edit.start], append([]byte(edit.text), output[edit.end:]...)...)
+	}
+	return output, nil
+}
+
+// sampleValue gets the expression that replaces a call to generic.Sample
+// with the given index. A computed index goes round the samples.
+func sampleValue(typ, index string, available []string) string {
+	if len(available) == 0 {
+		return samplerCall(typ, index)
+	}
+	if i, err := strconv.Atoi(index); err == nil && i >= 0 {
+		return available[i%len(available)]
+	}
+	return fmt.Sprintf("[]%s{%s}[(%s)%%%d]", typ, strings.Join(available, ", "), index, len(available))
+}
+
+// errNoSamples is the error for a type that has no samples and isn't marked
+// as a generic.Sampler.
+func errNoSamples(typ string) error {
+	return &errBadSamples{Message: "no samples for type '" + typ + "'; declare them with " + strings.TrimSpace(sampleDirective) + ", or mark a type that implements generic.Sampler with " + strings.TrimSpace(samplerDirective)}
+}
+
+// errFewSamples is the error for a type that has fewer samples than are
+// needed, which would repeat them.
+func errFewSamples(typ string, have, need int) error {
+	return &errBadSamples{Message: fmt.Sprintf("type '%s' has %d different samples but %d are used; declare more with %s if it has more values", typ, have, need, strings.TrimSpace(sampleDirective))}
+}
+
+// samplerCall gets the expression that calls generic.Sampler on a type.
+func samplerCall(typ, index string) string {
+	return fmt.Sprintf("(*new(%s)).Sample(%s)", typ, index)
+}
+
+// zeroValue gets the expression that replaces a call to generic.Zero.
+func zeroValue(typ string) string {
+	switch typ {
+	case "bool":
+		return "false"
+	case "string":
+		return `""`
+	case "error":
+		return "nil"
+	}
+	for _, number := range append(Numbers, "byte", "rune", "uintptr", "complex64", "complex128") {
+		if typ == number {
+			return "0"
+		}
+	}
+	return "*new(" + typ + ")"
+}
@@ -0,0 +1,104 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tehbilly/genny/parse"
+)
+
+const samplesTemplate = `package samples
+
+import "testing"
+
+//genny:samples
+var keySamples = []Key{1, 2, 3}
+
+func TestKey(t *testing.T) {}
+`
+
+func TestSamplesFromSource(t *testing.T) {
+	out, err := parse.Generate("samples_test.go", strings.NewReader(samplesTemplate),
+		[]map[string]parse.TypeRef{{"Key": parse.TypeRef{Alias: "Score", Type: "Score"}}},
+		parse.Options{Samples: parse.SampleMap{"Score": {"Score(7)", "Score(8)", "Score(9)", "Score(10)"}}})
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "var scoreSamples = []Score{Score(7), Score(8), Score(9)}")
+}
+
+func TestSamplesBuiltins(t *testing.T) {
+	out, err := parse.Generate("samples_test.go", strings.NewReader(samplesTemplate),
+		[]map[string]parse.TypeRef{{"Key": parse.TypeRef{Alias: "int", Type: "int"}}},
+		parse.Options{})
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "var intSamples = []int{1, 2, 3}")
+}
+
+func TestSamplesTooFew(t *testing.T) {
+	// bool has only two values, so three different samples can't be had
+	_, err := parse.Generate("samples_test.go", strings.NewReader(samplesTemplate),
+		[]map[string]parse.TypeRef{{"Key": parse.TypeRef{Alias: "bool", Type: "bool"}}},
+		parse.Options{})
+	assert.EqualError(t, err, "Bad samples: type 'bool' has 2 different samples but 3 are used; declare more with //genny:sample if it has more values")
+
+	template := strings.Replace(sampleCallsTemplate, "generic.Sample[Key](1)", "generic.Sample[Key](4)", 1)
+	_, err = parse.Generate("samples_test.go", strings.NewReader(template),
+		[]map[string]parse.TypeRef{{"Key": parse.TypeRef{Alias: "Point", Type: "Point"}}},
+		parse.Options{Samples: parse.SampleMap{"Point": {"Point{1, 2}", "Point{3, 4}"}}})
+	assert.EqualError(t, err, "Bad samples: type 'Point' has 2 different samples but 5 are used; declare more with //genny:sample if it has more values")
+}
+
+func TestSamplesMissing(t *testing.T) {
+	_, err := parse.Generate("samples_test.go", strings.NewReader(samplesTemplate),
+		[]map[string]parse.TypeRef{{"Key": parse.TypeRef{Alias: "MyType", Type: "MyType"}}},
+		parse.Options{})
+	assert.EqualError(t, err, "Bad samples: no samples for type 'MyType'; declare them with //genny:sample, or mark a type that implements generic.Sampler with //genny:sampler")
+
+	_, err = parse.Generate("samples_test.go", strings.NewReader(sampleCallsTemplate),
+		[]map[string]parse.TypeRef{{"Key": parse.TypeRef{Alias: "Point", Type: "Point"}}},
+		parse.Options{})
+	assert.EqualError(t, err, "Bad samples: no samples for type 'Point'; declare them with //genny:sample, or mark a type that implements generic.Sampler with //genny:sampler")
+}
+
+func TestSamplesSampler(t *testing.T) {
+	template := strings.Replace(samplesTemplate, "//genny:samples\n", "//genny:sampler MyType\n\n//genny:samples\n", 1)
+	out, err := parse.Generate("samples_test.go", strings.NewReader(template),
+		[]map[string]parse.TypeRef{{"Key": parse.TypeRef{Alias: "MyType", Type: "MyType"}}},
+		parse.Options{})
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "var myTypeSamples = []MyType{(*new(MyType)).Sample(0), (*new(MyType)).Sample(1), (*new(MyType)).Sample(2)}")
+	assert.NotContains(t, string(out), "genny:sampler")
+}
+
+const sampleCallsTemplate = `package samples
+
+import (
+	"testing"
+
+	"github.com/tehbilly/genny/generic"
+)
+
+//genny:sampler MyType
+
+func TestKey(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		use(generic.Sample[Key](i), generic.Sample[Key](1), generic.Zero[Key]())
+	}
+}
+`
+
+func TestSampleCalls(t *testing.T) {
+	for specific, expected := range map[string]string{
+		"int":    `use([]int{1, 2, 3, 4, 5}[(i)%5], 2, 0)`,
+		"string": `use([]string{"a", "b", "c", "d", "e"}[(i)%5], "b", "")`,
+		"MyType": `use((*new(MyType)).Sample(i), (*new(MyType)).Sample(1), *new(MyType))`,
+	} {
+		out, err := parse.Generate("samples_test.go", strings.NewReader(sampleCallsTemplate),
+			[]map[string]parse.TypeRef{{"Key": parse.TypeRef{Alias: specific, Type: specific}}},
+			parse.Options{})
+		require.NoError(t, err)
+		assert.Contains(t, string(out), expected)
+		assert.NotContains(t, string(out), "generic")
+	}
+}
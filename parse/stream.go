@@ -0,0 +1,307 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GenerateTo is like Generate, but writes the output to w as it is
+// generated instead of returning it. Each instantiation is written as soon
+// as it is ready and is formatted one declaration at a time, so memory use
+// stays bounded no matter how many typesets are generated.
+//
+// Unlike Generate, the imports are worked out before any code is generated:
+// the output imports everything the template imports, except the generic
+// package, plus the packages of the specific types.
+func GenerateTo(w io.Writer, filename string, in io.ReadSeeker, typeSets []map[string]TypeRef, opts Options) error {
+	if opts.Rename.Enabled() {
+		return &errBadTypeArgs{Arg: "rename", Message: "declarations can't be renamed when the output is streamed"}
+	}
+	samples := opts.sampleSource()
+
+	typeSets, err := withConstants(typeSets, opts.Constants)
+	if err != nil {
+		return err
+	}
+
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	source, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	tmpl, sampleTmpl, err := prepareTemplate(filename, source, typeSets, opts)
+	if err != nil {
+		return err
+	}
+	var typeImports []string
+	if !opts.Format.FormatOnly {
+		types := make([]map[string]TypeRef, len(typeSets))
+		for i, typeSet := range typeSets {
+			types[i] = tmpl.typesOnly(typeSet)
+		}
+		if typeImports, err = resolveTypeImports(filename, tmpl.file, types); err != nil {
+			return err
+		}
+	}
+
+	constraints, err := newConstraintRewrite(opts.StripTags, opts.BuildConstraints)
+	if err != nil {
+		return err
+	}
+
+	h := newFileHeader(tmpl.file, tmpl.fset, source, opts)
+	m := newMerger(constraints, h.license)
+	return generateEach(tmpl, typeSets, opts, sampleTmpl, samples, func(i int, transformedOutput []byte) error {
+		lines, err := m.merge(transformedOutput)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			importLineIndex := m.importLineIndex
+			if importLineIndex < 0 {
+				importLineIndex = packageLineIndex(lines) + 1
+			}
+			head := append([]string{}, h.lines...)
+			head = append(head, lines[:importLineIndex]...)
+			used := usedPackages(transformedOutput)
+			head = append(head, streamImportBlock(m.imports, used, opts.Imports, typeImports)...)
+			if err := writeHead(w, filename, head, opts.PackageName, opts.Format); err != nil {
+				return err
+			}
+			lines = lines[importLineIndex:]
+		}
+		return writeDecls(w, filename, lines, opts.Format)
+	})
+}
+
+// packageLineIndex gets the index of the package clause in lines.
+func packageLineIndex(lines []string) int {
+	for i, line := range lines {
+		if strings.HasPrefix(line, string(packageKeyword)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// usedPackages gets the names used as package qualifiers in a generated
+// file, or nil if it cannot be parsed.
+func usedPackages(src []byte) map[string]bool {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	used := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok {
+				used[pkg.Name] = true
+			}
+		}
+		return true
+	})
+	return used
+}
+
+// streamImportBlock gets the import declaration of a streamed file. The
+// collected imports of the template are only kept if they are in used, since
+// the template is the same for every typeset; if used is nil they are all
+// kept. The generic package is always left out.
+func streamImportBlock(collected stringArraySet, used map[string]bool, importPaths, typeImports []string) []string {
+	var std, other []string
+	add := func(spec string, mustBeUsed bool) {
+		spec = strings.TrimSpace(spec)
+		if spec == "" || strings.HasPrefix(spec, "//") {
+			return
+		}
+		fields := strings.Fields(spec)
+		importPath, err := strconv.Unquote(fields[len(fields)-1])
+		if err != nil || path.Base(importPath) == genericPackage {
+			return
+		}
+		if mustBeUsed && used != nil {
+			name := path.Base(importPath)
+			if len(fields) > 1 {
+				name = fields[0]
+			}
+			// the package name of paths like gopkg.in/yaml.v2 can't be
+			// told from the path, so those are kept
+			if token.IsIdentifier(name) && name != "_" && !used[name] {
+				return
+			}
+		}
+		if strings.Contains(strings.Split(importPath, "/")[0], ".") {
+			other = append(other, spec)
+		} else {
+			std = append(std, spec)
+		}
+	}
+	for _, spec := range collected {
+		add(spec, true)
+	}
+	fromTemplate := len(std) + len(other)
+	for _, importPath := range importPaths {
+		add(strconv.Quote(importPath), false)
+	}
+	for _, spec := range typeImports {
+		add(spec, false)
+	}
+	switch {
+	case len(std)+len(other) == 0:
+		return nil
+	case len(std)+len(other) == 1 && fromTemplate == 0:
+		// like goimports, which adds a single import without parentheses
+		return []string{fmt.Sprintln("import", append(std, other...)[0])}
+	}
+
+	var specs stringArraySet
+	for _, spec := range std {
+		specs = specs.append(makeLine(spec))
+	}
+	if len(std) > 0 && len(other) > 0 {
+		specs = append(specs, fmt.Sprintln())
+	}
+	for _, spec := range other {
+		specs = specs.append(makeLine(spec))
+	}
+	return (&merger{imports: specs}).importBlock()
+}
+
+// resolveTypeImports finds the import specs for the packages that the
+// specific types refer to and the template does not import already.
+func resolveTypeImports(filename string, file *ast.File, typeSets []map[string]TypeRef) ([]string, error) {
+	imported := make(map[string]bool)
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imported[name] = true
+	}
+
+	var selectors []string
+	for _, typeSet := range typeSets {
+		for _, specific := range typeSet {
+			expr, err := parser.ParseExpr(specific.Type)
+			if err != nil {
+				continue
+			}
+			ast.Inspect(expr, func(n ast.Node) bool {
+				sel, ok := n.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				if pkg, ok := sel.X.(*ast.Ident); ok && !imported[pkg.Name] {
+					imported[pkg.Name] = true
+					selectors = append(selectors, pkg.Name+"."+sel.Sel.Name)
+				}
+				return false
+			})
+		}
+	}
+	if len(selectors) == 0 {
+		return nil, nil
+	}
+	sort.Strings(selectors)
+
+	// let goimports find the packages
+	var stub bytes.Buffer
+	fmt.Fprintln(&stub, "package stub")
+	for _, sel := range selectors {
+		fmt.Fprintf(&stub, "var _ %s\n", sel)
+	}
+	resolved, err := FormatOptions{}.process(filename, stub.Bytes(), false)
+	if err != nil {
+		return nil, err
+	}
+	stubFile, err := parser.ParseFile(token.NewFileSet(), filename, resolved, parser.ImportsOnly)
+	if err != nil {
+		return nil, &errImports{Err: err}
+	}
+	var specs []string
+	for _, spec := range stubFile.Imports {
+		if spec.Name != nil {
+			specs = append(specs, spec.Name.Name+" "+spec.Path.Value)
+		} else {
+			specs = append(specs, spec.Path.Value)
+		}
+	}
+	return specs, nil
+}
+
+// writeHead formats and writes everything up to and including the imports.
+func writeHead(w io.Writer, filename string, lines []string, pkgName string, opts FormatOptions) error {
+	head := []byte(strings.Join(lines, ""))
+	if pkgName != "" {
+		head = changePackage(bytes.NewReader(head), pkgName)
+	}
+	formatted, err := opts.process(filename, head, true)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// streamPackageClause is put in front of code so that it can be parsed and
+// formatted on its own.
+const streamPackageClause = "package p\n"
+
+// writeDecls formats and writes lines of code one declaration at a time.
+// Each declaration is written with the comments and blank lines in front of
+// it.
+func writeDecls(w io.Writer, filename string, lines []string, opts FormatOptions) error {
+	src := streamPackageClause + strings.Join(lines, "")
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return &errImports{Err: err, Source: []byte(src)}
+	}
+
+	writeSegment := func(segment string) error {
+		if strings.TrimSpace(segment) == "" {
+			return nil
+		}
+		formatted, err := opts.process(filename, []byte(streamPackageClause+segment), true)
+		if err != nil {
+			return err
+		}
+		// gofmt always puts a blank line after the package clause; keep
+		// it only if the segment starts with one
+		formatted = bytes.TrimLeft(formatted[len(streamPackageClause):], "\n")
+		if strings.HasPrefix(strings.TrimLeft(segment, " \t"), "\n") {
+			formatted = append([]byte("\n"), formatted...)
+		}
+		_, err = w.Write(formatted)
+		return err
+	}
+
+	offset := len(streamPackageClause)
+	for _, decl := range file.Decls {
+		// the declaration ends at the end of its last line, so that any
+		// trailing comment stays with it
+		end := fset.Position(decl.End()).Offset
+		if nl := strings.IndexByte(src[end:], '\n'); nl >= 0 {
+			end += nl + 1
+		} else {
+			end = len(src)
+		}
+		if err := writeSegment(src[offset:end]); err != nil {
+			return err
+		}
+		offset = end
+	}
+	return writeSegment(src[offset:])
+}
@@ -0,0 +1,392 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// matcher finds a generic type name in identifiers, literals and comments.
+// Matchers are compiled once per generic type and shared by all typesets.
+type matcher struct {
+	name  string
+	lower string
+	exact *regexp.Regexp
+	// plural is the plural of the name, which may not contain it, as in
+	// Entries.
+	plural      string
+	pluralLower string
+}
+
+func newMatcher(name string) *matcher {
+	plural := pluralize(name)
+	return &matcher{
+		name:        name,
+		lower:       strings.ToLower(name),
+		exact:       regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`),
+		plural:      plural,
+		pluralLower: strings.ToLower(plural),
+	}
+}
+
+// in gets whether the generic type name, or its plural, is in s, ignoring
+// case.
+func (m *matcher) in(s string) bool {
+	lower := strings.ToLower(s)
+	return strings.Contains(lower, m.lower) || strings.Contains(lower, m.pluralLower)
+}
+
+// template is a source file that has been read and parsed once, so that it
+// can be instantiated for any number of typesets.
+type template struct {
+	filename string
+	source   []byte
+	// lines are the lines of the source, used by the line based
+	// implementation.
+	lines []string
+	// fset and file are the parsed source, used by the AST based
+	// implementation. They are never modified; each typeset transforms a
+	// clone of file.
+	fset *token.FileSet
+	file *ast.File
+	// genericTypes are the names of the types declared as generic.Type,
+	// generic.Number or generic.Name. Interfaces that embed them are
+	// substituted when a typeset gives them, but aren't required.
+	genericTypes []string
+	// names are those of the generic types declared as generic.Name, whose
+	// values are only used in identifiers.
+	names    []string
+	matchers map[string]*matcher
+	// consts are the constants declared as generic.Int, generic.String etc.
+	consts []genericConstSpec
+	// verbatimLines are the lines of the comment groups marked
+	// `//genny:verbatim`, which the line based implementation copies as they
+	// are.
+	verbatimLines map[int]bool
+	// literalLines are the lines whose string literals are marked
+	// `//genny:literal`.
+	literalLines map[int]bool
+	// pkg is the package of the template when generating into another
+	// package, or nil.
+	pkg *templatePackage
+	// shared are the methods on receivers that are generated once, or nil.
+	shared *sharedMethods
+	// naming gets the words for the specific types, NamingOptions{} if nil,
+	// and placement is where they go in identifiers.
+	naming    Naming
+	placement Placement
+	// literals is the policy for string literals.
+	literals LiteralPolicy
+}
+
+// newTemplate parses the source and compiles the matchers for the generic
+// types of the typeSets.
+func newTemplate(filename string, source []byte, typeSets []map[string]TypeRef) (*template, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, source, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, &errSource{Err: err}
+	}
+
+	t := &template{
+		filename: filename,
+		source:   source,
+		fset:     fset,
+		file:     file,
+		matchers: make(map[string]*matcher),
+	}
+
+	bs := bufio.NewScanner(bytes.NewReader(source))
+	for bs.Scan() {
+		t.lines = append(t.lines, bs.Text())
+	}
+	if err := bs.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, gt := range scanGenericTypes(file) {
+		if gt.iface != nil {
+			continue
+		}
+		t.genericTypes = append(t.genericTypes, gt.name)
+		if gt.kind == "Name" {
+			t.names = append(t.names, gt.name)
+		}
+	}
+
+	t.consts = scanGenericConsts(fset, file, source)
+	t.verbatimLines = t.scanVerbatimLines()
+	t.literalLines = t.scanLiteralLines()
+
+	for _, typeSet := range typeSets {
+		for name := range t.typesOnly(typeSet) {
+			if _, ok := t.matchers[name]; !ok {
+				t.matchers[name] = newMatcher(name)
+			}
+		}
+	}
+	t.shared = findSharedMethods(t)
+	return t, nil
+}
+
+// genericTypeSpec is the declaration of a generic type.
+type genericTypeSpec struct {
+	name string
+	// kind is the generic placeholder, "Type", "Number" or "Name".
+	kind string
+	spec *ast.TypeSpec
+	// iface is the interface the type is declared as, if it is constrained
+	// by one.
+	iface *ast.InterfaceType
+}
+
+// scanGenericTypes finds the declarations of generic types in a template.
+func scanGenericTypes(file *ast.File) []genericTypeSpec {
+	var found []genericTypeSpec
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			switch tt := ts.Type.(type) {
+			case *ast.SelectorExpr:
+				if kind, ok := genericPlaceholder(tt); ok {
+					found = append(found, genericTypeSpec{name: ts.Name.Name, kind: kind, spec: ts})
+				}
+			case *ast.InterfaceType:
+				for _, field := range tt.Methods.List {
+					sel, ok := field.Type.(*ast.SelectorExpr)
+					if !ok || len(field.Names) > 0 {
+						continue
+					}
+					if kind, ok := genericPlaceholder(sel); ok {
+						found = append(found, genericTypeSpec{name: ts.Name.Name, kind: kind, spec: ts, iface: tt})
+						break
+					}
+				}
+			}
+		}
+	}
+	return found
+}
+
+// genericPlaceholder gets the name of the generic placeholder, like "Type" for
+// generic.Type, that a type expression refers to.
+func genericPlaceholder(sel *ast.SelectorExpr) (string, bool) {
+	if name, ok := sel.X.(*ast.Ident); ok && name.Name == genericPackage {
+		return sel.Sel.Name, true
+	}
+	return "", false
+}
+
+// checkTypeSet makes sure every generic.Type is represented in the typeSet,
+// and that the values of the generic names are identifiers.
+func (t *template) checkTypeSet(typeSet map[string]TypeRef) error {
+	for _, name := range t.genericTypes {
+		if _, ok := typeSet[name]; !ok {
+			return &errMissingSpecificType{GenericType: name}
+		}
+	}
+	for _, name := range t.names {
+		if specific := typeSet[name]; !token.IsIdentifier(specific.Type) || !token.IsIdentifier(wordify(specific.Alias, true)) {
+			return &errBadTypeArgs{Arg: name + "=" + specific.Alias, Message: "a generic.Name has to be given an identifier"}
+		}
+	}
+	return nil
+}
+
+// generate instantiates the template for the typeSet. The substitutions are
+// recorded with the tracer, if not nil.
+func (t *template) generate(typeSet map[string]TypeRef, useAst bool, tracer Tracer) ([]byte, error) {
+	if err := t.checkTypeSet(typeSet); err != nil {
+		return nil, err
+	}
+	words, err := t.words(typeSet)
+	if err != nil {
+		return nil, err
+	}
+	trace := newSubstitutionTrace(tracer, t, typeSet, useAst)
+	var output []byte
+	if useAst {
+		output, err = generateSpecificAst(t, t.typesOnly(typeSet), words, trace)
+	} else {
+		output, err = generateSpecific(t, t.typesOnly(typeSet), words, trace)
+	}
+	if err == nil && len(t.consts) > 0 {
+		output, err = t.applyConsts(output, typeSet, trace)
+	}
+	if err == nil && (len(t.verbatimLines) > 0 || len(t.literalLines) > 0) {
+		output = deleteDirectives(output, verbatimDirective, literalDirective)
+	}
+	if err == nil {
+		err = trace.error()
+	}
+	return output, err
+}
+
+// prepareTemplate parses a template and sets it up with the options. The
+// sample template is nil if the template has no samples.
+func prepareTemplate(filename string, source []byte, typeSets []map[string]TypeRef, opts Options) (*template, *sampleTemplate, error) {
+	sampleTmpl, err := newSampleTemplate(filename, source)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl, err := newTemplate(filename, source, typeSets)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl.naming, tmpl.placement, tmpl.literals = opts.Naming, opts.Placement, opts.Literals
+	if opts.TemplatePackage != "" {
+		if tmpl.pkg, err = loadTemplatePackage(filename, tmpl.file, opts.TemplatePackage); err != nil {
+			return nil, nil, err
+		}
+	}
+	return tmpl, sampleTmpl, nil
+}
+
+// forTypeSet gets the template that is instantiated for typeSets[i], which
+// is t itself unless it has samples, which are filled in for the typeset.
+func (t *template) forTypeSet(typeSets []map[string]TypeRef, i int, samples *sampleTemplate, source SampleSource) (*template, error) {
+	if samples == nil {
+		return t, nil
+	}
+	specificSource, err := samples.apply(typeSets[i], source)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := newTemplate(t.filename, specificSource, typeSets[i:i+1])
+	if err != nil {
+		return nil, err
+	}
+	tmpl.naming, tmpl.placement, tmpl.literals = t.naming, t.placement, t.literals
+	return tmpl, nil
+}
+
+// generateTypeSet instantiates the template for typeSets[i], with the shared
+// methods renamed and the declarations of the template's package qualified.
+func (t *template) generateTypeSet(typeSets []map[string]TypeRef, i int, useAst bool, samples *sampleTemplate, source SampleSource, tracer Tracer) ([]byte, error) {
+	tmpl, err := t.forTypeSet(typeSets, i, samples, source)
+	if err != nil {
+		return nil, err
+	}
+	output, err := tmpl.generate(typeSets[i], useAst, tracer)
+	// with a single typeset the shared methods keep their names
+	if err == nil && t.shared != nil && len(typeSets) > 1 {
+		var words map[string]string
+		if words, err = t.words(typeSets[i]); err == nil {
+			output, err = t.shared.rename(output, words)
+		}
+	}
+	if err == nil && t.pkg != nil {
+		output, err = t.pkg.qualify(output)
+	}
+	return output, err
+}
+
+// recoverGenerate calls generate for the i-th typeset, getting a panic in it
+// as an error. A panic in a worker can't be recovered by the caller of
+// generateEach, so it would end the program.
+func recoverGenerate(generate func(i int) ([]byte, error), i int, typeSet map[string]TypeRef) (output []byte, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			output, err = nil, &errPanic{TypeSet: TypeSetString(typeSet), Value: p}
+		}
+	}()
+	return generate(i)
+}
+
+// generateEach instantiates the template for every typeset on a pool of
+// workers, and calls emit with each output in the same order as the
+// typesets. Only a few outputs are held in memory at any time. If samples is
+// not nil, each typeset gets its own copy of the template with the samples
+// filled in.
+func generateEach(t *template, typeSets []map[string]TypeRef, opts Options, samples *sampleTemplate, source SampleSource, emit func(i int, output []byte) error) error {
+	var tracer Tracer
+	if opts.Trace != nil {
+		tracer = &lockedTracer{tracer: opts.Trace}
+	}
+	generate := func(i int) ([]byte, error) {
+		output, err := t.generateTypeSet(typeSets, i, opts.UseAst, samples, source, tracer)
+		if err != nil || tracer == nil {
+			return output, err
+		}
+		err = tracer.Intermediate(Intermediate{Index: i, TypeSet: TypeSetString(typeSets[i]), Source: string(output)})
+		return output, err
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(typeSets) {
+		workers = len(typeSets)
+	}
+
+	type result struct {
+		output []byte
+		err    error
+	}
+	results := make([]chan result, len(typeSets))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+
+	// pending limits the number of outputs that have been generated but
+	// not emitted yet.
+	pending := make(chan struct{}, 2*workers)
+	done := make(chan struct{})
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		for i := range typeSets {
+			select {
+			case pending <- struct{}{}:
+			case <-done:
+				return
+			}
+			select {
+			case indexes <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				output, err := recoverGenerate(generate, i, typeSets[i])
+				results[i] <- result{output, err}
+			}
+		}()
+	}
+
+	var err error
+	for i := range typeSets {
+		r := <-results[i]
+		<-pending
+		if err = r.err; err != nil {
+			break
+		}
+		if err = emit(i, r.output); err != nil {
+			break
+		}
+	}
+	close(done)
+	wg.Wait()
+	return err
+}
@@ -0,0 +1,55 @@
+package parse
+
+import (
+	"bytes"
+	"go/printer"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneFile(t *testing.T) {
+	src, err := ioutil.ReadFile("../examples/btree/btree.go")
+	require.NoError(t, err)
+	tmpl, err := newTemplate("btree.go", src, nil)
+	require.NoError(t, err)
+
+	var original bytes.Buffer
+	require.NoError(t, printer.Fprint(&original, tmpl.fset, tmpl.file))
+
+	clone := cloneFile(tmpl.file)
+	var cloned bytes.Buffer
+	require.NoError(t, printer.Fprint(&cloned, tmpl.fset, clone))
+	assert.Equal(t, original.String(), cloned.String())
+
+	// transforming the clone leaves the template untouched
+	generateSpecificType(tmpl.fset, clone, replaceSpec{genericType: "key", specificType: TypeRef{Alias: "int", Type: "int"}, matcher: newMatcher("key"), word: "Int"})
+	var after bytes.Buffer
+	require.NoError(t, printer.Fprint(&after, tmpl.fset, tmpl.file))
+	assert.Equal(t, original.String(), after.String())
+}
+
+func benchmarkGenerateEach(b *testing.B, useAst bool, workers int) {
+	src, err := ioutil.ReadFile("../examples/btree/btree.go")
+	require.NoError(b, err)
+	typeSets, err := TypeSet("key=int,string value=NUMBERS")
+	require.NoError(b, err)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tmpl, err := newTemplate("btree.go", src, typeSets)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := generateEach(tmpl, typeSets, Options{UseAst: useAst, Workers: workers}, nil, nil, func(int, []byte) error { return nil }); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGenerateEach(b *testing.B)          { benchmarkGenerateEach(b, false, 0) }
+func BenchmarkGenerateEachSerial(b *testing.B)    { benchmarkGenerateEach(b, false, 1) }
+func BenchmarkGenerateEachAst(b *testing.B)       { benchmarkGenerateEach(b, true, 0) }
+func BenchmarkGenerateEachAstSerial(b *testing.B) { benchmarkGenerateEach(b, true, 1) }
@@ -14,14 +14,56 @@ func (Receiver) StringsToInts(string []string) []int {
 	return []int{}
 }
 
+// LenInt gets the number of Ints.
+func (Receiver) LenInt(values []int) int {
+	return len(values)
+}
+
+// FirstStringInt gets the first of the Ints converted from the Strings, if there are any.
+func (r Receiver) FirstStringInt(values []string) (first int, ok bool) {
+	converted := r.StringsToInts(values)
+	if r.LenInt(converted) == 0 {
+		return first, false
+	}
+	return converted[0], true
+}
+
 // StringsToFloat64s converts a []string to a []float64
 func (Receiver) StringsToFloat64s(string []string) []float64 {
 	// returning an empty float64 slice is sufficient for this test.
 	return []float64{}
 }
 
+// LenFloat64 gets the number of Float64s.
+func (Receiver) LenFloat64(values []float64) int {
+	return len(values)
+}
+
+// FirstStringFloat64 gets the first of the Float64s converted from the Strings, if there are any.
+func (r Receiver) FirstStringFloat64(values []string) (first float64, ok bool) {
+	converted := r.StringsToFloat64s(values)
+	if r.LenFloat64(converted) == 0 {
+		return first, false
+	}
+	return converted[0], true
+}
+
 // StringsToBools converts a []string to a []bool
 func (Receiver) StringsToBools(string []string) []bool {
 	// returning an empty bool slice is sufficient for this test.
 	return []bool{}
 }
+
+// LenBool gets the number of Bools.
+func (Receiver) LenBool(values []bool) int {
+	return len(values)
+}
+
+// FirstStringBool gets the first of the Bools converted from the Strings, if there are any.
+func (r Receiver) FirstStringBool(values []string) (first bool, ok bool) {
+	converted := r.StringsToBools(values)
+	if r.LenBool(converted) == 0 {
+		return first, false
+	}
+	return converted[0], true
+}
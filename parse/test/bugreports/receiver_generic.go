@@ -0,0 +1,36 @@
+//go:build genny
+
+package bugreports
+
+import "github.com/tehbilly/genny/generic"
+
+// TA will be replaced in tests
+type TA generic.Type
+
+// Receiver is the struct used for tests.
+type Receiver struct{}
+
+//genny:start
+
+// TB will be replaced in tests
+type TB generic.Type
+
+// TAsToTBs converts a []TA to a []TB
+func (Receiver) TAsToTBs(ta []TA) []TB {
+	// returning an empty TB slice is sufficient for this test.
+	return []TB{}
+}
+
+// Len gets the number of TBs.
+func (Receiver) Len(values []TB) int {
+	return len(values)
+}
+
+// First gets the first of the TBs converted from the TAs, if there are any.
+func (r Receiver) First(values []TA) (first TB, ok bool) {
+	converted := r.TAsToTBs(values)
+	if r.Len(converted) == 0 {
+		return first, false
+	}
+	return converted[0], true
+}
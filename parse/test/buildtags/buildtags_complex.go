@@ -0,0 +1,18 @@
+//go:build genny && gennylist && (linux || darwin)
+// +build genny
+// +build gennylist
+// +build linux darwin
+
+package buildtags
+
+import (
+	"fmt"
+
+	"github.com/tehbilly/genny/generic"
+)
+
+type _t_ generic.Type
+
+func _t_Describe(t _t_) string {
+	return fmt.Sprint(t)
+}
@@ -0,0 +1,77 @@
+// Code generated by genny. DO NOT EDIT.
+// This file was automatically generated by genny.
+// Any changes will be lost if this file is regenerated.
+// see https://github.com/tehbilly/genny
+
+package constants
+
+import (
+	"fmt"
+)
+
+// Label is the name the rings report.
+const Label string = "buffer"
+
+// Degree64 is the capacity of an IntRing64.
+const Degree64 int = 64
+
+// IntRing64 is a ring buffer that keeps the last Degree64 ints pushed.
+type IntRing64 struct {
+	ints [Degree64]int
+	next int
+	full bool
+}
+
+// Push adds v, dropping the oldest int if the ring is full.
+func (r *IntRing64) Push(v int) {
+	r.ints[r.next] = v
+	r.next = (r.next + 1) % Degree64
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Len gets the number of ints in the ring.
+func (r *IntRing64) Len() int {
+	if r.full {
+		return Degree64
+	}
+	return r.next
+}
+
+// String describes the ring.
+func (r *IntRing64) String() string {
+	return fmt.Sprintf("%s of %d", Label, r.Len())
+}
+
+// DegreeBig is the capacity of an IntRingBig.
+const DegreeBig int = 1024
+
+// IntRingBig is a ring buffer that keeps the last DegreeBig ints pushed.
+type IntRingBig struct {
+	ints [DegreeBig]int
+	next int
+	full bool
+}
+
+// Push adds v, dropping the oldest int if the ring is full.
+func (r *IntRingBig) Push(v int) {
+	r.ints[r.next] = v
+	r.next = (r.next + 1) % DegreeBig
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Len gets the number of ints in the ring.
+func (r *IntRingBig) Len() int {
+	if r.full {
+		return DegreeBig
+	}
+	return r.next
+}
+
+// String describes the ring.
+func (r *IntRingBig) String() string {
+	return fmt.Sprintf("%s of %d", Label, r.Len())
+}
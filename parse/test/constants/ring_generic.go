@@ -0,0 +1,48 @@
+//go:build genny
+
+package constants
+
+import (
+	"fmt"
+
+	"github.com/tehbilly/genny/generic"
+)
+
+// Label is the name the rings report.
+const Label generic.String = "ring"
+
+type Item generic.Type
+
+//genny:start
+
+// Degree is the capacity of an ItemRingDegree.
+const Degree generic.Int = 32 //genny:name
+
+// ItemRingDegree is a ring buffer that keeps the last Degree items pushed.
+type ItemRingDegree struct {
+	items [Degree]Item
+	next  int
+	full  bool
+}
+
+// Push adds v, dropping the oldest item if the ring is full.
+func (r *ItemRingDegree) Push(v Item) {
+	r.items[r.next] = v
+	r.next = (r.next + 1) % Degree
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Len gets the number of items in the ring.
+func (r *ItemRingDegree) Len() int {
+	if r.full {
+		return Degree
+	}
+	return r.next
+}
+
+// String describes the ring.
+func (r *ItemRingDegree) String() string {
+	return fmt.Sprintf("%s of %d", Label, r.Len())
+}
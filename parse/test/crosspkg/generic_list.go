@@ -0,0 +1,30 @@
+package crosspkg
+
+import "github.com/tehbilly/genny/generic"
+
+type Item generic.Type
+
+// ItemList is a list of at most opts.Limit Items.
+type ItemList struct {
+	items []Item
+	opts  Options
+}
+
+func NewItemList(opts Options) *ItemList {
+	if opts.Limit == 0 {
+		opts.Limit = DefaultLimit
+	}
+	return &ItemList{opts: opts}
+}
+
+func (l *ItemList) Add(item Item) bool {
+	if len(l.items) >= l.opts.Limit {
+		return false
+	}
+	l.items = append(l.items, item)
+	return true
+}
+
+func (l *ItemList) At(i int) Item {
+	return l.items[Clamp(i, 0, len(l.items)-1)]
+}
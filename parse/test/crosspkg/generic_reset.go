@@ -0,0 +1,10 @@
+package crosspkg
+
+import "github.com/tehbilly/genny/generic"
+
+type Value generic.Type
+
+func ResetValue(v *Value) {
+	reset()
+	*v = *new(Value)
+}
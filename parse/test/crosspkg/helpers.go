@@ -0,0 +1,22 @@
+package crosspkg
+
+// DefaultLimit is the limit of a list when none is given.
+const DefaultLimit = 10
+
+// Options configures a list.
+type Options struct {
+	Limit int
+}
+
+// Clamp gets n limited to the range lo to hi.
+func Clamp(n, lo, hi int) int {
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+	return n
+}
+
+func reset() {}
@@ -0,0 +1,60 @@
+// Code generated by genny. DO NOT EDIT.
+// This file was automatically generated by genny.
+// Any changes will be lost if this file is regenerated.
+// see https://github.com/tehbilly/genny
+
+package other
+
+import (
+	"github.com/tehbilly/genny/parse/test/crosspkg"
+)
+
+// IntList is a list of at most opts.Limit Ints.
+type IntList struct {
+	ints []int
+	opts crosspkg.Options
+}
+
+func NewIntList(opts crosspkg.Options) *IntList {
+	if opts.Limit == 0 {
+		opts.Limit = crosspkg.DefaultLimit
+	}
+	return &IntList{opts: opts}
+}
+
+func (l *IntList) Add(int int) bool {
+	if len(l.ints) >= l.opts.Limit {
+		return false
+	}
+	l.ints = append(l.ints, int)
+	return true
+}
+
+func (l *IntList) At(i int) int {
+	return l.ints[crosspkg.Clamp(i, 0, len(l.ints)-1)]
+}
+
+// StringList is a list of at most opts.Limit Strings.
+type StringList struct {
+	strings []string
+	opts    crosspkg.Options
+}
+
+func NewStringList(opts crosspkg.Options) *StringList {
+	if opts.Limit == 0 {
+		opts.Limit = crosspkg.DefaultLimit
+	}
+	return &StringList{opts: opts}
+}
+
+func (l *StringList) Add(string string) bool {
+	if len(l.strings) >= l.opts.Limit {
+		return false
+	}
+	l.strings = append(l.strings, string)
+	return true
+}
+
+func (l *StringList) At(i int) string {
+	return l.strings[crosspkg.Clamp(i, 0, len(l.strings)-1)]
+}
@@ -0,0 +1,15 @@
+package format
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/tehbilly/genny/generic"
+	"github.com/tehbilly/genny/out"
+)
+
+type Item generic.Type
+
+// CheckItem checks that the value would be written to the file.
+func CheckItem(t assert.TestingT, f *out.LazyFile, v Item) bool {
+	return assert.NotEmpty(t, fmt.Sprint(v), f.FileName)
+}
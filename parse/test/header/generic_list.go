@@ -0,0 +1,12 @@
+// Copyright 2020 The Genny Authors. All rights reserved.
+// Use of this source code is governed by an MIT license.
+
+// Package header tests the headers of generated files.
+package header
+
+import "github.com/tehbilly/genny/generic"
+
+type Something generic.Type
+
+// SomethingList is a list of Somethings.
+type SomethingList []Something
@@ -0,0 +1,16 @@
+/*
+Copyright 2020 The Genny Authors. All rights reserved.
+
+Use of this source code is governed by an MIT license
+that can be found in the LICENSE file.
+*/
+
+// Package license tests block comment licenses.
+package license
+
+import "github.com/tehbilly/genny/generic"
+
+type Something generic.Type
+
+// SomethingList is a list of Somethings.
+type SomethingList []Something
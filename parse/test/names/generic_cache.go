@@ -0,0 +1,29 @@
+package names
+
+import "github.com/tehbilly/genny/generic"
+
+// Prefix tells the caches of the same values apart.
+type Prefix generic.Name
+
+type Elem generic.Type
+
+// PrefixElemCache keeps the most recent Elem for each key.
+type PrefixElemCache struct {
+	values map[string]Elem
+}
+
+// NewPrefixElemCache makes an empty PrefixElemCache.
+func NewPrefixElemCache() *PrefixElemCache {
+	return &PrefixElemCache{values: make(map[string]Elem)}
+}
+
+// Get gets the Elem for the key, if any.
+func (c *PrefixElemCache) Get(key string) (Elem, bool) {
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// Put sets the Elem for the key.
+func (c *PrefixElemCache) Put(key string, v Elem) {
+	c.values[key] = v
+}
@@ -0,0 +1,48 @@
+// Code generated by genny. DO NOT EDIT.
+// This file was automatically generated by genny.
+// Any changes will be lost if this file is regenerated.
+// see https://github.com/tehbilly/genny
+
+package names
+
+// SessionStringCache keeps the most recent string for each key.
+type SessionStringCache struct {
+	values map[string]string
+}
+
+// NewSessionStringCache makes an empty SessionStringCache.
+func NewSessionStringCache() *SessionStringCache {
+	return &SessionStringCache{values: make(map[string]string)}
+}
+
+// Get gets the string for the key, if any.
+func (c *SessionStringCache) Get(key string) (string, bool) {
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// Put sets the string for the key.
+func (c *SessionStringCache) Put(key string, v string) {
+	c.values[key] = v
+}
+
+// RequestStringCache keeps the most recent string for each key.
+type RequestStringCache struct {
+	values map[string]string
+}
+
+// NewRequestStringCache makes an empty RequestStringCache.
+func NewRequestStringCache() *RequestStringCache {
+	return &RequestStringCache{values: make(map[string]string)}
+}
+
+// Get gets the string for the key, if any.
+func (c *RequestStringCache) Get(key string) (string, bool) {
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// Put sets the string for the key.
+func (c *RequestStringCache) Put(key string, v string) {
+	c.values[key] = v
+}
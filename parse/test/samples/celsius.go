@@ -0,0 +1,9 @@
+package samples
+
+// Celsius is a custom type that provides its own samples.
+type Celsius float64
+
+// Sample gets the i-th sample temperature.
+func (Celsius) Sample(i int) Celsius {
+	return Celsius(i) * 10
+}
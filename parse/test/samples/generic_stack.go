@@ -0,0 +1,33 @@
+package samples
+
+import "github.com/tehbilly/genny/generic"
+
+// Item is the type of the values held by the stack.
+type Item generic.Type
+
+// ItemStack is a last-in first-out stack of Items.
+type ItemStack struct {
+	values []Item
+}
+
+// NewItemStack makes a new empty ItemStack.
+func NewItemStack() *ItemStack {
+	return &ItemStack{}
+}
+
+// Push adds a value to the top of the stack.
+func (s *ItemStack) Push(v Item) {
+	s.values = append(s.values, v)
+}
+
+// Pop removes and returns the value at the top of the stack.
+func (s *ItemStack) Pop() Item {
+	v := s.values[len(s.values)-1]
+	s.values = s.values[:len(s.values)-1]
+	return v
+}
+
+// Len gets the number of values in the stack.
+func (s *ItemStack) Len() int {
+	return len(s.values)
+}
@@ -0,0 +1,43 @@
+package samples
+
+import (
+	"testing"
+
+	"github.com/tehbilly/genny/generic"
+)
+
+//genny:sample Point Point{1, 2}, Point{3, 4}, Point{5, 6}
+//genny:sampler Celsius
+
+//genny:samples
+var itemSamples = []Item{1, 2, 3}
+
+func TestItemStack(t *testing.T) {
+	s := NewItemStack()
+	for _, v := range itemSamples {
+		s.Push(v)
+	}
+	if s.Len() != len(itemSamples) {
+		t.Errorf("Push should add the value")
+	}
+	for i := len(itemSamples) - 1; i >= 0; i-- {
+		if v := s.Pop(); v != itemSamples[i] {
+			t.Errorf("Pop should return the last value pushed: got %v, want %v", v, itemSamples[i])
+		}
+	}
+	if s.Len() != 0 {
+		t.Errorf("Pop should remove the value")
+	}
+}
+
+func TestItemStackZero(t *testing.T) {
+	s := NewItemStack()
+	s.Push(generic.Sample[Item](0))
+	s.Push(generic.Zero[Item]())
+	if v := s.Pop(); v != generic.Zero[Item]() {
+		t.Errorf("Pop should return the zero value: got %v", v)
+	}
+	if v := s.Pop(); v != generic.Sample[Item](0) {
+		t.Errorf("Pop should return the sample: got %v", v)
+	}
+}
@@ -0,0 +1,6 @@
+package samples
+
+// Point is a custom type with samples declared in the test template.
+type Point struct {
+	X, Y int
+}
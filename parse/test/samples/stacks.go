@@ -0,0 +1,114 @@
+// Code generated by genny. DO NOT EDIT.
+// This file was automatically generated by genny.
+// Any changes will be lost if this file is regenerated.
+// see https://github.com/tehbilly/genny
+
+package samples
+
+// IntStack is a last-in first-out stack of Ints.
+type IntStack struct {
+	values []int
+}
+
+// NewIntStack makes a new empty IntStack.
+func NewIntStack() *IntStack {
+	return &IntStack{}
+}
+
+// Push adds a value to the top of the stack.
+func (s *IntStack) Push(v int) {
+	s.values = append(s.values, v)
+}
+
+// Pop removes and returns the value at the top of the stack.
+func (s *IntStack) Pop() int {
+	v := s.values[len(s.values)-1]
+	s.values = s.values[:len(s.values)-1]
+	return v
+}
+
+// Len gets the number of values in the stack.
+func (s *IntStack) Len() int {
+	return len(s.values)
+}
+
+// StringStack is a last-in first-out stack of Strings.
+type StringStack struct {
+	values []string
+}
+
+// NewStringStack makes a new empty StringStack.
+func NewStringStack() *StringStack {
+	return &StringStack{}
+}
+
+// Push adds a value to the top of the stack.
+func (s *StringStack) Push(v string) {
+	s.values = append(s.values, v)
+}
+
+// Pop removes and returns the value at the top of the stack.
+func (s *StringStack) Pop() string {
+	v := s.values[len(s.values)-1]
+	s.values = s.values[:len(s.values)-1]
+	return v
+}
+
+// Len gets the number of values in the stack.
+func (s *StringStack) Len() int {
+	return len(s.values)
+}
+
+// PointStack is a last-in first-out stack of Points.
+type PointStack struct {
+	values []Point
+}
+
+// NewPointStack makes a new empty PointStack.
+func NewPointStack() *PointStack {
+	return &PointStack{}
+}
+
+// Push adds a value to the top of the stack.
+func (s *PointStack) Push(v Point) {
+	s.values = append(s.values, v)
+}
+
+// Pop removes and returns the value at the top of the stack.
+func (s *PointStack) Pop() Point {
+	v := s.values[len(s.values)-1]
+	s.values = s.values[:len(s.values)-1]
+	return v
+}
+
+// Len gets the number of values in the stack.
+func (s *PointStack) Len() int {
+	return len(s.values)
+}
+
+// CelsiusStack is a last-in first-out stack of Celsiuses.
+type CelsiusStack struct {
+	values []Celsius
+}
+
+// NewCelsiusStack makes a new empty CelsiusStack.
+func NewCelsiusStack() *CelsiusStack {
+	return &CelsiusStack{}
+}
+
+// Push adds a value to the top of the stack.
+func (s *CelsiusStack) Push(v Celsius) {
+	s.values = append(s.values, v)
+}
+
+// Pop removes and returns the value at the top of the stack.
+func (s *CelsiusStack) Pop() Celsius {
+	v := s.values[len(s.values)-1]
+	s.values = s.values[:len(s.values)-1]
+	return v
+}
+
+// Len gets the number of values in the stack.
+func (s *CelsiusStack) Len() int {
+	return len(s.values)
+}
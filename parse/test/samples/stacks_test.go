@@ -0,0 +1,138 @@
+// Code generated by genny. DO NOT EDIT.
+// This file was automatically generated by genny.
+// Any changes will be lost if this file is regenerated.
+// see https://github.com/tehbilly/genny
+
+package samples
+
+import (
+	"testing"
+)
+
+var intSamples = []int{1, 2, 3}
+
+func TestIntStack(t *testing.T) {
+	s := NewIntStack()
+	for _, v := range intSamples {
+		s.Push(v)
+	}
+	if s.Len() != len(intSamples) {
+		t.Errorf("Push should add the value")
+	}
+	for i := len(intSamples) - 1; i >= 0; i-- {
+		if v := s.Pop(); v != intSamples[i] {
+			t.Errorf("Pop should return the last value pushed: got %v, want %v", v, intSamples[i])
+		}
+	}
+	if s.Len() != 0 {
+		t.Errorf("Pop should remove the value")
+	}
+}
+
+func TestIntStackZero(t *testing.T) {
+	s := NewIntStack()
+	s.Push(1)
+	s.Push(0)
+	if v := s.Pop(); v != 0 {
+		t.Errorf("Pop should return the zero value: got %v", v)
+	}
+	if v := s.Pop(); v != 1 {
+		t.Errorf("Pop should return the sample: got %v", v)
+	}
+}
+
+var stringSamples = []string{"a", "b", "c"}
+
+func TestStringStack(t *testing.T) {
+	s := NewStringStack()
+	for _, v := range stringSamples {
+		s.Push(v)
+	}
+	if s.Len() != len(stringSamples) {
+		t.Errorf("Push should add the value")
+	}
+	for i := len(stringSamples) - 1; i >= 0; i-- {
+		if v := s.Pop(); v != stringSamples[i] {
+			t.Errorf("Pop should return the last value pushed: got %v, want %v", v, stringSamples[i])
+		}
+	}
+	if s.Len() != 0 {
+		t.Errorf("Pop should remove the value")
+	}
+}
+
+func TestStringStackZero(t *testing.T) {
+	s := NewStringStack()
+	s.Push("a")
+	s.Push("")
+	if v := s.Pop(); v != "" {
+		t.Errorf("Pop should return the zero value: got %v", v)
+	}
+	if v := s.Pop(); v != "a" {
+		t.Errorf("Pop should return the sample: got %v", v)
+	}
+}
+
+var pointSamples = []Point{Point{1, 2}, Point{3, 4}, Point{5, 6}}
+
+func TestPointStack(t *testing.T) {
+	s := NewPointStack()
+	for _, v := range pointSamples {
+		s.Push(v)
+	}
+	if s.Len() != len(pointSamples) {
+		t.Errorf("Push should add the value")
+	}
+	for i := len(pointSamples) - 1; i >= 0; i-- {
+		if v := s.Pop(); v != pointSamples[i] {
+			t.Errorf("Pop should return the last value pushed: got %v, want %v", v, pointSamples[i])
+		}
+	}
+	if s.Len() != 0 {
+		t.Errorf("Pop should remove the value")
+	}
+}
+
+func TestPointStackZero(t *testing.T) {
+	s := NewPointStack()
+	s.Push(Point{1, 2})
+	s.Push(*new(Point))
+	if v := s.Pop(); v != *new(Point) {
+		t.Errorf("Pop should return the zero value: got %v", v)
+	}
+	if v := s.Pop(); v != (Point{1, 2}) {
+		t.Errorf("Pop should return the sample: got %v", v)
+	}
+}
+
+var celsiusSamples = []Celsius{(*new(Celsius)).Sample(0), (*new(Celsius)).Sample(1), (*new(Celsius)).Sample(2)}
+
+func TestCelsiusStack(t *testing.T) {
+	s := NewCelsiusStack()
+	for _, v := range celsiusSamples {
+		s.Push(v)
+	}
+	if s.Len() != len(celsiusSamples) {
+		t.Errorf("Push should add the value")
+	}
+	for i := len(celsiusSamples) - 1; i >= 0; i-- {
+		if v := s.Pop(); v != celsiusSamples[i] {
+			t.Errorf("Pop should return the last value pushed: got %v, want %v", v, celsiusSamples[i])
+		}
+	}
+	if s.Len() != 0 {
+		t.Errorf("Pop should remove the value")
+	}
+}
+
+func TestCelsiusStackZero(t *testing.T) {
+	s := NewCelsiusStack()
+	s.Push((*new(Celsius)).Sample(0))
+	s.Push(*new(Celsius))
+	if v := s.Pop(); v != *new(Celsius) {
+		t.Errorf("Pop should return the zero value: got %v", v)
+	}
+	if v := s.Pop(); v != (*new(Celsius)).Sample(0) {
+		t.Errorf("Pop should return the sample: got %v", v)
+	}
+}
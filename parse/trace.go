@@ -0,0 +1,195 @@
+package parse
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Tracer records what generating a template does, for debugging the
+// substitutions. Its methods are not called concurrently. Generation fails
+// with the first error one of them returns.
+type Tracer interface {
+	// Substitution is called for every substitution decision.
+	Substitution(s Substitution) error
+	// Intermediate is called with the code generated for each typeset,
+	// before the typesets are merged and formatted.
+	Intermediate(i Intermediate) error
+}
+
+// Substitution is a substitution decision.
+type Substitution struct {
+	// TypeSet is the typeset being generated, in the format TypeSet reads.
+	TypeSet string `json:"typeSet"`
+	// Engine is "ast" or "line".
+	Engine string `json:"engine"`
+	// Kind is the kind of node, e.g. "FIELD TYPE" or "COMMENT", or "LINE"
+	// for the line based implementation. Nodes of kinds that are not
+	// substituted are recorded as "UNHANDLED" with the type of the parent
+	// node, and are left as they are.
+	Kind string `json:"kind"`
+	// File, Line and Column are the position in the template.
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Intermediate is the code generated for a typeset before merging.
+type Intermediate struct {
+	// Index is the index of the typeset.
+	Index   int    `json:"index"`
+	TypeSet string `json:"typeSet"`
+	Source  string `json:"source"`
+}
+
+// NewJSONTracer gets a Tracer that writes a JSON object for each
+// substitution and intermediate output to w, one per line. Each has an
+// "event" field, "substitution" or "intermediate".
+func NewJSONTracer(w io.Writer) Tracer {
+	return &jsonTracer{enc: json.NewEncoder(w)}
+}
+
+type jsonTracer struct {
+	enc *json.Encoder
+}
+
+func (t *jsonTracer) Substitution(s Substitution) error {
+	return t.enc.Encode(struct {
+		Event string `json:"event"`
+		Substitution
+	}{"substitution", s})
+}
+
+func (t *jsonTracer) Intermediate(i Intermediate) error {
+	return t.enc.Encode(struct {
+		Event string `json:"event"`
+		Intermediate
+	}{"intermediate", i})
+}
+
+// NewDirTracer gets a Tracer that writes the intermediate output of each
+// typeset to its own file in dir, named after the index and the typeset,
+// and the substitutions to trace.jsonl in it. The directory is created if
+// needed.
+func NewDirTracer(dir string) (Tracer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	log, err := os.Create(filepath.Join(dir, "trace.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	return &dirTracer{dir: dir, log: log, jsonTracer: jsonTracer{enc: json.NewEncoder(log)}}, nil
+}
+
+type dirTracer struct {
+	jsonTracer
+	dir string
+	log *os.File
+}
+
+func (t *dirTracer) Intermediate(i Intermediate) error {
+	// the files don't end in .go, so that the go tool ignores them
+	name := fmt.Sprintf("%04d_%s.go.txt", i.Index, fileNamePart(i.TypeSet))
+	return ioutil.WriteFile(filepath.Join(t.dir, name), []byte(i.Source), 0644)
+}
+
+// Close closes the trace log.
+func (t *dirTracer) Close() error {
+	return t.log.Close()
+}
+
+// fileNamePart makes s safe to use in a file name.
+func fileNamePart(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '=' || r == '-' || r == '.' || isAlphaNumeric(r) && r < unicode.MaxASCII {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// lockedTracer serializes the calls of the workers to a Tracer.
+type lockedTracer struct {
+	mu     sync.Mutex
+	tracer Tracer
+}
+
+func (t *lockedTracer) Substitution(s Substitution) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tracer.Substitution(s)
+}
+
+func (t *lockedTracer) Intermediate(i Intermediate) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tracer.Intermediate(i)
+}
+
+// substitutionTrace records the substitutions made for a typeset. A nil
+// trace records nothing.
+type substitutionTrace struct {
+	tracer  Tracer
+	fset    *token.FileSet
+	engine  string
+	typeSet string
+	// err is the first error from the tracer.
+	err error
+}
+
+// newSubstitutionTrace gets the trace of generating the template for the
+// typeSet, or nil if there is no tracer.
+func newSubstitutionTrace(tracer Tracer, t *template, typeSet map[string]TypeRef, useAst bool) *substitutionTrace {
+	if tracer == nil {
+		return nil
+	}
+	engine := "line"
+	if useAst {
+		engine = "ast"
+	}
+	return &substitutionTrace{tracer: tracer, fset: t.fset, engine: engine, typeSet: TypeSetString(typeSet)}
+}
+
+// record records a substitution at a position of the template.
+func (tr *substitutionTrace) record(kind string, pos token.Pos, before, after string) {
+	if tr == nil {
+		return
+	}
+	tr.recordPosition(kind, tr.fset.Position(pos), before, after)
+}
+
+// recordPosition records a substitution at a position that is not in the
+// file set of the template.
+func (tr *substitutionTrace) recordPosition(kind string, pos token.Position, before, after string) {
+	if tr == nil || tr.err != nil {
+		return
+	}
+	tr.err = tr.tracer.Substitution(Substitution{
+		TypeSet: tr.typeSet,
+		Engine:  tr.engine,
+		Kind:    kind,
+		File:    pos.Filename,
+		Line:    pos.Line,
+		Column:  pos.Column,
+		Before:  before,
+		After:   after,
+	})
+}
+
+// error gets the first error from the tracer.
+func (tr *substitutionTrace) error() error {
+	if tr == nil {
+		return nil
+	}
+	return tr.err
+}
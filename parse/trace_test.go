@@ -0,0 +1,179 @@
+package parse_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tehbilly/genny/parse"
+)
+
+type recordingTracer struct {
+	substitutions []parse.Substitution
+	intermediates []parse.Intermediate
+	err           error
+}
+
+func (t *recordingTracer) Substitution(s parse.Substitution) error {
+	t.substitutions = append(t.substitutions, s)
+	return t.err
+}
+
+func (t *recordingTracer) Intermediate(i parse.Intermediate) error {
+	t.intermediates = append(t.intermediates, i)
+	return t.err
+}
+
+func TestGenerateTrace(t *testing.T) {
+	in, err := contents("test/queue/generic_queue.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("Something=int,string")
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		useAst   bool
+		expected parse.Substitution
+	}{
+		{true, parse.Substitution{TypeSet: "Something=int", Engine: "ast", Kind: "FUNC NAME", File: "generic_queue.go", Line: 13, Column: 6, Before: "NewSomethingQueue", After: "NewIntQueue"}},
+		{false, parse.Substitution{TypeSet: "Something=int", Engine: "line", Kind: "LINE", File: "generic_queue.go", Line: 13, Column: 1,
+			Before: "func NewSomethingQueue() *SomethingQueue {", After: "func NewIntQueue ( ) * IntQueue { "}},
+	} {
+		untraced, err := parse.Generate("generic_queue.go", strings.NewReader(in), typeSets, parse.Options{UseAst: test.useAst})
+		require.NoError(t, err)
+
+		tracer := &recordingTracer{}
+		out, err := parse.Generate("generic_queue.go", strings.NewReader(in), typeSets, parse.Options{UseAst: test.useAst, Workers: 2, Trace: tracer})
+		require.NoError(t, err)
+		assert.Equal(t, string(untraced), string(out))
+
+		assert.Contains(t, tracer.substitutions, test.expected)
+		require.Len(t, tracer.intermediates, 2)
+		for _, i := range tracer.intermediates {
+			switch i.Index {
+			case 0:
+				assert.Equal(t, "Something=int", i.TypeSet)
+				assert.Contains(t, i.Source, "IntQueue")
+			case 1:
+				assert.Equal(t, "Something=string", i.TypeSet)
+				assert.Contains(t, i.Source, "StringQueue")
+			default:
+				t.Errorf("unexpected index %d", i.Index)
+			}
+		}
+
+		// errors from the tracer stop generation
+		failing := &recordingTracer{err: errors.New("disk full")}
+		_, err = parse.Generate("generic_queue.go", strings.NewReader(in), typeSets, parse.Options{UseAst: test.useAst, Trace: failing})
+		assert.EqualError(t, err, "disk full")
+	}
+}
+
+func TestJSONTracer(t *testing.T) {
+	in, err := contents("test/queue/generic_queue.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("Something=int")
+	require.NoError(t, err)
+
+	var log strings.Builder
+	_, err = parse.Generate("generic_queue.go", strings.NewReader(in), typeSets, parse.Options{UseAst: true, Trace: parse.NewJSONTracer(&log)})
+	require.NoError(t, err)
+
+	events := make(map[string]int)
+	sc := bufio.NewScanner(strings.NewReader(log.String()))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var event struct {
+			Event   string `json:"event"`
+			Kind    string `json:"kind"`
+			TypeSet string `json:"typeSet"`
+			Source  string `json:"source"`
+		}
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &event))
+		assert.Equal(t, "Something=int", event.TypeSet)
+		events[event.Event]++
+		if event.Event == "intermediate" {
+			assert.Contains(t, event.Source, "func NewIntQueue() *IntQueue")
+		}
+	}
+	assert.Equal(t, 1, events["intermediate"])
+	assert.True(t, events["substitution"] > 0)
+}
+
+func TestDirTracer(t *testing.T) {
+	in, err := contents("test/queue/generic_queue.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("Something=int,*time.Time")
+	require.NoError(t, err)
+
+	dir := filepath.Join(t.TempDir(), "trace")
+	tracer, err := parse.NewDirTracer(dir)
+	require.NoError(t, err)
+	_, err = parse.Generate("generic_queue.go", strings.NewReader(in), typeSets, parse.Options{Trace: tracer})
+	require.NoError(t, err)
+	require.NoError(t, tracer.(io.Closer).Close())
+
+	source, err := ioutil.ReadFile(filepath.Join(dir, "0000_Something=int.go.txt"))
+	require.NoError(t, err)
+	assert.Contains(t, string(source), "IntQueue")
+	source, err = ioutil.ReadFile(filepath.Join(dir, "0001_Something=_time.Time.go.txt"))
+	require.NoError(t, err)
+	assert.Contains(t, string(source), "TimeQueue")
+	log, err := ioutil.ReadFile(filepath.Join(dir, "trace.jsonl"))
+	require.NoError(t, err)
+	assert.Contains(t, string(log), `"kind":"LINE"`)
+}
+
+// panickingTracer panics when a substitution of its typeset is traced.
+type panickingTracer struct {
+	typeSet string
+}
+
+func (t panickingTracer) Substitution(s parse.Substitution) error {
+	if s.TypeSet == t.typeSet {
+		panic("traced " + s.TypeSet)
+	}
+	return nil
+}
+
+func (t panickingTracer) Intermediate(i parse.Intermediate) error {
+	return nil
+}
+
+func TestGeneratePanic(t *testing.T) {
+	in, err := contents("test/queue/generic_queue.go")
+	require.NoError(t, err)
+	typeSets, err := parse.TypeSet("Something=int,string,bool")
+	require.NoError(t, err)
+
+	// one of the templates generated at the same time panics in a worker
+	tracers := []parse.Tracer{nil, panickingTracer{typeSet: "Something=string"}, nil}
+	outs := make([][]byte, len(tracers))
+	errs := make([]error, len(tracers))
+	var wg sync.WaitGroup
+	for i, tracer := range tracers {
+		wg.Add(1)
+		go func(i int, tracer parse.Tracer) {
+			defer wg.Done()
+			outs[i], errs[i] = parse.Generate("generic_queue.go", strings.NewReader(in), typeSets, parse.Options{UseAst: true, Workers: 2, Trace: tracer})
+		}(i, tracer)
+	}
+	wg.Wait()
+
+	assert.EqualError(t, errs[1], "Panic while generating Something=string: traced Something=string")
+	for _, i := range []int{0, 2} {
+		if assert.NoError(t, errs[i]) {
+			assert.Contains(t, string(outs[i]), "type BoolQueue struct")
+		}
+	}
+
+	err = parse.GenerateTo(ioutil.Discard, "generic_queue.go", strings.NewReader(in), typeSets, parse.Options{Trace: panickingTracer{typeSet: "Something=bool"}})
+	assert.EqualError(t, err, "Panic while generating Something=bool: traced Something=bool")
+}
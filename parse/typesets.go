@@ -10,21 +10,61 @@ const (
 	keyValueSep = "="
 	valuesSep   = ","
 	aliasSep    = ":"
-	builtins    = "BUILTINS"
-	numbers     = "NUMBERS"
+	// pluralSep can't appear in a Go type or constant expression, so that
+	// values such as 3/4 aren't taken for plurals.
+	pluralSep = "@"
+	builtins  = "BUILTINS"
+	numbers   = "NUMBERS"
 )
 
 type TypeRef struct {
 	Alias string
 	Type  string
+	// Plural, if not empty, is the plural of the word for the type, for
+	// those that don't follow the rules, as in Child@Children.
+	Plural string
 }
 
+// ParseTypeRef parses a specific type, with an optional alias as in
+// Alias:Type, and an optional plural as in Alias@Plural:Type or Type@Plural.
+// The alias and the plural have to be made of letters, digits and
+// underscores, which are used in identifiers.
 func ParseTypeRef(s string) (*TypeRef, error) {
+	var plural string
+	if i := strings.Index(s, pluralSep); i >= 0 {
+		end := strings.Index(s, aliasSep)
+		if end < i {
+			end = len(s)
+		}
+		plural = s[i+len(pluralSep) : end]
+		if word := wordify(plural, true); word == "" || strings.IndexFunc(word, func(r rune) bool { return !isAlphaNumeric(r) }) >= 0 {
+			return nil, &errBadTypeArgs{Arg: s, Message: "the plural has to be made of letters, digits and underscores"}
+		}
+		s = s[:i] + s[end:]
+	}
+	ref, err := parseTypeRef(s)
+	if err != nil {
+		return nil, err
+	}
+	ref.Plural = plural
+	return ref, nil
+}
+
+func parseTypeRef(s string) (*TypeRef, error) {
 	parts := strings.Split(s, aliasSep)
 	switch len(parts) {
 	case 1:
+		if parts[0] == "" {
+			return nil, &errBadTypeArgs{Arg: s, Message: "no specific type given"}
+		}
 		return &TypeRef{Alias: parts[0], Type: parts[0]}, nil
 	case 2:
+		if parts[1] == "" {
+			return nil, &errBadTypeArgs{Arg: s, Message: "no specific type given"}
+		}
+		if word := wordify(parts[0], true); word == "" || strings.IndexFunc(word, func(r rune) bool { return !isAlphaNumeric(r) }) >= 0 {
+			return nil, &errBadTypeArgs{Arg: s, Message: "the alias has to be made of letters, digits and underscores"}
+		}
 		return &TypeRef{Alias: parts[0], Type: parts[1]}, nil
 	default:
 		return nil, errors.New("unable to parse type ref: " + s)
@@ -61,6 +101,9 @@ func TypeSet(arg string) ([]map[string]TypeRef, error) {
 			} else if t == numbers {
 				types[key] = append(types[key], Numbers...)
 			} else {
+				if _, err := ParseTypeRef(t); err != nil {
+					return nil, err
+				}
 				types[key] = append(types[key], t)
 			}
 		}
@@ -75,3 +75,27 @@ func TestArgsToTypeset(t *testing.T) {
 	}
 
 }
+
+func TestTypeSetBadAliases(t *testing.T) {
+	for _, arg := range []string{"Generic=", "Generic=:int", "Generic=int:", "Generic=my-type:int", "Generic=int,"} {
+		_, err := parse.TypeSet(arg)
+		assert.Error(t, err, arg)
+	}
+	ts, err := parse.TypeSet("Generic=Stamp:*time.Time")
+	if assert.NoError(t, err) {
+		assert.Equal(t, parse.TypeRef{Alias: "Stamp", Type: "*time.Time"}, ts[0]["Generic"])
+	}
+}
+
+func TestTypeSetPlurals(t *testing.T) {
+	ts, err := parse.TypeSet("Generic=Person@People:string,Child@Children")
+	if assert.NoError(t, err) {
+		assert.Equal(t, parse.TypeRef{Alias: "Person", Type: "string", Plural: "People"}, ts[0]["Generic"])
+		assert.Equal(t, parse.TypeRef{Alias: "Child", Type: "Child", Plural: "Children"}, ts[1]["Generic"])
+		assert.Equal(t, "Generic=Person@People:string", parse.TypeSetString(ts[0]))
+	}
+	for _, arg := range []string{"Generic=Person@:string", "Generic=Person@Peo-ple:string", "Generic=@People:string"} {
+		_, err := parse.TypeSet(arg)
+		assert.Error(t, err, arg)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tehbilly/genny/parse"
+)
+
+// runPlan runs `genny plan`. It takes the flags of gen, so that the plan is
+// for the code gen would generate with them.
+func runPlan(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("genny plan", flag.ContinueOnError)
+	c := &genCommand{}
+	c.flags(fs)
+	asJSON := fs.Bool("json", false, "print the plans as JSON")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return &exitError{exitcodeInvalidArgs, err}
+	}
+	if fs.NArg() != 1 {
+		return &exitError{exitcodeInvalidArgs, errors.New("plan needs the types, like genny gen")}
+	}
+	if err := c.parseArgs([]string{"gen", fs.Arg(0)}); err != nil {
+		return err
+	}
+	opts := c.options()
+
+	filename := "stdin"
+	if c.in != "" {
+		file, err := os.Open(c.in)
+		if err != nil {
+			return &exitError{exitcodeSourceFileInvalid, err}
+		}
+		defer file.Close()
+		filename, stdin = c.in, file
+		opts.TemplatePackage = c.templatePackage(c.in, c.out)
+	}
+	plans, err := parse.Preview(filename, stdin, c.typeSets, opts)
+	if err != nil {
+		return &exitError{exitcodeGenFailed, err}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plans)
+	}
+	for i, plan := range plans {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		printPlan(stdout, plan)
+	}
+	return nil
+}
+
+// printPlan prints a plan as text.
+func printPlan(w io.Writer, plan parse.Plan) {
+	fmt.Fprintf(w, "%s\n", parse.TypeSetString(plan.TypeSet))
+
+	fmt.Fprintln(w, "  renames:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, r := range plan.Renames {
+		fmt.Fprintf(tw, "    %s\t-> %s\t(line %d, %d times)\n", r.From, r.To, r.Line, r.Count)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "  exported API:")
+	if len(plan.API) == 0 {
+		fmt.Fprintln(w, "    none")
+	}
+	for _, decl := range plan.API {
+		fmt.Fprintf(w, "    %s\n", strings.ReplaceAll(decl, "\n", "\n    "))
+	}
+}
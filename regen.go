@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/tehbilly/genny/lib"
+	"github.com/tehbilly/genny/parse"
+)
+
+// runRegen runs `genny regen`.
+func runRegen(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("genny regen", flag.ContinueOnError)
+	workers := fs.Int("workers", runtime.GOMAXPROCS(0), "how many files to regenerate at the same time")
+	verbose := fs.Bool("v", false, "also list the files that are unchanged")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return &exitError{exitcodeInvalidArgs, err}
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	var files []string
+	for _, pattern := range patterns {
+		found, err := matchGenerated(pattern)
+		if err != nil {
+			return &exitError{exitcodeSourceFileInvalid, err}
+		}
+		files = append(files, found...)
+	}
+
+	// files that can't be regenerated fail without running
+	results := make([]invocationResult, len(files))
+	var invocations []invocation
+	var indexes []int
+	for i, file := range files {
+		inv, err := regenInvocation(file)
+		if err != nil {
+			results[i] = invocationResult{inv: invocation{source: file}, err: err}
+			continue
+		}
+		invocations = append(invocations, inv)
+		indexes = append(indexes, i)
+	}
+	for i, r := range runInvocations(invocations, *workers) {
+		results[indexes[i]] = r
+	}
+	return report(results, *verbose, stdout)
+}
+
+// matchGenerated finds the generated files matched by a pattern. Files given
+// by name are always included; in directories, only the files with
+// provenance are.
+func matchGenerated(pattern string) ([]string, error) {
+	if strings.HasSuffix(pattern, ".go") {
+		if _, err := os.Stat(pattern); err != nil {
+			return nil, err
+		}
+		return []string{pattern}, nil
+	}
+
+	var files []string
+	add := func(path string) error {
+		p, err := readProvenance(path)
+		if err == nil && p != nil {
+			files = append(files, path)
+		}
+		return nil
+	}
+
+	if slashed := filepath.ToSlash(pattern); slashed == "..." || strings.HasSuffix(slashed, "/...") {
+		root := strings.TrimSuffix(strings.TrimSuffix(slashed, "..."), "/")
+		if root == "" {
+			root = "."
+		}
+		root = filepath.FromSlash(root)
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				name := info.Name()
+				if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(path, ".go") {
+				return nil
+			}
+			return add(path)
+		})
+		return files, err
+	}
+
+	if _, err := os.Stat(pattern); err != nil {
+		return nil, err
+	}
+	names, err := filepath.Glob(filepath.Join(pattern, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		add(name)
+	}
+	return files, nil
+}
+
+// readProvenance reads the provenance in the header of a generated file.
+func readProvenance(path string) (*parse.Provenance, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parse.ReadProvenance(f)
+}
+
+// regenInvocation gets the invocation that regenerates a file, from the
+// provenance in its header.
+func regenInvocation(file string) (invocation, error) {
+	p, err := readProvenance(file)
+	if err != nil {
+		return invocation{}, err
+	}
+	if p == nil {
+		return invocation{}, errors.New("no provenance in the header; generate the file with -provenance first")
+	}
+	if len(p.Args) == 0 {
+		return invocation{}, errors.New("the provenance in the header has no command line")
+	}
+
+	if p.Template == "" {
+		return invocation{}, errors.New("generated from stdin; can't be regenerated")
+	}
+
+	dir := filepath.Dir(file)
+	var source []byte
+	switch {
+	case strings.HasPrefix(p.Template, gennylibPrefix):
+		// fetched when it is regenerated
+	case strings.HasPrefix(p.Template, lib.Prefix):
+		source, _ = lib.Source(strings.TrimPrefix(p.Template, lib.Prefix))
+	default:
+		template := filepath.Join(dir, filepath.FromSlash(p.Template))
+		if source, err = ioutil.ReadFile(template); err != nil {
+			return invocation{}, fmt.Errorf("the template %s has moved or been deleted", template)
+		}
+	}
+	return invocation{
+		dir:             filepath.Join(dir, filepath.FromSlash(p.Dir)),
+		source:          file,
+		args:            p.Args,
+		output:          file,
+		templateChanged: source != nil && p.TemplateChanged(source),
+	}, nil
+}
+
+// writeTo makes the command write to file, which it is regenerating.
+func (c *genCommand) writeTo(dir, file string) error {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	if c.out == "" {
+		c.out = abs
+		return nil
+	}
+	out, err := filepath.Abs(c.path(dir, c.out))
+	if err != nil {
+		return err
+	}
+	if out != abs {
+		return fmt.Errorf("the command line in the header writes to %s, not to this file", c.path(dir, c.out))
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegen(t *testing.T) {
+	dir := t.TempDir()
+	template := filepath.Join(dir, "queue.go")
+	output := filepath.Join(dir, "gen", "queue.go")
+	require.NoError(t, ioutil.WriteFile(template, []byte(watchTemplate), 0644))
+
+	inv := invocation{dir: dir, source: "test", args: []string{"-provenance", "-in=queue.go", "-out=gen/queue.go", "gen", "Something=int"}}
+	cmd, err := inv.command()
+	require.NoError(t, err)
+	require.NoError(t, cmd.run(inv.dir, nil, ioutil.Discard))
+	generated, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Contains(t, string(generated), "// genny:template ../queue.go\n")
+
+	// an edited file is restored
+	require.NoError(t, ioutil.WriteFile(output, append(generated, "\n// edited\n"...), 0644))
+	var log bytes.Buffer
+	require.NoError(t, runRegen([]string{output}, &log))
+	assert.Equal(t, "changed   "+output+"\n1 changed, 0 unchanged, 0 failed\n", log.String())
+	regenerated, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, string(generated), string(regenerated))
+
+	// so is a file whose template has changed, which is reported
+	require.NoError(t, ioutil.WriteFile(template, []byte(watchTemplate+"\n// changed\n"), 0644))
+	log.Reset()
+	require.NoError(t, runRegen([]string{output}, &log))
+	assert.Equal(t, "changed   "+output+" (the template has changed)\n1 changed, 0 unchanged, 0 failed\n", log.String())
+
+	// patterns only find files with provenance
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "gen", "plain.go"), []byte("package queue\n"), 0644))
+	log.Reset()
+	require.NoError(t, runRegen([]string{"-v", dir + "/..."}, &log))
+	assert.Equal(t, "unchanged "+output+"\n0 changed, 1 unchanged, 0 failed\n", log.String())
+
+	// files without provenance fail when named
+	log.Reset()
+	assert.Error(t, runRegen([]string{filepath.Join(dir, "gen", "plain.go")}, &log))
+	assert.Contains(t, log.String(), "no provenance in the header")
+
+	// and files generated from stdin
+	stdin := filepath.Join(dir, "gen", "stdin.go")
+	require.NoError(t, ioutil.WriteFile(stdin, []byte("// Code generated by genny. DO NOT EDIT.\n//\n// genny:dir ..\n// genny:args gen Something=int\n\npackage queue\n"), 0644))
+	log.Reset()
+	assert.Error(t, runRegen([]string{stdin}, &log))
+	assert.Contains(t, log.String(), "FAIL      "+stdin+": generated from stdin; can't be regenerated")
+	require.NoError(t, os.Remove(stdin))
+
+	// so do files whose template has moved
+	require.NoError(t, os.Rename(template, filepath.Join(dir, "moved.go")))
+	log.Reset()
+	assert.Error(t, runRegen([]string{output}, &log))
+	assert.Contains(t, log.String(), "FAIL      "+output+": the template "+template+" has moved or been deleted")
+}
+
+func TestWriteTo(t *testing.T) {
+	c := &genCommand{}
+	require.NoError(t, c.writeTo("dir", "gen.go"))
+	abs, err := filepath.Abs("gen.go")
+	require.NoError(t, err)
+	assert.Equal(t, abs, c.out)
+
+	c = &genCommand{out: "../gen.go"}
+	assert.NoError(t, c.writeTo("dir", "gen.go"))
+	c = &genCommand{out: "other.go"}
+	assert.Error(t, c.writeTo("dir", "gen.go"))
+}
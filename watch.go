@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// runWatch runs `genny watch`.
+func runWatch(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("genny watch", flag.ContinueOnError)
+	interval := fs.Duration("interval", time.Second, "how often to check the templates for changes")
+	manifest := fs.String("manifest", "", "file listing genny command lines to watch, one per line, instead of the //go:generate lines in dir")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return &exitError{exitcodeInvalidArgs, err}
+	}
+
+	root := "."
+	if fs.NArg() > 0 {
+		root = fs.Arg(0)
+	}
+
+	w := newWatcher(root, *manifest, stdout)
+	w.poll()
+	fmt.Fprintf(w.out, "watching %d templates\n", len(w.stamps))
+	for {
+		time.Sleep(*interval)
+		w.poll()
+	}
+}
+
+// fileStamp is used to tell whether a file has changed.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// watcher regenerates outputs whose templates have changed since the last
+// time it polled them.
+type watcher struct {
+	root     string
+	manifest string
+	out      io.Writer
+
+	stamps map[string]fileStamp
+	// reported holds the errors that have already been printed, so that
+	// they are not printed again on every poll.
+	reported map[string]bool
+}
+
+func newWatcher(root, manifest string, out io.Writer) *watcher {
+	return &watcher{
+		root:     root,
+		manifest: manifest,
+		out:      out,
+		stamps:   make(map[string]fileStamp),
+		reported: make(map[string]bool),
+	}
+}
+
+// invocations gets the command lines to watch.
+func (w *watcher) invocations() ([]invocation, error) {
+	if w.manifest != "" {
+		return readManifest(w.manifest)
+	}
+	return findDirectives(w.root)
+}
+
+// poll regenerates the outputs of the templates that have changed. The
+// first time a template is seen it is only recorded.
+func (w *watcher) poll() {
+	invocations, err := w.invocations()
+	if err != nil {
+		w.report(err)
+		return
+	}
+
+	changed := make(map[string]bool)
+	for _, inv := range invocations {
+		cmd, err := inv.command()
+		if err != nil {
+			w.report(err)
+			continue
+		}
+		if cmd.in == "" || cmd.out == "" || cmd.get != "" {
+			// nothing to watch or nowhere to write to
+			continue
+		}
+
+		templates := []string{cmd.path(inv.dir, cmd.in)}
+		if cmd.genTest {
+			templates = append(templates, testFileName(templates[0]))
+		}
+		regenerate := false
+		for _, template := range templates {
+			if _, ok := changed[template]; !ok {
+				changed[template] = w.changed(template)
+			}
+			regenerate = regenerate || changed[template]
+		}
+		if !regenerate {
+			continue
+		}
+
+		start := time.Now()
+		if err := cmd.run(inv.dir, nil, io.Discard); err != nil {
+			fmt.Fprintf(w.out, "FAIL %s: %v\n", cmd.path(inv.dir, cmd.out), err)
+			continue
+		}
+		fmt.Fprintf(w.out, "ok   %s (%v)\n", cmd.path(inv.dir, cmd.out), time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// changed records the stamp of the file and gets whether it differs from
+// the one recorded before.
+func (w *watcher) changed(path string) bool {
+	var stamp fileStamp
+	if info, err := os.Stat(path); err == nil {
+		stamp = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	previous, seen := w.stamps[path]
+	w.stamps[path] = stamp
+	return seen && previous != stamp && stamp != fileStamp{}
+}
+
+// report prints an error unless it has been printed before.
+func (w *watcher) report(err error) {
+	if w.reported[err.Error()] {
+		return
+	}
+	w.reported[err.Error()] = true
+	fmt.Fprintf(w.out, "FAIL %v\n", err)
+}
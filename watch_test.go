@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const watchTemplate = `package queue
+
+import "github.com/tehbilly/genny/generic"
+
+//go:generate genny -in=$GOFILE -out=gen-$GOFILE gen "Something=int"
+
+type Something generic.Type
+
+type SomethingQueue []Something
+`
+
+func writeTemplate(t *testing.T, path, src string, modTime time.Time) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(src), 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genny-watch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	template := filepath.Join(dir, "queue.go")
+	output := filepath.Join(dir, "gen-queue.go")
+	modTime := time.Now().Add(-time.Hour)
+	writeTemplate(t, template, watchTemplate, modTime)
+
+	var log bytes.Buffer
+	w := newWatcher(dir, "", &log)
+
+	// the first poll only records the templates
+	w.poll()
+	assert.Empty(t, log.String())
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err), "Expected output not to be generated")
+
+	// a changed template regenerates its output
+	writeTemplate(t, template, watchTemplate+"\ntype SomethingList []Something\n", modTime.Add(time.Minute))
+	w.poll()
+	assert.Contains(t, log.String(), "ok   "+output)
+	generated, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Contains(t, string(generated), "type IntList []int")
+
+	// failures are reported and watching continues
+	log.Reset()
+	writeTemplate(t, template, watchTemplate+"\nfunc {\n", modTime.Add(2*time.Minute))
+	w.poll()
+	assert.Contains(t, log.String(), "FAIL "+output)
+
+	log.Reset()
+	writeTemplate(t, template, watchTemplate, modTime.Add(3*time.Minute))
+	w.poll()
+	assert.Contains(t, log.String(), "ok   "+output)
+
+	// unchanged templates are not regenerated
+	log.Reset()
+	w.poll()
+	assert.Empty(t, log.String())
+}
+
+func TestSplitArgs(t *testing.T) {
+	args, err := splitArgs(`-in=$GOFILE -out=gen-$GOFILE -imp "github.com/a/b" gen "Key=string,int Value=$DOLLAR"`,
+		map[string]string{"GOFILE": "map.go", "DOLLAR": "$"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-in=map.go", "-out=gen-map.go", "-imp", "github.com/a/b", "gen", "Key=string,int Value=$"}, args)
+
+	_, err = splitArgs(`gen "Key=string`, nil)
+	assert.Error(t, err)
+}